@@ -1,6 +1,12 @@
 package main
 
-import "slices"
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
 
 type TokenArrayInterface interface {
 	at(index uint) Token
@@ -94,9 +100,417 @@ func (s *Stack) Delete(index int) FunctionVal {
 	return v
 }
 
+// returns a copy of the stack
+func (s *Stack) clone() *Stack {
+	return &Stack{stack: slices.Clone(s.stack), length: s.length}
+}
+
+// removes the elements above length
+func (s *Stack) truncate(length int) {
+	if length < s.length {
+		s.stack = s.stack[:length]
+		s.length = length
+	}
+}
+
 func NewStack() *Stack {
 	return &Stack{
 		stack:  []FunctionVal{},
 		length: 0,
 	}
 }
+
+//#region Array methods
+
+// a method every array has, arr is the array it is called on
+type arrayMethod func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal
+
+// the native methods of arrays, they are looked up when a property of an array is read
+var arrayMethods map[string]arrayMethod
+
+// the method of arr named name bound to arr, nil when arrays have no such method
+func (arr *ArrayVal) method(name string) *Macro {
+	method, ok := arrayMethods[name]
+	if !ok {
+		return nil
+	}
+	return MK_MACRO(name, func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return method(arr, args, env, pos, r)
+	})
+}
+
+// the values of the elements of arr, holes are undefined
+func (arr *ArrayVal) values() []RuntimeVal {
+	values := make([]RuntimeVal, arr.elements.length)
+	for i := range values {
+		values[i] = arr.get(i)
+	}
+	return values
+}
+
+// replaces the elements of arr with refs
+func (arr *ArrayVal) setRefs(refs []Ref) {
+	arr.elements.slice = refs
+	arr.elements.length = len(refs)
+}
+
+// compares like ===, NaN is not equal to anything, itself included
+func strictEquals(a, b RuntimeVal) bool {
+	if n, ok := a.(*NumberVal); ok {
+		m, ok := b.(*NumberVal)
+		return ok && n.value == m.value
+	}
+	return RtvAreEqual(a, b) && ValueType(a) == ValueType(b)
+}
+
+// compares like strictEquals, but NaN is equal to NaN
+func sameValueZero(a, b RuntimeVal) bool {
+	if n, ok := a.(*NumberVal); ok && math.IsNaN(n.value) {
+		m, ok := b.(*NumberVal)
+		return ok && math.IsNaN(m.value)
+	}
+	return strictEquals(a, b)
+}
+
+// stores value as the length of arr when key is "length" and reports whether it was:
+// a shorter length drops the elements past it, a longer one adds holes
+func (arr *ArrayVal) assignLength(key, value RuntimeVal, env *Environment, pos Pos) bool {
+	if key, ok := key.(*StringVal); !ok || key.value != "length" {
+		return false
+	}
+	n, ok := value.(*NumberVal)
+	if !ok || n.value < 0 || n.value != math.Trunc(n.value) || n.value > math.MaxInt32 {
+		env.ThrowRangeError("invalid array length", value.noAnsi()+SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	length := int(n.value)
+	if length < arr.elements.length {
+		// the dropped elements can be collected
+		clear(arr.elements.slice[length:])
+		arr.setRefs(arr.elements.slice[:length])
+	} else {
+		arr.setRefs(append(arr.elements.slice[:arr.elements.length], make([]Ref, length-arr.elements.length)...))
+	}
+	return true
+}
+
+// the function argument at index of the function name
+func fnArg(args []RuntimeVal, index int, name string, env *Environment, pos Pos) RuntimeVal {
+	fn := argAt(args, index)
+	switch fn.(type) {
+	case *FunctionVal, *Macro:
+		return fn
+	}
+	env.ThrowTypeError(fmt.Sprintf("%s expects a function, got type %s%s", name, ValueType(fn), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	return nil
+}
+
+// the index argument at index of the function name, counted from the end when it is
+// negative and clamped to the length of the array, fallback when it was not passed
+func indexArg(args []RuntimeVal, index, length, fallback int, name string, env *Environment, pos Pos) int {
+	switch arg := argAt(args, index).(type) {
+	case *Undefined:
+		return fallback
+	case *NumberVal:
+		i := int(math.Trunc(arg.value))
+		if i < 0 {
+			i += length
+		}
+		return max(0, min(i, length))
+	default:
+		env.ThrowTypeError(fmt.Sprintf("%s expects an index of type number, got type %s%s", name, ValueType(arg), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	return 0
+}
+
+// calls fn for the element of arr at index with the element, index and array
+func (r *Interpreter) callElement(fn RuntimeVal, arr *ArrayVal, index int, env *Environment, pos Pos) RuntimeVal {
+	rv, _ := CallFunction(fn, env, []RuntimeVal{arr.get(index), MK_NUMBER(float64(index)), arr}, r, pos)
+	return rv
+}
+
+// appends value to values, or its elements when it is an array and depth is
+// above 0, the arrays in it are flattened to depth - 1
+func flatten(values []RuntimeVal, value RuntimeVal, depth int) []RuntimeVal {
+	arr, ok := value.(*ArrayVal)
+	if !ok || depth <= 0 {
+		return append(values, value)
+	}
+	for _, element := range arr.values() {
+		values = flatten(values, element, depth-1)
+	}
+	return values
+}
+
+// the text of an element of an array that is joined, null and undefined are empty
+func joinText(value RuntimeVal, sep string) string {
+	switch v := value.(type) {
+	case *NullVal, *Undefined:
+		return ""
+	case *ArrayVal:
+		texts := []string{}
+		for _, element := range v.values() {
+			texts = append(texts, joinText(element, ","))
+		}
+		return strings.Join(texts, sep)
+	}
+	return value.noAnsi()
+}
+
+func init() {
+	arrayMethods = map[string]arrayMethod{
+		"push": func(arr *ArrayVal, args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			arr.Push(args...)
+			return MK_NUMBER(float64(arr.elements.length))
+		},
+		"pop": func(arr *ArrayVal, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			length := arr.elements.length
+			if length == 0 {
+				return undefined
+			}
+			last := arr.get(length - 1)
+			arr.setRefs(arr.elements.slice[:length-1])
+			return last
+		},
+		"shift": func(arr *ArrayVal, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			if arr.elements.length == 0 {
+				return undefined
+			}
+			first := arr.get(0)
+			arr.setRefs(slices.Delete(arr.elements.slice, 0, 1))
+			return first
+		},
+		"unshift": func(arr *ArrayVal, args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			refs := make([]Ref, len(args))
+			for i, arg := range args {
+				refs[i] = Memory.alloc(arg)
+			}
+			arr.setRefs(slices.Insert(arr.elements.slice, 0, refs...))
+			return MK_NUMBER(float64(arr.elements.length))
+		},
+		"at": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			index, ok := argAt(args, 0).(*NumberVal)
+			if !ok {
+				env.ThrowTypeError(fmt.Sprintf("array.at expects an index of type number, got type %s%s", ValueType(argAt(args, 0)), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+			}
+			i := int(math.Trunc(index.value))
+			if i < 0 {
+				i += arr.elements.length
+			}
+			return arr.get(i)
+		},
+		"slice": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			length := arr.elements.length
+			start := indexArg(args, 0, length, 0, "array.slice", env, pos)
+			end := indexArg(args, 1, length, length, "array.slice", env, pos)
+			if end < start {
+				return MK_ARRAY()
+			}
+			return MK_ARRAY(arr.values()[start:end]...)
+		},
+		"splice": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			length := arr.elements.length
+			start := indexArg(args, 0, length, length, "array.splice", env, pos)
+			count := length - start
+			if len(args) > 1 {
+				count = indexArg(args, 1, length-start, 0, "array.splice", env, pos)
+				// a negative count removes nothing
+				if n, ok := args[1].(*NumberVal); ok && n.value < 0 {
+					count = 0
+				}
+			}
+			removed := MK_ARRAY(arr.values()[start : start+count]...)
+			refs := []Ref{}
+			if len(args) > 2 {
+				for _, arg := range args[2:] {
+					refs = append(refs, Memory.alloc(arg))
+				}
+			}
+			arr.setRefs(slices.Replace(arr.elements.slice, start, start+count, refs...))
+			return removed
+		},
+		"concat": func(arr *ArrayVal, args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			values := arr.values()
+			for _, arg := range args {
+				values = flatten(values, arg, 1)
+			}
+			return MK_ARRAY(values...)
+		},
+		"forEach": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.forEach", env, pos)
+			for i := 0; i < arr.elements.length; i++ {
+				r.callElement(fn, arr, i, env, pos)
+			}
+			return undefined
+		},
+		"map": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.map", env, pos)
+			mapped := MK_ARRAY()
+			for i := 0; i < arr.elements.length; i++ {
+				mapped.Push(r.callElement(fn, arr, i, env, pos))
+			}
+			return mapped
+		},
+		"filter": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.filter", env, pos)
+			filtered := MK_ARRAY()
+			for i := 0; i < arr.elements.length; i++ {
+				if element := arr.get(i); RtvToBool(r.callElement(fn, arr, i, env, pos)) {
+					filtered.Push(element)
+				}
+			}
+			return filtered
+		},
+		"reduce": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.reduce", env, pos)
+			i := 0
+			accumulator := argAt(args, 1)
+			if len(args) < 2 {
+				if arr.elements.length == 0 {
+					env.ThrowTypeError("array.reduce of an empty array with no initial value" + SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+				}
+				accumulator = arr.get(0)
+				i++
+			}
+			for ; i < arr.elements.length; i++ {
+				accumulator, _ = CallFunction(fn, env, []RuntimeVal{accumulator, arr.get(i), MK_NUMBER(float64(i)), arr}, r, pos)
+			}
+			return accumulator
+		},
+		"find": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.find", env, pos)
+			for i := 0; i < arr.elements.length; i++ {
+				if element := arr.get(i); RtvToBool(r.callElement(fn, arr, i, env, pos)) {
+					return element
+				}
+			}
+			return undefined
+		},
+		"findIndex": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.findIndex", env, pos)
+			for i := 0; i < arr.elements.length; i++ {
+				if RtvToBool(r.callElement(fn, arr, i, env, pos)) {
+					return MK_NUMBER(float64(i))
+				}
+			}
+			return MK_NUMBER(-1)
+		},
+		"some": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.some", env, pos)
+			for i := 0; i < arr.elements.length; i++ {
+				if RtvToBool(r.callElement(fn, arr, i, env, pos)) {
+					return MK_BOOL(true)
+				}
+			}
+			return MK_BOOL(false)
+		},
+		"every": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.every", env, pos)
+			for i := 0; i < arr.elements.length; i++ {
+				if !RtvToBool(r.callElement(fn, arr, i, env, pos)) {
+					return MK_BOOL(false)
+				}
+			}
+			return MK_BOOL(true)
+		},
+		"indexOf": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			from := indexArg(args, 1, arr.elements.length, 0, "array.indexOf", env, pos)
+			for i := from; i < arr.elements.length; i++ {
+				if strictEquals(arr.get(i), argAt(args, 0)) {
+					return MK_NUMBER(float64(i))
+				}
+			}
+			return MK_NUMBER(-1)
+		},
+		"includes": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			from := indexArg(args, 1, arr.elements.length, 0, "array.includes", env, pos)
+			for i := from; i < arr.elements.length; i++ {
+				if sameValueZero(arr.get(i), argAt(args, 0)) {
+					return MK_BOOL(true)
+				}
+			}
+			return MK_BOOL(false)
+		},
+		"sort": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			compare := func(a, b RuntimeVal) int {
+				// numbers and strings are ordered by value, anything else by its text
+				if order, ok := compareValues(a, b); ok {
+					return order
+				}
+				return strings.Compare(a.noAnsi(), b.noAnsi())
+			}
+			if len(args) > 0 {
+				fn := fnArg(args, 0, "array.sort", env, pos)
+				compare = func(a, b RuntimeVal) int {
+					rv, _ := CallFunction(fn, env, []RuntimeVal{a, b}, r, pos)
+					order, ok := rv.(*NumberVal)
+					if !ok {
+						env.ThrowTypeError(fmt.Sprintf("the comparator of array.sort must return a number, got type %s%s", ValueType(rv), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+					}
+					return cmp.Compare(order.value, 0)
+				}
+			}
+			values := arr.values()
+			slices.SortStableFunc(values, func(a, b RuntimeVal) int {
+				// undefined always goes last and is never passed to the comparator
+				_, aUndefined := a.(*Undefined)
+				_, bUndefined := b.(*Undefined)
+				switch {
+				case aUndefined && bUndefined:
+					return 0
+				case aUndefined:
+					return 1
+				case bUndefined:
+					return -1
+				}
+				return compare(a, b)
+			})
+			for i, value := range values {
+				arr.set(i, value)
+			}
+			return arr
+		},
+		"join": func(arr *ArrayVal, args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			sep := ","
+			if s, ok := argAt(args, 0).(*StringVal); ok {
+				sep = s.value
+			}
+			return MK_STRING(joinText(arr, sep))
+		},
+		"flat": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			depth := 1
+			switch d := argAt(args, 0).(type) {
+			case *Undefined:
+			case *NumberVal:
+				depth = int(math.Min(d.value, math.MaxInt32))
+			default:
+				env.ThrowTypeError(fmt.Sprintf("array.flat expects a depth of type number, got type %s%s", ValueType(d), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+			}
+			values := []RuntimeVal{}
+			for _, element := range arr.values() {
+				values = flatten(values, element, depth)
+			}
+			return MK_ARRAY(values...)
+		},
+		"flatMap": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			fn := fnArg(args, 0, "array.flatMap", env, pos)
+			values := []RuntimeVal{}
+			for i := 0; i < arr.elements.length; i++ {
+				values = flatten(values, r.callElement(fn, arr, i, env, pos), 1)
+			}
+			return MK_ARRAY(values...)
+		},
+		"reverse": func(arr *ArrayVal, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			slices.Reverse(arr.elements.slice)
+			return arr
+		},
+		"fill": func(arr *ArrayVal, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			length := arr.elements.length
+			start := indexArg(args, 1, length, 0, "array.fill", env, pos)
+			end := indexArg(args, 2, length, length, "array.fill", env, pos)
+			for i := start; i < end; i++ {
+				arr.set(i, argAt(args, 0))
+			}
+			return arr
+		},
+	}
+}
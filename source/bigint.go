@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+//#region literals and conversions
+
+// the value of a bigint literal like 123n, 0xffn or 1_000n
+func parseBigIntLiteral(src string) *big.Int {
+	src = strings.ReplaceAll(strings.TrimSuffix(src, "n"), "_", "")
+	base := 10
+	if len(src) > 2 && src[0] == '0' {
+		if prefixed := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[src[1]]; prefixed != 0 {
+			base, src = prefixed, src[2:]
+		}
+	}
+	n, _ := new(big.Int).SetString(src, base)
+	return n
+}
+
+// the integer str is the text of as BigInt() reads it: decimal digits with a sign, or
+// hex, octal or binary ones after their prefix; blank text is 0, ok is false for anything else
+func parseBigInt(str string) (*big.Int, bool) {
+	str = strings.TrimSpace(str)
+	if str == "" {
+		return new(big.Int), true
+	}
+	base := 10
+	if len(str) > 2 && str[0] == '0' {
+		if prefixed := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[str[1]]; prefixed != 0 {
+			base, str = prefixed, str[2:]
+			// SetString would read a sign after the prefix
+			if str[0] == '+' || str[0] == '-' {
+				return nil, false
+			}
+		}
+	}
+	return new(big.Int).SetString(str, base)
+}
+
+// BigInt(value): integral numbers, the text of an integer and booleans are converted
+func toBigInt(value RuntimeVal, env *Environment, pos Pos) *BigIntVal {
+	switch v := value.(type) {
+	case *BigIntVal:
+		return v
+	case *NumberVal:
+		if math.IsNaN(v.value) || math.IsInf(v.value, 0) || v.value != math.Trunc(v.value) {
+			env.ThrowRangeError("BigInt cannot convert", formatNumber(v.value), "to a bigint, it is not an integer", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		n, _ := big.NewFloat(v.value).Int(nil)
+		return MK_BIGINT(n)
+	case *StringVal:
+		n, ok := parseBigInt(v.value)
+		if !ok {
+			env.ThrowSyntaxError(fmt.Sprintf("BigInt cannot convert %q to a bigint%s", v.value, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+		}
+		return MK_BIGINT(n)
+	case *BoolVal:
+		if v.value {
+			return MK_BIGINT(big.NewInt(1))
+		}
+		return MK_BIGINT(new(big.Int))
+	}
+	env.ThrowTypeError("BigInt cannot convert a value of type", ValueType(value), "to a bigint", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	return nil
+}
+
+// Number(value): bigints become the nearest number, the text of a number is read the
+// way literals are and any other text is NaN; no value at all is 0
+func toNumber(args []RuntimeVal) float64 {
+	if len(args) == 0 {
+		return 0
+	}
+	switch v := args[0].(type) {
+	case *NumberVal:
+		return v.value
+	case *BigIntVal:
+		n, _ := new(big.Float).SetInt(v.value).Float64()
+		return n
+	case *StringVal:
+		return stringToNumber(v.value)
+	case *BoolVal:
+		if v.value {
+			return 1
+		}
+		return 0
+	case *NullVal:
+		return 0
+	}
+	return math.NaN()
+}
+
+// the number str is the text of, NaN when it is not one; blank text is 0
+func stringToNumber(str string) float64 {
+	str = strings.TrimSpace(str)
+	switch str {
+	case "":
+		return 0
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+	if len(str) > 2 && str[0] == '0' && strings.ContainsRune("xXoObB", rune(str[1])) {
+		if n, ok := parseBigInt(str); ok {
+			f, _ := new(big.Float).SetInt(n).Float64()
+			return f
+		}
+		return math.NaN()
+	}
+	// all of the text has to be the number, parseFloat reads the start of it
+	if floatPrefix.FindString(str) == str {
+		return parseFloat(str)
+	}
+	return math.NaN()
+}
+
+//#endregion
+
+//#region operators
+
+// the value of an arithmetic, bitwise or shift operator with a bigint operand; the other one has
+// to be a bigint too, numbers are never converted implicitly, only + joins a bigint to a string
+func (r *Interpreter) bigint(op string, v1, v2 RuntimeVal, pos Pos, env *Environment) RuntimeVal {
+	_, s1 := v1.(*StringVal)
+	_, s2 := v2.(*StringVal)
+	if op == "+" && (s1 || s2) {
+		return MK_STRING(v1.noAnsi() + v2.noAnsi())
+	}
+	n1, ok := v1.(*BigIntVal)
+	n2, v2ok := v2.(*BigIntVal)
+	if !ok || !v2ok {
+		hint := ""
+		if ValueType(v1) == "number" || ValueType(v2) == "number" {
+			hint = ", convert one of them with BigInt() or Number()"
+		}
+		env.ThrowTypeError(fmt.Sprintf("'%s' operation between type %s and %s is invalid%s.%s", op, ValueType(v1), ValueType(v2), hint, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	a, b := n1.value, n2.value
+	n := new(big.Int)
+	switch op {
+	case "+":
+		n.Add(a, b)
+	case "-":
+		n.Sub(a, b)
+	case "*":
+		n.Mul(a, b)
+	case "/", "%":
+		if b.Sign() == 0 {
+			env.ThrowRangeError("division by zero", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		// both round towards zero, the remainder has the sign of a
+		if op == "/" {
+			n.Quo(a, b)
+		} else {
+			n.Rem(a, b)
+		}
+	case "**":
+		if b.Sign() < 0 {
+			env.ThrowRangeError("the exponent of a bigint cannot be negative", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		n.Exp(a, b, nil)
+	case "&":
+		n.And(a, b)
+	case "|":
+		n.Or(a, b)
+	case "^":
+		n.Xor(a, b)
+	case "<<", ">>":
+		// a negative count shifts the other way
+		if (op == "<<") != (b.Sign() < 0) {
+			if !b.IsInt64() || b.CmpAbs(big.NewInt(math.MaxInt32)) > 0 {
+				env.ThrowRangeError("a bigint cannot be shifted by", b.String(), "bits", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			n.Lsh(a, uint(new(big.Int).Abs(b).Int64()))
+		} else {
+			// shifting out every bit leaves 0, or -1 for a negative bigint
+			shift := new(big.Int).Abs(b)
+			if shift.BitLen() > 32 {
+				shift.SetInt64(int64(a.BitLen()) + 1)
+			}
+			n.Rsh(a, uint(shift.Int64()))
+		}
+	default:
+		env.ThrowTypeError(fmt.Sprintf("'%s' operation is invalid for bigints, they have no unsigned shift.%s", op, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	return MK_BIGINT(n)
+}
+
+// orders a bigint and a number exactly, ok is false when n is NaN
+func compareBigIntNumber(a *big.Int, n float64) (int, bool) {
+	switch {
+	case math.IsNaN(n):
+		return 0, false
+	case math.IsInf(n, 1):
+		return -1, true
+	case math.IsInf(n, -1):
+		return 1, true
+	}
+	return new(big.Float).SetInt(a).Cmp(big.NewFloat(n)), true
+}
+
+// the value of a relational operator with a bigint operand, the other
+// one can be a bigint or a number, which is compared exactly
+func compareBigInt(op string, left, right RuntimeVal, err_msg func() string, env *Environment) bool {
+	order, ok := 0, true
+	switch l := left.(type) {
+	case *BigIntVal:
+		switch r := right.(type) {
+		case *BigIntVal:
+			order = l.value.Cmp(r.value)
+		case *NumberVal:
+			order, ok = compareBigIntNumber(l.value, r.value)
+		default:
+			env.ThrowTypeError(err_msg())
+		}
+	case *NumberVal:
+		order, ok = compareBigIntNumber(right.(*BigIntVal).value, l.value)
+		order = -order
+	default:
+		env.ThrowTypeError(err_msg())
+	}
+	if !ok {
+		return false
+	}
+	switch op {
+	case "<":
+		return order < 0
+	case ">":
+		return order > 0
+	case "<=":
+		return order <= 0
+	default:
+		return order >= 0
+	}
+}
+
+//#endregion
+
+//#region methods
+
+type bigintMethod func(n *big.Int, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal
+
+// the native methods of bigints, they are looked up when a property of a bigint is read
+var bigintMethods map[string]bigintMethod
+
+// the method of n named name bound to n, nil when bigints have no such method
+func (n *BigIntVal) method(name string) *Macro {
+	method, ok := bigintMethods[name]
+	if !ok {
+		return nil
+	}
+	return MK_MACRO(name, func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return method(n.value, args, env, pos, r)
+	})
+}
+
+func init() {
+	bigintMethods = map[string]bigintMethod{
+		"toString": func(n *big.Int, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			radix := numberArg(args, 0, 10, "bigint.toString", env, pos)
+			if radix < 2 || radix > 36 || radix != math.Trunc(radix) {
+				env.ThrowRangeError("bigint.toString expects a radix between 2 and 36, got", formatNumber(radix), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			return MK_STRING(n.Text(int(radix)))
+		},
+	}
+}
+
+//#endregion
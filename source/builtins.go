@@ -1,16 +1,6 @@
 package main
 
-import (
-	"fmt"
-	"runtime"
-	"slices"
-)
-
-func (r *Interpreter) IsMemoryHigh() bool {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	return m.HeapInuse > r.MemThreshold // Or use m.Alloc, etc.
-}
+import "slices"
 
 type Task struct {
 	macro *Macro
@@ -28,186 +18,383 @@ type MicroTaskQueue struct {
 func (q *MicroTaskQueue) queueMicroTask(task Task) {
 	q.queue = append(q.queue, task)
 	q.length = len(q.queue)
+	eventLoop.wake()
 }
 
 func (q *MicroTaskQueue) execCurrentTask() {
 	if len(q.queue) == 0 {
 		return
 	}
-	index := len(q.queue) - 1
-	task := q.queue[index]
-	q.queue = slices.Delete(q.queue, index, index+1)
+	// first in, first out
+	task := q.queue[0]
+	q.queue = slices.Delete(q.queue, 0, 1)
 	// fmt.Printf("\x1b[34mTask\x1b[0m\r\nmacro: %+v, args: %+v\r\n", task.macro, task.args)
 	task.macro.call(task.args, task.env, task.pos, task.r)
 	q.length = len(q.queue)
 }
 
-var promise_mem_loc = GenerateRadix(16)
+// states of a promise
+const (
+	PENDING   = "pending"
+	FULFILLED = "fulfilled"
+	REJECTED  = "rejected"
+)
 
-type Callable interface {
-	RuntimeVal
-	Call(env *Environment, args []RuntimeVal, r *Interpreter, pos Pos) RuntimeVal
+// PromiseState is the native side of an instance of the stdlib Promise class,
+// stored in its #state field.
+type PromiseState struct {
+	state string
+	value RuntimeVal
+	// callbacks waiting for the promise to settle
+	reactions []func()
+	// a callback was attached, so a rejection is not reported as unhandled
+	handled bool
 }
 
-func Promise(fn Callable, fn_args []RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
-	var declEnv *Environment
-	switch fn := fn.(type) {
-	case *FunctionVal:
-		declEnv = fn.declEnv
-	case *Macro:
-		declEnv = env
+// returns the state of a promise instance, or nil when value is not a promise
+func promiseOf(value RuntimeVal) *PromiseState {
+	instance, ok := value.(*Instance)
+	if !ok {
+		return nil
 	}
-	PromiseExecutorWrapper := MK_MACRO("#_promise_exec_wrapper", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		args_len := len(args)
-		if args_len < 2 {
-			env.throwError([]string{"#_promise_exec_wrapper expects 2 arguments, but it was given", fmt.Sprint(args_len), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
-		}
-		exec := args[0].(Callable)
-		class := args[1].(*Instance)
-
-		resolve, ok := GetInstanceMember(class, "resolve").(*Macro)
-		// reject, ok := GetNativeMember(class, "reject").(*Macro)
-		switch exec := exec.(type) {
-		case *FunctionVal:
-			// keep this line or else... you want to cry
-			exec.async = false
-		}
-		value := exec.Call(env, fn_args, r, pos)
-		if ok {
-			resolve.call([]RuntimeVal{value, class}, env, pos, r)
+	state, ok := GetInstanceMember(instance, "#state").(*RawVal[*PromiseState])
+	if !ok {
+		return nil
+	}
+	return state.value
+}
+
+// creates a pending instance of the stdlib Promise class
+func NewPromise(env *Environment, r *Interpreter, pos Pos) (*Instance, *PromiseState) {
+	if stdEnv != nil && stdEnv.variables.has("Promise") {
+		executor := MK_MACRO("executor", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return undefined
+		})
+		instance := r.Instantiate(stdEnv.variables.get("Promise"), stdEnv, []RuntimeVal{executor}, pos)
+		if state := promiseOf(instance); state != nil {
+			return instance, state
 		}
-		return value
+	}
+	// the stdlib has not declared the class (yet)
+	state := &PromiseState{state: PENDING, value: undefined}
+	ml := Memory.alloc(MK_RAW(state))
+	props := NewMap[RuntimeVal, Ref]()
+	props.set(MK_STRING("#state"), ml)
+	return MK_INSTANCE("Promise", nil, props, GetUDRef(r), env, r), state
+}
+
+// runs eval right away and returns a promise of its result:
+// fulfilled with the value it returns, or rejected with the value it throws
+func (r *Interpreter) AsyncCall(eval func() RuntimeVal, env *Environment, pos Pos) *Instance {
+	promise, state := NewPromise(env, r, pos)
+	value, err := r.guard(eval)
+	if err != nil {
+		state.settle(REJECTED, err.(*ThrownError).value, r, env, pos)
+	} else {
+		state.resolve(value, r, env, pos)
+	}
+	return promise
+}
+
+// queues a native callback as a micro task
+func (r *Interpreter) queueJob(job func(), env *Environment, pos Pos) {
+	r.microTaskQueue.queueMicroTask(Task{
+		macro: MK_MACRO("#_job", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			job()
+			return undefined
+		}),
+		env: env,
+		pos: pos,
+		r:   r,
 	})
+}
 
-	var class *NativeClass
-	var instance *Instance
-
-	props := NewMap[string, string]()
-	// then
-	thenCallback := GenerateRadix(16)
-	Memory.set(thenCallback, undefined)
-	props.set("thenCallback", thenCallback)
-	// catch
-	catchCallback := GenerateRadix(16)
-	Memory.set(catchCallback, undefined)
-	props.set("catchCallback", catchCallback)
-	// finally
-	finallyCallback := GenerateRadix(16)
-	Memory.set(finallyCallback, undefined)
-	props.set("finallyCallback", finallyCallback)
-
-	resolve := MK_MACRO(
-		"resolve",
-		func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-			var value RuntimeVal
-			if len(args) < 2 {
-				return undefined
-			}
-			value = args[0]
-			promise, ok := args[1].(*Instance)
-			if ok {
-				then := GetInstanceMember(promise, "thenCallback")
-				args := []RuntimeVal{value}
-				switch then := then.(type) {
-				case *Macro:
-					then.call(args, env, pos, r)
-				case *FunctionVal:
-					then.Call(env, args, r, pos)
-				}
+// runs reaction (as a micro task) once the promise is settled
+func (p *PromiseState) subscribe(reaction func(), r *Interpreter, env *Environment, pos Pos) {
+	p.handled = true
+	if p.state == PENDING {
+		p.reactions = append(p.reactions, reaction)
+		return
+	}
+	r.queueJob(reaction, env, pos)
+}
+
+// fulfills or rejects the promise, it can only be settled once
+func (p *PromiseState) settle(state string, value RuntimeVal, r *Interpreter, env *Environment, pos Pos) {
+	if p.state != PENDING {
+		return
+	}
+	p.state, p.value = state, value
+	if state == REJECTED && !p.handled {
+		eventLoop.rejections = append(eventLoop.rejections, p)
+	}
+	for _, reaction := range p.reactions {
+		r.queueJob(reaction, env, pos)
+	}
+	p.reactions = nil
+}
+
+// fulfills the promise with value, or makes it follow value when it is a promise or a thenable
+func (p *PromiseState) resolve(value RuntimeVal, r *Interpreter, env *Environment, pos Pos) {
+	if p.state != PENDING {
+		return
+	}
+	if other := promiseOf(value); other != nil {
+		if other == p {
+			p.settle(REJECTED, NewErrorValue("TypeError", "a promise cannot be resolved with itself", "", env, r), r, env, pos)
+			return
+		}
+		other.subscribe(func() {
+			p.settle(other.state, other.value, r, env, pos)
+		}, r, env, pos)
+		return
+	}
+	var then RuntimeVal = undefined
+	switch v := value.(type) {
+	case *Instance:
+		then = GetInstanceMember(v, "then")
+	case *ObjectVal:
+		if ml := v.properties.get(MK_STRING("then")); ml != nil {
+			then = Memory.get(ml)
+		}
+	}
+	if is_value(ValueType(then), "function", "macro") {
+		r.queueJob(func() {
+			resolve, reject := p.resolvers()
+			if _, err := r.SafeCall(then, env, []RuntimeVal{resolve, reject}, pos); err != nil {
+				reject.call([]RuntimeVal{err.(*ThrownError).value}, env, pos, r)
 			}
-			return value
-		},
-	)
-	then := MK_MACRO("then", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		var callback RuntimeVal
-		args_len := len(args)
-		if args_len < 1 {
-			env.throwError([]string{"Promise.then expects 1 argument (callback), but it was given", fmt.Sprint(args_len), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
-			return undefined
+		}, env, pos)
+		return
+	}
+	p.settle(FULFILLED, value, r, env, pos)
+}
+
+// the resolve and reject functions given to an executor, only the first call of either counts
+func (p *PromiseState) resolvers() (*Macro, *Macro) {
+	done := false
+	resolve := MK_MACRO("resolve", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		if !done {
+			done = true
+			p.resolve(argAt(args, 0), r, env, pos)
 		}
-		callback = args[0]
-		Memory.set(thenCallback, callback)
-		return instance
+		return undefined
 	})
-	catch := MK_MACRO("catch", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		var callback RuntimeVal
-		args_len := len(args)
-		if args_len < 2 {
-			callback = undefined
-			return callback
+	reject := MK_MACRO("reject", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		if !done {
+			done = true
+			p.settle(REJECTED, argAt(args, 0), r, env, pos)
 		}
-		callback = args[0]
-		promise, ok := args[1].(*Instance)
-		if !ok {
-			env.throwError([]string{"Promise.catch expects 2 arguments (callback, Promise), but it was given", fmt.Sprint(args_len), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
-		}
-		Memory.set(catchCallback, callback)
-		return promise
+		return undefined
 	})
-	finally := MK_MACRO("finally", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		var callback RuntimeVal
-		args_len := len(args)
-		if args_len < 2 {
-			callback = undefined
-			return callback
+	return resolve, reject
+}
+
+// returns a promise settled by onFulfilled or onRejected,
+// a callback that is not a function passes the result on
+func (p *PromiseState) then(onFulfilled, onRejected RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	promise, derived := NewPromise(env, r, pos)
+	p.subscribe(func() {
+		callback := onFulfilled
+		if p.state == REJECTED {
+			callback = onRejected
 		}
-		callback = args[0]
-		promise, ok := args[1].(*Instance)
-		if !ok {
-			env.throwError([]string{"Promise.finally expects 2 arguments (callback, Promise), but it was given", fmt.Sprint(args_len), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		if !is_value(ValueType(callback), "function", "macro") {
+			derived.settle(p.state, p.value, r, env, pos)
+			return
 		}
-		Memory.set(finallyCallback, callback)
-		return promise
-	})
-	methods := []*Macro{
-		resolve, then, catch, finally,
+		value, err := r.SafeCall(callback, env, []RuntimeVal{p.value}, pos)
+		if err != nil {
+			derived.settle(REJECTED, err.(*ThrownError).value, r, env, pos)
+			return
+		}
+		derived.resolve(value, r, env, pos)
+	}, r, env, pos)
+	return promise
+}
+
+// returns a promise settled like p once onFinally ran (and the promise it returned settled),
+// unless onFinally throws or returns a rejected promise
+func (p *PromiseState) finally(onFinally RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	promise, derived := NewPromise(env, r, pos)
+	p.subscribe(func() {
+		if is_value(ValueType(onFinally), "function", "macro") {
+			value, err := r.SafeCall(onFinally, env, []RuntimeVal{}, pos)
+			if err != nil {
+				derived.settle(REJECTED, err.(*ThrownError).value, r, env, pos)
+				return
+			}
+			if other := promiseOf(value); other != nil {
+				other.subscribe(func() {
+					if other.state == REJECTED {
+						derived.settle(REJECTED, other.value, r, env, pos)
+					} else {
+						derived.settle(p.state, p.value, r, env, pos)
+					}
+				}, r, env, pos)
+				return
+			}
+		}
+		derived.settle(p.state, p.value, r, env, pos)
+	}, r, env, pos)
+	return promise
+}
+
+// turns the elements of an array into promises, for the Promise combinators
+func promisesOf(iterable RuntimeVal, name string, r *Interpreter, env *Environment, pos Pos) []*PromiseState {
+	array, ok := iterable.(*ArrayVal)
+	if !ok {
+		env.ThrowTypeError(name+": argument of type", ValueType(iterable), "is not an array", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 	}
-	constructor := MK_MACRO("constructor", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		if len(args) == 0 {
-			env.throwError([]string{"Promise expects one argument, but it was given none", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+	states := []*PromiseState{}
+	for i := 0; i < array.elements.length; i++ {
+		value := array.get(i)
+		state := promiseOf(value)
+		if state == nil {
+			_, state = NewPromise(env, r, pos)
+			state.resolve(value, r, env, pos)
 		}
-		callback := args[0]
-		vt := ValueType(callback)
-		if !is_value(vt, "function", "macro") {
-			env.throwError([]string{"Promise expects an argument of type 'function', but it was given one of", vt, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		states = append(states, state)
+	}
+	return states
+}
+
+// fulfills with the values of all the promises, or rejects with the first rejection
+func PromiseAll(iterable RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	states := promisesOf(iterable, "Promise.all", r, env, pos)
+	promise, result := NewPromise(env, r, pos)
+	values := make([]RuntimeVal, len(states))
+	remaining := len(states)
+	if remaining == 0 {
+		result.resolve(MK_ARRAY(), r, env, pos)
+	}
+	for i, state := range states {
+		state.subscribe(func() {
+			if state.state == REJECTED {
+				result.settle(REJECTED, state.value, r, env, pos)
+				return
+			}
+			values[i] = state.value
+			remaining--
+			if remaining == 0 {
+				result.resolve(MK_ARRAY(values...), r, env, pos)
+			}
+		}, r, env, pos)
+	}
+	return promise
+}
+
+// settles like the first of the promises to settle
+func PromiseRace(iterable RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	states := promisesOf(iterable, "Promise.race", r, env, pos)
+	promise, result := NewPromise(env, r, pos)
+	for _, state := range states {
+		state.subscribe(func() {
+			result.settle(state.state, state.value, r, env, pos)
+		}, r, env, pos)
+	}
+	return promise
+}
+
+// fulfills with the first fulfilled promise, or rejects with an AggregateError of all the rejections
+func PromiseAny(iterable RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	states := promisesOf(iterable, "Promise.any", r, env, pos)
+	promise, result := NewPromise(env, r, pos)
+	reasons := make([]RuntimeVal, len(states))
+	remaining := len(states)
+	rejectAll := func() {
+		errors := MK_ARRAY(reasons...)
+		message := "all promises were rejected"
+		var err RuntimeVal
+		if stdEnv != nil && stdEnv.variables.has("AggregateError") {
+			err = r.Instantiate(stdEnv.variables.get("AggregateError"), stdEnv, []RuntimeVal{errors, MK_STRING(message)}, pos)
+		} else {
+			err = NewErrorValue("AggregateError", message, "", env, r)
 		}
-		r.microTaskQueue.queueMicroTask(Task{
-			macro: PromiseExecutorWrapper,
-			args:  []RuntimeVal{callback, instance},
-			env:   declEnv,
-			pos:   pos,
-			r:     r,
-		})
-		return undefined
-	})
-	class = MK_NT_CLASS("Promise", constructor, props, methods, declEnv, "")
-	Memory.set(promise_mem_loc, class)
-	instance_props := NewMap[RuntimeVal, string]()
-	class.properties.forEach(func(key, value string) {
-		instance_props.set(MK_STRING(key), value)
-	})
-	for _, method := range class.methods {
-		ml := GenerateRadix(16)
-		Memory.set(ml, method)
-		instance_props.set(MK_STRING(method.name), ml)
+		result.settle(REJECTED, err, r, env, pos)
+	}
+	if remaining == 0 {
+		rejectAll()
+	}
+	for i, state := range states {
+		state.subscribe(func() {
+			if state.state == FULFILLED {
+				result.settle(FULFILLED, state.value, r, env, pos)
+				return
+			}
+			reasons[i] = state.value
+			remaining--
+			if remaining == 0 {
+				rejectAll()
+			}
+		}, r, env, pos)
+	}
+	return promise
+}
+
+// fulfills once all the promises settled, with objects describing each outcome
+func PromiseAllSettled(iterable RuntimeVal, r *Interpreter, env *Environment, pos Pos) *Instance {
+	states := promisesOf(iterable, "Promise.allSettled", r, env, pos)
+	promise, result := NewPromise(env, r, pos)
+	outcomes := make([]RuntimeVal, len(states))
+	remaining := len(states)
+	if remaining == 0 {
+		result.resolve(MK_ARRAY(), r, env, pos)
+	}
+	for i, state := range states {
+		state.subscribe(func() {
+			key := "value"
+			if state.state == REJECTED {
+				key = "reason"
+			}
+			props := NewMap[RuntimeVal, Ref]()
+			for _, prop := range []struct {
+				key   string
+				value RuntimeVal
+			}{{"status", MK_STRING(state.state)}, {key, state.value}} {
+				ml := Memory.alloc(prop.value)
+				props.set(MK_STRING(prop.key), ml)
+			}
+			outcomes[i] = MK_OBJECT(props, nil, r)
+			remaining--
+			if remaining == 0 {
+				result.resolve(MK_ARRAY(outcomes...), r, env, pos)
+			}
+		}, r, env, pos)
+	}
+	return promise
+}
+
+// prints the rejected promises that never got a callback, once the event loop ran out of work
+func (l *EventLoop) ReportUnhandledRejections() {
+	for _, state := range l.rejections {
+		if !state.handled {
+			printUncaught("Uncaught (in promise)", state.value)
+			failure = true
+		}
+	}
+	l.rejections = nil
+}
+
+// returns args[index], or undefined
+func argAt(args []RuntimeVal, index int) RuntimeVal {
+	if index < len(args) {
+		return args[index]
 	}
-	instance = MK_INSTANCE(class.name, promise_mem_loc, instance_props, GetUDRef(r), declEnv, r)
-	class.ctor.call([]RuntimeVal{fn}, declEnv, pos, r)
-	return instance
+	return undefined
 }
 
-func Instantiate(ml string, args []RuntimeVal, env *Environment, r *Interpreter, pos Pos) *Instance {
+func Instantiate(ml Ref, args []RuntimeVal, env *Environment, r *Interpreter, pos Pos) *Instance {
 	class, ok := Memory.get(ml).(*NativeClass)
-	props := NewMap[RuntimeVal, string]()
+	props := NewMap[RuntimeVal, Ref]()
 	if ok {
-		var _default string = GetUDRef(r)
-		class.properties.forEach(func(key, value string) {
+		var _default Ref = GetUDRef(r)
+		class.properties.forEach(func(key string, value Ref) {
 			props.set(MK_STRING(key), value)
 			_default = value
 		})
 		for _, method := range class.methods {
-			ml := GenerateRadix(16)
-			Memory.set(ml, method)
+			ml := Memory.alloc(method)
 			props.set(MK_STRING(method.name), ml)
 		}
 		class.ctor.call(args, env, pos, r)
@@ -220,10 +407,10 @@ func Instantiate(ml string, args []RuntimeVal, env *Environment, r *Interpreter,
 func GetInstanceMember(class *Instance, member string) RuntimeVal {
 	prop := MK_STRING(member)
 	ml := class.properties.get(prop)
-	if len(ml) == 0 {
+	if ml == nil {
 		p := class.prototype
 		ml = GetPropMlFromProto(prop, p)
-		if len(ml) == 0 {
+		if ml == nil {
 			return MK_UD()
 		}
 	}
@@ -0,0 +1,190 @@
+package main
+
+import "strings"
+
+// the name of a function declaration, a computed name is evaluated in env
+func (r *Interpreter) functionName(decl *FunctionDecl, env *Environment) string {
+	if decl.name.dynamic {
+		return r.Evaluate(decl.name.node, env).noAnsi()
+	} else if !decl.anonymous {
+		// always identifier
+		return decl.name.node.(*Identifier).Symbol
+	}
+	return ""
+}
+
+// stores a method declared in the class body env into props under name and returns
+// its reference; a getter and a setter of the same name share one Accessor
+func (r *Interpreter) defineMethod(props *Map[RuntimeVal, Ref], method *ClassMethod, name string, env *Environment) Ref {
+	decl := method.decl
+	fn := MK_FUNCTION(name, decl.body, decl.params, env, decl.async, false, false, r)
+	fn.generator = decl.generator
+	key := MK_STRING(name)
+	if method.kind == "" {
+		ml := Memory.alloc(fn)
+		props.set(key, ml)
+		return ml
+	}
+	ml := props.get(key)
+	accessor, ok := Memory.get(ml).(*Accessor)
+	if !ok {
+		accessor = &Accessor{name: name}
+		ml = Memory.alloc(accessor)
+		props.set(key, ml)
+	}
+	if method.kind == "get" {
+		accessor.get = fn
+	} else {
+		accessor.set = fn
+	}
+	return ml
+}
+
+// evaluates the static members of a class in the order they are declared, in a
+// body of their own where this is the class
+func (r *Interpreter) EvalStatics(class *ClassVal, statics []Node, env *Environment) {
+	body := NewEnv(env, "object", env.sourcePath)
+	body.class = class
+	body.DeclareVar("this", class, "constant", 0, 0, 0, env.sourcePath, r)
+	class.staticPrivate = map[string]bool{}
+	for _, member := range statics {
+		switch member := member.(type) {
+		case *ClassProperty:
+			if member.private {
+				class.staticPrivate[member.name] = true
+			}
+			value := r.Evaluate(member.value, body)
+			class.properties.set(MK_STRING(member.name), Memory.alloc(value))
+		case *ClassMethod:
+			name := r.functionName(&member.decl, body)
+			if member.private {
+				class.staticPrivate[name] = true
+			}
+			r.defineMethod(class.properties, member, name, body)
+		case *StaticBlock:
+			block := MK_FUNCTION("static", member.body, []Node{}, body, false, false, false, r)
+			block.Call(body, []RuntimeVal{}, r, member.Pos)
+		}
+	}
+}
+
+// the value of the member at ml, the getter of an accessor is called for it
+func (r *Interpreter) memberValue(ml Ref, env *Environment, pos Pos) RuntimeVal {
+	value := Memory.get(ml)
+	if value == nil {
+		return undefined
+	}
+	if accessor, ok := value.(*Accessor); ok {
+		if accessor.get == nil {
+			return undefined
+		}
+		return accessor.get.Call(env, []RuntimeVal{}, r, pos)
+	}
+	return value
+}
+
+// stores value in the member at ml, the setter of an accessor is called with it
+func (r *Interpreter) setMember(ml Ref, value RuntimeVal, env *Environment, pos Pos) {
+	accessor, ok := Memory.get(ml).(*Accessor)
+	if !ok {
+		Memory.set(ml, value)
+		return
+	}
+	if accessor.set == nil {
+		env.ThrowTypeError("cannot set", accessor.name+",", "it only has a getter", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	accessor.set.Call(env, []RuntimeVal{value}, r, pos)
+}
+
+// throws when prop is a private member of owner and env is not inside the body of owner,
+// members whose name starts with # are always private
+func (r *Interpreter) checkPrivate(prop RuntimeVal, owner *ClassVal, static bool, env *Environment, pos Pos) {
+	key, ok := prop.(*StringVal)
+	if !ok || owner == nil {
+		return
+	}
+	private := owner.private
+	if static {
+		private = owner.staticPrivate
+	}
+	if !strings.HasPrefix(key.value, "#") && !private[key.value] {
+		return
+	}
+	for e := env; e != nil; e = e.parent {
+		// class values are copied when passed around, their objects are not
+		if e.class != nil && e.class.ObjectVal == owner.ObjectVal {
+			return
+		}
+	}
+	name := owner.name
+	if name == "" || name[0] == '$' {
+		name = "(anonymous)"
+	}
+	env.ThrowTypeError("cannot access private member", key.value, "from outside of class", name, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+}
+
+// looks prop up in the prototype chain that starts at proto, nil when no object of it has prop
+func (r *Interpreter) protoRef(prop RuntimeVal, proto RuntimeVal, env *Environment, pos Pos) Ref {
+	for {
+		object, ok := proto.(*ObjectVal)
+		if !ok {
+			return nil
+		}
+		if ml := object.properties.get(prop); ml != nil {
+			if object.body_env != nil {
+				r.checkPrivate(prop, object.body_env.class, false, env, pos)
+			}
+			return ml
+		}
+		proto = object.prototype
+	}
+}
+
+// looks prop up in the static members of class, then in those of the classes it extends
+func (r *Interpreter) staticRef(class *ClassVal, prop RuntimeVal, env *Environment, pos Pos) Ref {
+	for class != nil {
+		if ml := class.properties.get(prop); ml != nil {
+			r.checkPrivate(prop, class, true, env, pos)
+			return ml
+		}
+		if class.extends == nil {
+			return nil
+		}
+		class, _ = Memory.get(class.extends).(*ClassVal)
+	}
+	return nil
+}
+
+// the reference of super.prop: prop is looked up from the base class of the class
+// whose body the code that runs is in
+func (r *Interpreter) superRef(expr *MemberExpr, env *Environment) Ref {
+	pos := getPosFromNode(expr.object)
+	body := env
+	for body != nil && body.class == nil {
+		body = body.parent
+	}
+	if body == nil {
+		env.ThrowSyntaxError("super can only be used in the body of a class", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	computed_property, property := GetMemberExprProp(expr, r, env)
+	var prop RuntimeVal = MK_STRING(property)
+	if expr.computed {
+		prop = computed_property
+	}
+	// this is the instance of the class whose body it is, which is
+	// linked to the instance of its base class by CallSuper
+	if this, ok := Memory.get(body.variables.get("this")).(*Instance); ok {
+		return r.protoRef(prop, this.prototype.(*ObjectVal).prototype, env, pos)
+	}
+	if body.class.extends == nil {
+		return nil
+	}
+	base, _ := Memory.get(body.class.extends).(*ClassVal)
+	return r.staticRef(base, prop, env, pos)
+}
+
+// super on its own is neither a call nor a member access
+func (r *Interpreter) Eval_super_ref(node *SuperRef, env *Environment) RuntimeVal {
+	env.ThrowSyntaxError("super must be followed by an argument list or a member access", SourceLog(node.line, node.col, node.count, env.sourcePath, ""))
+	return undefined
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const version = "0.1"
+
+const usage = `ArachnoScript Runtime Environment v` + version + `
+
+usage: are [flags] <command> [arguments]
+       are [flags] <script> [arguments]
+
+commands:
+  run <script> [arguments]   runs a script, the arguments are passed to runtime.args
+  repl                       starts the REPL (the default without arguments)
+  check <files...>           parses the files and reports syntax errors
+  fmt [-w] <files...>        re-indents the files, -w writes them back instead of printing them
+  test [paths...]            runs the *.test.as files found in the paths (default: .)
+  ast <file>                 prints the syntax tree of a file
+  tokens <file>              prints the tokens of a file
+
+flags:
+  --vm                       runs blocks on the bytecode VM
+  --stdlib <dir>             loads the standard library from dir instead of the one built in
+  --mem <MB>                 heap in use above which memory is given back to the OS (default: 100)
+  -h, --help                 prints this message
+  -v, --version              prints the version
+`
+
+// exit codes
+const (
+	EXIT_OK    = 0
+	EXIT_ERROR = 1
+	EXIT_USAGE = 2
+)
+
+// the command the runtime was started with, see runtime.command
+var command = "repl"
+
+// where the standard library is loaded from, set by --stdlib
+var stdlibDir = ""
+
+// heap in use above which Memory.collect gives freed memory back to the OS, set by --mem
+var memThreshold uint64 = 100 * 1024 * 1024 // 100MB
+
+func usageError(message string) {
+	fmt.Fprintln(os.Stderr, "\x1b[31mError\x1b[0m: "+message)
+	fmt.Fprintln(os.Stderr, "run 'are --help' for usage.")
+	os.Exit(EXIT_USAGE)
+}
+
+// parses the flags and the command, what is left of args is
+// given to the command
+func RunCLI(args []string) {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		flag, value, has_value := strings.Cut(args[0], "=")
+		args = args[1:]
+		// takes the value of a flag, either after = or the next argument
+		takeValue := func() string {
+			if has_value {
+				return value
+			}
+			if len(args) == 0 {
+				usageError("flag " + flag + " expects a value")
+			}
+			value = args[0]
+			args = args[1:]
+			return value
+		}
+		switch flag {
+		case "--":
+			goto command
+		case "-h", "--help":
+			print(usage)
+			os.Exit(EXIT_OK)
+		case "-v", "--version":
+			println("are v" + version)
+			os.Exit(EXIT_OK)
+		case "--vm":
+			useVM = true
+		case "--stdlib":
+			stdlibDir = takeValue()
+		case "--mem":
+			mb, err := strconv.ParseUint(takeValue(), 10, 64)
+			if err != nil || mb == 0 {
+				usageError("flag --mem expects a positive number of megabytes")
+			}
+			memThreshold = mb * 1024 * 1024
+		default:
+			usageError("unknown flag: " + flag)
+		}
+	}
+command:
+	if len(args) == 0 {
+		args = []string{"repl"}
+	}
+	command, args = args[0], args[1:]
+	switch command {
+	case "run":
+		if len(args) == 0 {
+			usageError("run expects a script")
+		}
+		arguments = args
+		RunSTD()
+	case "repl":
+		arguments = []string{}
+		RunSTD()
+	case "test":
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		arguments = args
+		RunSTD()
+	case "check":
+		if len(args) == 0 {
+			usageError("check expects at least 1 file")
+		}
+		for _, path := range args {
+			// a syntax error exits with EXIT_ERROR
+			NewParser(existingPath(path), "program", "", Tokenize).Parse(true)
+		}
+	case "fmt":
+		write := len(args) > 0 && args[0] == "-w"
+		if write {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			usageError("fmt expects at least 1 file")
+		}
+		for _, path := range args {
+			path = existingPath(path)
+			formatted := Format(ReadTextFile(path), path)
+			if !write {
+				print(formatted)
+				continue
+			}
+			if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+				throwMessage("\x1b[35mError\x1b[0m: " + err.Error())
+			}
+		}
+	case "ast":
+		if len(args) != 1 {
+			usageError("ast expects 1 file")
+		}
+		println(NewParser(existingPath(args[0]), "program", "", Tokenize).Parse(true).String())
+	case "tokens":
+		if len(args) != 1 {
+			usageError("tokens expects 1 file")
+		}
+		path := existingPath(args[0])
+		tokens := Tokenize(ReadTextFile(path), path)
+		for _, token := range tokens.elements {
+			fmt.Printf("%d:%d\t%s\t%q\n", token.line, token.col, token.typ, token.src)
+		}
+	default:
+		if !strings.HasSuffix(command, ".as") && !pathExists(command) {
+			usageError("unknown command: " + command)
+		}
+		// are <script> [arguments]
+		arguments = append([]string{command}, args...)
+		command = "run"
+		RunSTD()
+	}
+	if failure {
+		os.Exit(EXIT_ERROR)
+	}
+	os.Exit(EXIT_OK)
+}
+
+// returns the absolute path of a file that exists
+func existingPath(path string) string {
+	path = AbsPath(path)
+	if !pathExists(path) {
+		throwMessage("path: \x1b[31m" + path + "\x1b[0m; does not exist")
+	}
+	return path
+}
+
+// runs every *.test.as file found in paths in its own runtime, a file
+// passes when nothing it throws is left uncaught and no rejection unhandled
+func RunTests(paths []string) {
+	files := []string{}
+	for _, path := range paths {
+		path = existingPath(path)
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && strings.HasSuffix(file, ".test.as") {
+				files = append(files, file)
+			}
+			return nil
+		})
+	}
+	failed := 0
+	for _, file := range files {
+		start := time.Now()
+		program := NewParser(file, "program", "", Tokenize).Parse(true)
+		runtime := NewRuntime()
+		failure = false
+		_, err := runtime.Exec(program, NewEnv(stdEnv, "program", file))
+		name := RelativePath(".", file)
+		if err != nil || failure {
+			failed++
+			println("\x1b[31mFAIL\x1b[0m " + name)
+			if err != nil {
+				PrintUncaught(err)
+			}
+			continue
+		}
+		fmt.Printf("\x1b[32mok\x1b[0m   %s (%s)\n", name, time.Since(start).Round(time.Millisecond))
+	}
+	fmt.Printf("%d passed, %d failed\n", len(files)-failed, failed)
+	failure = failed > 0
+}
+
+// Format re-indents source by two spaces per open brace, bracket or
+// parenthesis, trims trailing white space and collapses runs of blank lines.
+// the lines are read through Tokenize, so strings, template literals, regular
+// expressions and comments are left as they are and their brackets not counted.
+func Format(source string, path string) string {
+	newline := "\n"
+	if strings.Contains(source, "\r\n") {
+		newline = "\r\n"
+	}
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	lines := strings.Split(source, "\n")
+	// the offsets at which the lines start
+	starts := make([]int, len(lines))
+	for line := 1; line < len(lines); line++ {
+		starts[line] = starts[line-1] + len(lines[line-1]) + 1
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(starts, offset+1) - 1
+	}
+	// a line that starts inside a literal or a comment is kept as it is
+	kept := make([]bool, len(lines)+1)
+	keep := func(offset int, length int) {
+		for line := lineOf(offset) + 1; line < len(lines) && starts[line] < offset+length; line++ {
+			kept[line] = true
+		}
+	}
+	// the brackets each line opens and closes, and how many of those it starts with
+	opened := make([]int, len(lines))
+	leading := make([]int, len(lines))
+	// whether something other than a closing bracket was seen on a line
+	begun := make([]bool, len(lines))
+	tokens := Tokenize(source, path)
+	previous := 0
+	for i := uint(0); i < tokens.length; i++ {
+		token := tokens.at(i)
+		for _, comment := range token.comments {
+			offset := previous + strings.Index(source[previous:token.offset], comment)
+			keep(offset, len(comment))
+			begun[lineOf(offset)] = true
+			previous = offset + len(comment)
+		}
+		if token.typ == TokenType["EOF"] {
+			break
+		}
+		keep(token.offset, token.length)
+		line := lineOf(token.offset)
+		switch token.typ {
+		case TokenType["OpenBrace"], TokenType["OpenBracket"], TokenType["OpenParen"]:
+			opened[line]++
+			begun[line] = true
+		case TokenType["CloseBrace"], TokenType["CloseBracket"], TokenType["CloseParen"]:
+			opened[line]--
+			if !begun[line] {
+				leading[line]++
+			}
+		default:
+			begun[line] = true
+		}
+		previous = token.offset + token.length
+	}
+	out := []string{}
+	depth := 0
+	for i, line := range lines {
+		indent := depth - leading[i]
+		depth = max(depth+opened[i], 0)
+		if kept[i] {
+			out = append(out, line)
+			continue
+		}
+		line = strings.TrimLeft(line, " \t")
+		if !kept[i+1] {
+			// trailing white space of a literal that is still open is part of it
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" {
+			if len(out) > 0 && out[len(out)-1] != "" {
+				out = append(out, "")
+			}
+			continue
+		}
+		out = append(out, strings.Repeat("  ", max(indent, 0))+line)
+	}
+	for len(out) > 0 && out[len(out)-1] == "" {
+		out = out[:len(out)-1]
+	}
+	return strings.Join(out, newline) + newline
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"weak"
+)
+
+// CollectionState is the native side of an instance of the stdlib Map, Set,
+// WeakMap and WeakSet classes, stored in its #state field.
+//
+// the entries are linked in the order they were added, an entry that is deleted
+// keeps its next link so that iterators standing on it carry on after it
+type CollectionState struct {
+	mu      sync.Mutex
+	kind    string
+	entries map[any]*entry
+	first   *entry
+	last    *entry
+	// what the keys of a weak collection store its values under
+	self weak.Pointer[CollectionState]
+}
+
+type entry struct {
+	// key and value are nil in weak collections, which must not hold their keys
+	key     RuntimeVal
+	value   RuntimeVal
+	next    *entry
+	prev    *entry
+	deleted bool
+}
+
+// IteratorState is the native side of the iterators returned by keys, values
+// and entries, stored in their #state field.
+type IteratorState struct {
+	collection *CollectionState
+	kind       string
+	// the entry returned last, nil before the first one
+	at   *entry
+	done bool
+}
+
+// the values of weak collections are held by their keys, not by the collections, so a
+// value that refers to its key does not keep it: the two go once nothing else refers to
+// the key, or the value once the collection is gone
+type weakValues map[weak.Pointer[CollectionState]]RuntimeVal
+
+// guards the weakValues of every object, cleanups change them on a goroutine of their own
+var weakValuesMu sync.Mutex
+
+func NewCollectionState(kind string) *CollectionState {
+	state := &CollectionState{kind: kind, entries: map[any]*entry{}}
+	if state.weak() {
+		state.self = weak.Make(state)
+		runtime.AddCleanup(state, dropWeakValues, weakEntries{state.self, state.entries})
+	}
+	return state
+}
+
+// the argument of the cleanups of weak collections and their keys, which must not hold the collection
+type weakEntries struct {
+	state   weak.Pointer[CollectionState]
+	entries map[any]*entry
+}
+
+type weakEntry struct {
+	state weak.Pointer[CollectionState]
+	key   any
+}
+
+// removes the values a weak collection that was collected left in the keys that outlive it
+func dropWeakValues(collection weakEntries) {
+	weakValuesMu.Lock()
+	defer weakValuesMu.Unlock()
+	for key := range collection.entries {
+		if values := weakValuesOf(key); values != nil {
+			delete(*values, collection.state)
+		}
+	}
+}
+
+// deletes the entry of a key that was collected, unless the collection went first
+func removeWeakEntry(e weakEntry) {
+	if state := e.state.Value(); state != nil {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		state.remove(e.key)
+	}
+}
+
+// reports whether the keys of the collection are held weakly
+func (state *CollectionState) weak() bool {
+	return strings.HasPrefix(state.kind, "Weak")
+}
+
+// reports whether the collection stores values, which are their own keys
+func (state *CollectionState) set() bool {
+	return strings.HasSuffix(state.kind, "Set")
+}
+
+// the key value is stored under, values that are the same (SameValueZero) share one:
+// primitives are compared by value and NaN is the same as NaN, anything else by identity
+func sameValueKey(value RuntimeVal) any {
+	type primitive struct {
+		kind  string
+		value any
+	}
+	switch v := value.(type) {
+	case *NumberVal:
+		if math.IsNaN(v.value) {
+			return primitive{"number", "NaN"}
+		}
+		// -0 is the same as 0
+		return primitive{"number", v.value + 0}
+	case *BigIntVal:
+		return primitive{"bigint", v.value.String()}
+	case *StringVal:
+		return primitive{"string", v.value}
+	case *BoolVal:
+		return primitive{"boolean", v.value}
+	case *NullVal, *Undefined:
+		return primitive{ValueType(v), nil}
+	}
+	return identity(value)
+}
+
+// the object value refers to, copies of instances, functions and classes share it
+func identity(value RuntimeVal) any {
+	switch v := value.(type) {
+	case *Instance:
+		return v.ObjectVal
+	case *FunctionVal:
+		return v.ObjectVal
+	case *ClassVal:
+		return v.ObjectVal
+	}
+	return value
+}
+
+// the key of value in a weak collection, ok is false for values that are not objects
+func weakKey(value RuntimeVal) (any, bool) {
+	switch id := identity(value).(type) {
+	case *ObjectVal:
+		return weak.Make(id), true
+	case *ArrayVal:
+		return weak.Make(id), true
+	}
+	return nil, false
+}
+
+// the key value is stored under, throws when a weak collection is given a value that is not an object
+func (state *CollectionState) keyOf(value RuntimeVal, env *Environment, pos Pos) any {
+	if !state.weak() {
+		return sameValueKey(value)
+	}
+	key, ok := weakKey(value)
+	if !ok {
+		env.ThrowTypeError("type", ValueType(value), "cannot be used as a key of a "+state.kind+", only objects can", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return key
+}
+
+// the weak values of the object a weak key refers to, nil once it was collected.
+// the caller holds weakValuesMu
+func weakValuesOf(key any) *weakValues {
+	switch p := key.(type) {
+	case weak.Pointer[ObjectVal]:
+		if obj := p.Value(); obj != nil {
+			return &obj.weakValues
+		}
+	case weak.Pointer[ArrayVal]:
+		if arr := p.Value(); arr != nil {
+			return &arr.weakValues
+		}
+	}
+	return nil
+}
+
+// the value the object of a weak key holds for the collection
+func (state *CollectionState) weakValue(key any) RuntimeVal {
+	weakValuesMu.Lock()
+	defer weakValuesMu.Unlock()
+	if values := weakValuesOf(key); values != nil {
+		if value, ok := (*values)[state.self]; ok {
+			return value
+		}
+	}
+	return undefined
+}
+
+// stores the value of a weak key in the object it refers to, a nil value deletes it
+func (state *CollectionState) setWeakValue(key any, value RuntimeVal) {
+	weakValuesMu.Lock()
+	defer weakValuesMu.Unlock()
+	values := weakValuesOf(key)
+	switch {
+	case values == nil:
+	case value == nil:
+		delete(*values, state.self)
+	case *values == nil:
+		*values = weakValues{state.self: value}
+	default:
+		(*values)[state.self] = value
+	}
+}
+
+func (state *CollectionState) get(value RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	key := state.keyOf(value, env, pos)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if e, ok := state.entries[key]; ok {
+		if state.weak() {
+			return state.weakValue(key)
+		}
+		return e.value
+	}
+	return undefined
+}
+
+func (state *CollectionState) has(value RuntimeVal, env *Environment, pos Pos) bool {
+	if state.weak() {
+		// a value that is not an object is never in a weak collection
+		if _, ok := weakKey(value); !ok {
+			return false
+		}
+	}
+	key := state.keyOf(value, env, pos)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	_, ok := state.entries[key]
+	return ok
+}
+
+// adds an entry for value, or replaces the value of the one it has
+func (state *CollectionState) put(value, entryValue RuntimeVal, env *Environment, pos Pos) {
+	key := state.keyOf(value, env, pos)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if e, ok := state.entries[key]; ok {
+		if state.weak() {
+			state.setWeakValue(key, entryValue)
+		} else {
+			e.value = entryValue
+		}
+		return
+	}
+	e := &entry{key: value, value: entryValue, prev: state.last}
+	if state.weak() {
+		e.key, e.value = nil, nil
+		state.setWeakValue(key, entryValue)
+		state.collect(value, key)
+	}
+	if state.last != nil {
+		state.last.next = e
+	} else {
+		state.first = e
+	}
+	state.last = e
+	state.entries[key] = e
+}
+
+// deletes the entry of key once the object value refers to is collected
+func (state *CollectionState) collect(value RuntimeVal, key any) {
+	switch id := identity(value).(type) {
+	case *ObjectVal:
+		runtime.AddCleanup(id, removeWeakEntry, weakEntry{state.self, key})
+	case *ArrayVal:
+		runtime.AddCleanup(id, removeWeakEntry, weakEntry{state.self, key})
+	}
+}
+
+func (state *CollectionState) delete(value RuntimeVal, env *Environment, pos Pos) bool {
+	if state.weak() {
+		if _, ok := weakKey(value); !ok {
+			return false
+		}
+	}
+	key := state.keyOf(value, env, pos)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.remove(key)
+}
+
+// unlinks the entry of key, the caller holds mu
+func (state *CollectionState) remove(key any) bool {
+	e, ok := state.entries[key]
+	if !ok {
+		return false
+	}
+	delete(state.entries, key)
+	if state.weak() {
+		state.setWeakValue(key, nil)
+	}
+	e.deleted = true
+	if e.prev != nil {
+		e.prev.next = e.next
+	} else {
+		state.first = e.next
+	}
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		state.last = e.prev
+	}
+	return true
+}
+
+func (state *CollectionState) clear() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	for e := state.first; e != nil; e = e.next {
+		e.deleted = true
+	}
+	if state.weak() {
+		for key := range state.entries {
+			state.setWeakValue(key, nil)
+		}
+	}
+	// the cleanup of a weak collection holds the map
+	clear(state.entries)
+	state.first, state.last = nil, nil
+}
+
+func (state *CollectionState) size() int {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return len(state.entries)
+}
+
+// the entry after e, the first one when e is nil; deleted entries are skipped
+func (state *CollectionState) after(e *entry) *entry {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if e == nil {
+		return state.first
+	}
+	for e = e.next; e != nil && e.deleted; e = e.next {
+	}
+	return e
+}
+
+// calls fn for every entry, including the ones added while it runs
+func (state *CollectionState) forEach(fn func(key, value RuntimeVal)) {
+	for e := state.after(nil); e != nil; e = state.after(e) {
+		fn(e.key, e.value)
+	}
+}
+
+// the next value of the iterator, ok is false once every entry was returned
+func (it *IteratorState) next() (RuntimeVal, bool) {
+	if it.done {
+		return undefined, false
+	}
+	e := it.collection.after(it.at)
+	if e == nil {
+		it.done = true
+		return undefined, false
+	}
+	it.at = e
+	switch it.kind {
+	case "keys":
+		return e.key, true
+	case "values":
+		return e.value, true
+	}
+	return MK_ARRAY(e.key, e.value), true
+}
+
+// how a Map or Set is printed, weak collections do not show their entries
+func (state *CollectionState) String() string {
+	if state.weak() {
+		return state.kind + " { \x1b[36m<items unknown>\x1b[0m }"
+	}
+	items := []string{}
+	state.forEach(func(key, value RuntimeVal) {
+		if state.set() {
+			items = append(items, value.String(1, ""))
+		} else {
+			items = append(items, key.String(1, "")+" => "+value.String(1, ""))
+		}
+	})
+	if len(items) == 0 {
+		return state.kind + "(0) {}"
+	}
+	return state.kind + "(" + sprint(len(items)) + ") { " + strings.Join(items, ", ") + " }"
+}
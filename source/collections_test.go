@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// runs source as a program that imports the collections of the standard library, returns
+// the collection state of each of the variables names and the environment that holds them
+func collectionStates(t *testing.T, source string, names ...string) ([]*CollectionState, *Environment) {
+	lockInterpreter()
+	defer interpreterLock.Unlock()
+	path := filepath.Join(t.TempDir(), "weak.as")
+	source = "import \"std:symbols\"\nimport \"std:collections\"\n" + source
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	program := NewParser(path, "program", "", Tokenize).Parse(true)
+	r := NewRuntime()
+	env := CreateScriptEnv(r, path)
+	if _, err := r.Exec(program, env); err != nil {
+		t.Fatal(err)
+	}
+	states := []*CollectionState{}
+	for _, name := range names {
+		states = append(states, stateOf[*CollectionState](Memory.get(env.variables.get(name)), "test", "collection", env, Pos{}))
+	}
+	return states, env
+}
+
+// runs the collector until the collections are empty, cleanups run on a goroutine of their own
+func collectedAll(states []*CollectionState) bool {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		runtime.GC()
+		empty := true
+		for _, state := range states {
+			if state.size() > 0 {
+				empty = false
+			}
+		}
+		if empty {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestWeakCollectionsDropCollectedKeys(t *testing.T) {
+	states, _ := collectionStates(t, `
+spawn set = new WeakSet()
+spawn map = new WeakMap()
+spawn fill = () => {
+  spawn key = { name: "key" }
+  set.add(key)
+  $ a value that refers to its key does not keep it
+  map.set(key, [key])
+  map.set([1, 2], "array key")
+  spawn other = new WeakSet([key])
+  return set.has(key) && map.get(key)[0] == key && other.has(key)
+}
+if (fill() != true) {
+  throw "the entries were not added"
+}
+`, "set", "map")
+	for _, state := range states {
+		if state.size() == 0 {
+			t.Fatalf("%s is empty before the collection", state.kind)
+		}
+	}
+	if !collectedAll(states) {
+		t.Errorf("the entries of unreachable keys are left: WeakSet %d, WeakMap %d", states[0].size(), states[1].size())
+	}
+}
+
+func TestWeakCollectionsKeepReachableKeys(t *testing.T) {
+	states, env := collectionStates(t, `
+spawn key = {}
+spawn map = new WeakMap([[key, "value"]])
+`, "map")
+	runtime.GC()
+	runtime.GC()
+	if states[0].size() != 1 {
+		t.Errorf("the entry of a key that is still referred to was removed")
+	}
+	runtime.KeepAlive(env)
+}
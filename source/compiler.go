@@ -0,0 +1,685 @@
+package main
+
+import (
+	"slices"
+	"sync"
+)
+
+// Op is an instruction of the bytecode VM.
+type Op uint8
+
+const (
+	OP_CONST            Op = iota // push consts[a]
+	OP_POP                        // discard the top value
+	OP_POP_LAST                   // pop the value of an expression statement
+	OP_LOAD_SLOT                  // push slots[a]
+	OP_STORE_SLOT                 // slots[a] = top
+	OP_DECLARE_SLOT               // slots[a] = pop
+	OP_LOAD_NAME                  // push the variable names[a]
+	OP_STORE_NAME                 // assign top to the variable names[a], b: 1 to refuse constants
+	OP_DECLARE_NAME               // declare the variable names[a] of type names[b] with pop
+	OP_BINARY                     // rhs = pop, lhs = pop, push lhs names[a] rhs
+	OP_COMPARE                    // same as OP_BINARY for comparisons
+	OP_COMPOUND                   // lhs = pop, rhs = pop, push the value of lhs names[a] rhs
+	OP_COALESCE                   // lhs = pop, rhs = pop, push rhs if lhs is nullish, else push lhs and jump to a
+	OP_AND                        // push the value of lhs && rhs, both are evaluated
+	OP_OR                         // push the value of lhs || rhs, both are evaluated
+	OP_NOT                        // push !pop
+	OP_TYPEOF                     // push typeof pop
+	OP_GET_MEMBER                 // key = pop, object = pop, push object[key], a: 1 when computed
+	OP_SET_MEMBER                 // value = pop, key = pop, object = pop, object[key] = value, push value; a: nodes index of the member expression, b: 1 to check static variables
+	OP_MEMBER_COMPOUND            // rhs = pop, push object[key] names[a] rhs, object and key stay
+	OP_INCREMENT                  // value = pop, push the value of the expression then the value to store; a: names index of the operator, b: 1 when prefix
+	OP_INCREMENT_MEMBER           // key = pop, object = pop, push the value of the expression; a, b as OP_INCREMENT, c: 1 when computed
+	OP_CALL                       // call the value below a arguments
+	OP_ARRAY                      // push an array of the top a values
+	OP_TEMPLATE                   // push the template literal nodes[a] joined with its top b values
+	OP_JUMP                       // continue at a
+	OP_JUMP_IF_FALSE              // continue at a if pop is falsy
+	OP_PUSH_ENV                   // enter a scope of type names[a]
+	OP_POP_ENV                    // leave the current scope
+	OP_BREAK                      // leave loops[a]
+	OP_CONTINUE                   // start the next iteration of loops[a]
+	OP_RETURN                     // return pop from the function
+	OP_THROW                      // throw pop
+	OP_REDECLARE                  // throw the error of redeclaring names[a], a variable of type names[b]
+	OP_ASSIGN_CONSTANT            // throw the error of assigning the variable names[a] of type names[b]
+	OP_EVAL                       // push the value of nodes[a] evaluated by the tree-walker, b: loops index of the innermost loop or -1
+)
+
+type Instr struct {
+	op      Op
+	a, b, c int
+	pos     Pos
+}
+
+// Loop is where break and continue statements of a compiled loop jump to,
+// with the number of scopes that are left open there.
+type Loop struct {
+	breakAt, continueAt       int
+	breakDepth, continueDepth int
+}
+
+// Chunk is a compiled block.
+type Chunk struct {
+	code   []Instr
+	consts []RuntimeVal
+	names  []string
+	nodes  []Node
+	loops  []Loop
+	// number of local slots
+	slots int
+}
+
+// Compiler translates a block to bytecode. The statements and expressions the VM
+// knows are compiled, everything else is left to the tree-walker (OP_EVAL).
+//
+// a scope whose statement is compiled entirely, without closures or nodes left
+// to the tree-walker, is closed: no environment is created for it and its
+// variables live in slots, nothing but the compiled code can refer to them.
+type Compiler struct {
+	chunk  *Chunk
+	scopes []*CompileScope
+	// indexes of the compiled loops around the current instruction
+	loops []int
+	// number of open environments
+	depth int
+	names map[string]int
+}
+
+type CompileScope struct {
+	closed bool
+	// key: variable identifier, for closed scopes
+	slots map[string]Slot
+}
+
+type Slot struct {
+	index int
+	_type string
+}
+
+// key: the first statement of a block and its length
+type chunkKey struct {
+	first *Node
+	n     int
+}
+
+// compiles a block once for the program whose chunks are given and returns the chunk
+func CompileBlock(chunks *sync.Map, body []Node) *Chunk {
+	key := chunkKey{&body[0], len(body)}
+	if chunk, ok := chunks.Load(key); ok {
+		return chunk.(*Chunk)
+	}
+	if labelled(body) {
+		// the tree-walker runs a block with labels, it finds where goto statements go
+		chunks.Store(key, (*Chunk)(nil))
+		return nil
+	}
+	c := &Compiler{
+		chunk: &Chunk{},
+		names: map[string]int{},
+	}
+	// the block runs in the environment it is given
+	c.scopes = []*CompileScope{{}}
+	for _, stmt := range body {
+		c.stmt(stmt)
+	}
+	chunk, _ := chunks.LoadOrStore(key, c.chunk)
+	return chunk.(*Chunk)
+}
+
+func (c *Compiler) emit(op Op, a, b, cc int, pos Pos) int {
+	c.chunk.code = append(c.chunk.code, Instr{op, a, b, cc, pos})
+	return len(c.chunk.code) - 1
+}
+
+// the address of the next instruction
+func (c *Compiler) here() int {
+	return len(c.chunk.code)
+}
+
+// points the jump at address to the next instruction
+func (c *Compiler) patch(address int) {
+	c.chunk.code[address].a = c.here()
+}
+
+func (c *Compiler) constant(value RuntimeVal) int {
+	c.chunk.consts = append(c.chunk.consts, value)
+	return len(c.chunk.consts) - 1
+}
+
+func (c *Compiler) name(name string) int {
+	if i, ok := c.names[name]; ok {
+		return i
+	}
+	c.chunk.names = append(c.chunk.names, name)
+	c.names[name] = len(c.chunk.names) - 1
+	return c.names[name]
+}
+
+func (c *Compiler) node(node Node) int {
+	c.chunk.nodes = append(c.chunk.nodes, node)
+	return len(c.chunk.nodes) - 1
+}
+
+func (c *Compiler) scope() *CompileScope {
+	return c.scopes[len(c.scopes)-1]
+}
+
+// enters a scope of a statement, closed scopes stay closed
+func (c *Compiler) enter(_type string, closed bool, pos Pos) {
+	closed = closed || c.scope().closed
+	c.scopes = append(c.scopes, &CompileScope{closed: closed, slots: map[string]Slot{}})
+	if !closed {
+		c.emit(OP_PUSH_ENV, c.name(_type), 0, 0, pos)
+		c.depth++
+	}
+}
+
+func (c *Compiler) leave(pos Pos) {
+	if !c.scope().closed {
+		c.emit(OP_POP_ENV, 0, 0, 0, pos)
+		c.depth--
+	}
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// the slot of a variable, when it lives in one
+func (c *Compiler) resolve(name string) (Slot, bool) {
+	for i := len(c.scopes) - 1; i >= 0 && c.scopes[i].closed; i-- {
+		if slot, ok := c.scopes[i].slots[name]; ok {
+			return slot, true
+		}
+	}
+	return Slot{}, false
+}
+
+func (c *Compiler) innermostLoop() int {
+	if len(c.loops) == 0 {
+		return -1
+	}
+	return c.loops[len(c.loops)-1]
+}
+
+// leaves the node to the tree-walker
+func (c *Compiler) eval(node Node) {
+	c.emit(OP_EVAL, c.node(node), c.innermostLoop(), 0, getPosFromNode(node))
+}
+
+func (c *Compiler) stmt(node Node) {
+	if !compilable(node, len(c.loops) > 0) {
+		c.eval(node)
+		c.emit(OP_POP_LAST, 0, 0, 0, Pos{})
+		return
+	}
+	switch node := node.(type) {
+	case *VarDecl:
+		c.expr(node.right)
+		c.declare(node.left.(*Identifier).Symbol, node._type, node.Pos)
+	case *IfStmt:
+		closed := closes(node, len(c.loops) > 0)
+		c.expr(node.condition)
+		jump := c.emit(OP_JUMP_IF_FALSE, 0, 0, 0, node.Pos)
+		c.block("block", closed, node.body, node.Pos)
+		if node.elseBody != nil {
+			end := c.emit(OP_JUMP, 0, 0, 0, node.Pos)
+			c.patch(jump)
+			c.block("block", closed, node.elseBody, node.Pos)
+			c.patch(end)
+		} else {
+			c.patch(jump)
+		}
+	case *BlockStmt:
+		c.block("block", closes(node, len(c.loops) > 0), node.body, node.Pos)
+	case *WhileLoop:
+		c.whileLoop(node)
+	case *ForLoop:
+		c.forLoop(node)
+	case *BreakStmt:
+		c.emit(OP_BREAK, c.innermostLoop(), 0, 0, node.Pos)
+	case *ContinueStmt:
+		c.emit(OP_CONTINUE, c.innermostLoop(), 0, 0, node.Pos)
+	case *ReturnStmt:
+		if node.value != nil {
+			c.expr(node.value)
+		} else {
+			c.emit(OP_CONST, c.constant(undefined), 0, 0, node.Pos)
+		}
+		c.emit(OP_RETURN, 0, 0, 0, node.Pos)
+	case *ThrowStmt:
+		c.expr(node.value)
+		c.emit(OP_THROW, 0, 0, 0, node.Pos)
+	default:
+		c.expr(node)
+		c.emit(OP_POP_LAST, 0, 0, 0, Pos{})
+	}
+}
+
+func (c *Compiler) block(_type string, closed bool, body []Node, pos Pos) {
+	c.enter(_type, closed, pos)
+	for _, stmt := range body {
+		c.stmt(stmt)
+	}
+	c.leave(pos)
+}
+
+func (c *Compiler) declare(name, _type string, pos Pos) {
+	scope := c.scope()
+	if !scope.closed {
+		c.emit(OP_DECLARE_NAME, c.name(name), c.name(_type), 0, pos)
+		return
+	}
+	if slot, ok := scope.slots[name]; ok {
+		c.emit(OP_REDECLARE, c.name(name), c.name(slot._type), 0, pos)
+		return
+	}
+	slot := Slot{c.chunk.slots, _type}
+	c.chunk.slots++
+	scope.slots[name] = slot
+	c.emit(OP_DECLARE_SLOT, slot.index, 0, 0, pos)
+}
+
+// opens a loop, its addresses are set once it is compiled
+func (c *Compiler) loop() int {
+	c.chunk.loops = append(c.chunk.loops, Loop{})
+	index := len(c.chunk.loops) - 1
+	c.loops = append(c.loops, index)
+	return index
+}
+
+func (c *Compiler) endLoop() {
+	c.loops = c.loops[:len(c.loops)-1]
+}
+
+// the body of a while loop has a scope of its own every iteration
+func (c *Compiler) whileLoop(stmt *WhileLoop) {
+	closed := closes(stmt, true)
+	index := c.loop()
+	breakDepth := c.depth
+	start := c.here()
+	exit := -1
+	if !stmt.do {
+		c.expr(stmt.condition)
+		exit = c.emit(OP_JUMP_IF_FALSE, 0, 0, 0, stmt.Pos)
+	}
+	c.block("loop", closed, stmt.body, stmt.Pos)
+	continueAt := c.here()
+	if stmt.do {
+		c.expr(stmt.condition)
+		exit = c.emit(OP_JUMP_IF_FALSE, 0, 0, 0, stmt.Pos)
+	}
+	c.emit(OP_JUMP, start, 0, 0, stmt.Pos)
+	c.patch(exit)
+	c.endLoop()
+	c.chunk.loops[index] = Loop{
+		breakAt:       c.here(),
+		continueAt:    continueAt,
+		breakDepth:    breakDepth,
+		continueDepth: breakDepth,
+	}
+}
+
+// a for loop has a scope for the variables it declares before the first
+// iteration, and a scope of its own every iteration
+func (c *Compiler) forLoop(stmt *ForLoop) {
+	closed := closes(stmt, true)
+	index := c.loop()
+	breakDepth := c.depth
+	c.enter("loop", closed, stmt.Pos)
+	if expr, ok := stmt.before.(*AssignmentExpr); ok && expr.op == "=" {
+		c.expr(expr.right)
+		c.declare(expr.left.(*Identifier).Symbol, "mutable", expr.Pos)
+	} else {
+		c.expr(stmt.before)
+		c.emit(OP_POP, 0, 0, 0, stmt.Pos)
+	}
+	start := c.here()
+	c.enter("loop", closed, stmt.Pos)
+	c.expr(stmt.condition)
+	exit := c.emit(OP_JUMP_IF_FALSE, 0, 0, 0, stmt.Pos)
+	for _, s := range stmt.body {
+		c.stmt(s)
+	}
+	continueAt := c.here()
+	continueDepth := c.depth
+	c.expr(stmt.after)
+	c.emit(OP_POP, 0, 0, 0, stmt.Pos)
+	c.leave(stmt.Pos)
+	c.emit(OP_JUMP, start, 0, 0, stmt.Pos)
+	c.patch(exit)
+	// the scope of the last iteration is still open
+	if !c.scope().closed {
+		c.emit(OP_POP_ENV, 0, 0, 0, stmt.Pos)
+	}
+	c.leave(stmt.Pos)
+	c.endLoop()
+	c.chunk.loops[index] = Loop{
+		breakAt:       c.here(),
+		continueAt:    continueAt,
+		breakDepth:    breakDepth,
+		continueDepth: continueDepth,
+	}
+}
+
+func (c *Compiler) expr(node Node) {
+	pos := getPosFromNode(node)
+	if !compilable(node, len(c.loops) > 0) {
+		c.eval(node)
+		return
+	}
+	switch node := node.(type) {
+	case *Number:
+		c.emit(OP_CONST, c.constant(MK_NUMBER(node.Value)), 0, 0, pos)
+	case *BigInt:
+		c.emit(OP_CONST, c.constant(MK_BIGINT(node.Value)), 0, 0, pos)
+	case *String:
+		c.emit(OP_CONST, c.constant(MK_STRING(node.Value)), 0, 0, pos)
+	case *Identifier:
+		if slot, ok := c.resolve(node.Symbol); ok {
+			c.emit(OP_LOAD_SLOT, slot.index, 0, 0, pos)
+		} else {
+			c.emit(OP_LOAD_NAME, c.name(node.Symbol), 0, 0, pos)
+		}
+	case *BinaryExpr:
+		c.expr(node.left)
+		c.expr(node.right)
+		c.emit(OP_BINARY, c.name(node.op), 0, 0, node.Pos)
+	case *ComparisonExpr:
+		c.expr(node.left)
+		c.expr(node.right)
+		c.emit(OP_COMPARE, c.name(node.op), 0, 0, comparisonPos(node))
+	case *LogicalExpr:
+		c.expr(node.left)
+		switch node.op {
+		case "!":
+			c.emit(OP_NOT, 0, 0, 0, pos)
+		case "&&":
+			c.expr(node.right)
+			c.emit(OP_AND, 0, 0, 0, pos)
+		case "||":
+			c.expr(node.right)
+			c.emit(OP_OR, 0, 0, 0, pos)
+		}
+	case *TernaryExpr:
+		c.expr(node.condition)
+		jump := c.emit(OP_JUMP_IF_FALSE, 0, 0, 0, pos)
+		c.expr(node.then)
+		end := c.emit(OP_JUMP, 0, 0, 0, pos)
+		c.patch(jump)
+		c.expr(node._else)
+		c.patch(end)
+	case *GroupingExpr:
+		if len(node.exprs) == 0 {
+			c.emit(OP_CONST, c.constant(undefined), 0, 0, pos)
+		}
+		for i, expr := range node.exprs {
+			c.expr(expr)
+			if i < len(node.exprs)-1 {
+				c.emit(OP_POP, 0, 0, 0, pos)
+			}
+		}
+	case *TypeOfExpr:
+		c.expr(node.operand)
+		c.emit(OP_TYPEOF, 0, 0, 0, pos)
+	case *VoidExpr:
+		c.expr(node.operand)
+		c.emit(OP_POP, 0, 0, 0, pos)
+		c.emit(OP_CONST, c.constant(undefined), 0, 0, pos)
+	case *ArrayLiteral:
+		for _, el := range node.elements {
+			c.expr(el)
+		}
+		c.emit(OP_ARRAY, len(node.elements), 0, 0, pos)
+	case *TemplateString:
+		for _, e := range node.exprs {
+			c.expr(e)
+		}
+		c.emit(OP_TEMPLATE, c.node(node), len(node.exprs), 0, pos)
+	case *MemberExpr:
+		c.member(node)
+		c.emit(OP_GET_MEMBER, bit(node.computed), 0, 0, getPosFromNode(node.property))
+	case *CallExpr:
+		c.expr(node.caller)
+		for _, arg := range node.args {
+			c.expr(arg)
+		}
+		c.emit(OP_CALL, len(node.args), 0, 0, node.Pos)
+	case *AssignmentExpr:
+		c.assignment(node)
+	case *IncrementExpr:
+		operand_pos := getPosFromNode(node.operand)
+		switch operand := node.operand.(type) {
+		case *Identifier:
+			c.expr(operand)
+			c.emit(OP_INCREMENT, c.name(node.op), bit(node.pre), 0, operand_pos)
+			c.store(operand, false, pos)
+			c.emit(OP_POP, 0, 0, 0, pos)
+		case *MemberExpr:
+			c.member(operand)
+			c.emit(OP_INCREMENT_MEMBER, c.name(node.op), bit(node.pre), bit(operand.computed), operand_pos)
+		}
+	}
+}
+
+// pushes the object and the key of a member expression
+func (c *Compiler) member(expr *MemberExpr) {
+	c.expr(expr.object)
+	if expr.computed {
+		c.expr(expr.property)
+	} else {
+		c.emit(OP_CONST, c.constant(MK_STRING(expr.property.(*Identifier).Symbol)), 0, 0, getPosFromNode(expr.property))
+	}
+}
+
+func (c *Compiler) assignment(expr *AssignmentExpr) {
+	switch left := expr.left.(type) {
+	case *Identifier:
+		c.expr(expr.right)
+		end := -1
+		switch expr.op {
+		case "=":
+		case "??=":
+			c.expr(left)
+			end = c.emit(OP_COALESCE, 0, 0, 0, expr.Pos)
+		default:
+			c.expr(left)
+			c.emit(OP_COMPOUND, c.name(expr.op), 0, 0, expr.Pos)
+		}
+		c.store(left, true, expr.Pos)
+		if end >= 0 {
+			c.patch(end)
+		}
+	case *MemberExpr:
+		c.member(left)
+		c.expr(expr.right)
+		if expr.op != "=" {
+			c.emit(OP_MEMBER_COMPOUND, c.name(expr.op), bit(left.computed), 0, expr.Pos)
+		}
+		// the variable holding the object could be a slot, those are never static
+		check := true
+		if root, ok := ResolveMemberObject(left.object).(*Identifier); ok {
+			_, slot := c.resolve(root.Symbol)
+			check = !slot
+		}
+		c.emit(OP_SET_MEMBER, c.node(left), bit(check), 0, expr.Pos)
+	}
+}
+
+// stores the top value in a variable; assignments refuse constants declared in
+// the scope they run in, like Environment.AssignVar
+func (c *Compiler) store(ident *Identifier, assignment bool, pos Pos) {
+	scope := c.scope()
+	if slot, ok := c.resolve(ident.Symbol); ok {
+		if current, ok := scope.slots[ident.Symbol]; assignment && ok && is_value(current._type, "constant", "static") {
+			c.emit(OP_ASSIGN_CONSTANT, c.name(ident.Symbol), c.name(current._type), 0, pos)
+			return
+		}
+		c.emit(OP_STORE_SLOT, slot.index, 0, 0, pos)
+		return
+	}
+	// a closed scope has no environment, its variables would be the only ones checked
+	c.emit(OP_STORE_NAME, c.name(ident.Symbol), bit(assignment && !scope.closed), 0, pos)
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// reports whether the statement of a scope can close it: it is compiled
+// entirely and declares no closures
+func closes(node Node, loop bool) bool {
+	switch node.(type) {
+	case *WhileLoop, *ForLoop:
+		loop = true
+	}
+	return compilable(node, loop) && all(children(node), func(child Node) bool {
+		return closes(child, loop)
+	})
+}
+
+// reports whether the VM runs a node itself; loop tells whether break and
+// continue statements are inside a compiled loop
+func compilable(node Node, loop bool) bool {
+	switch node := node.(type) {
+	case *Number, *BigInt, *String, *Identifier, *BinaryExpr, *ComparisonExpr, *TernaryExpr,
+		*GroupingExpr, *TypeOfExpr, *VoidExpr, *ReturnStmt, *ThrowStmt,
+		*TemplateString:
+		return true
+	case *MemberExpr:
+		// super.prop is looked up from the class body the code is in
+		_, super := node.object.(*SuperRef)
+		return !super
+	case *BlockStmt:
+		return !labelled(node.body)
+	case *WhileLoop:
+		return node.label == "" && !labelled(node.body)
+	case *ForLoop:
+		if node.label != "" || labelled(node.body) || node.before == nil || node.condition == nil || node.after == nil {
+			return false
+		}
+		if expr, ok := node.before.(*AssignmentExpr); ok && expr.op == "=" {
+			_, ok = expr.left.(*Identifier)
+			return ok
+		}
+		return true
+	case *LogicalExpr:
+		return is_value(node.op, "!", "&&", "||")
+	case *BreakStmt:
+		return loop && node.label == ""
+	case *ContinueStmt:
+		return loop && node.label == ""
+	case *VarDecl:
+		_, ok := node.left.(*Identifier)
+		return ok && node._type != "static"
+	case *IfStmt:
+		_, decl := node.condition.(*VarDecl)
+		return !decl && !labelled(node.body) && !labelled(node.elseBody)
+	case *ArrayLiteral:
+		return all(node.elements, notSpread)
+	case *CallExpr:
+		return all(node.args, notSpread)
+	case *AssignmentExpr:
+		switch node.left.(type) {
+		case *Identifier:
+			return is_value(node.op, "=", "??=") || compoundOp(node.op)
+		case *MemberExpr:
+			return node.op == "=" || compoundOp(node.op)
+		}
+	case *IncrementExpr:
+		switch node.operand.(type) {
+		case *Identifier, *MemberExpr:
+			return true
+		}
+	}
+	return false
+}
+
+// the assignment operators r.compound implements
+func compoundOp(op string) bool {
+	return is_value(op, "+=", "-=", "*=", "/=", "%=", "**=", "&=", "|=", "^=", "<<=", ">>=", ">>>=")
+}
+
+func notSpread(node Node) bool {
+	_, spread := node.(*RestOrSpreadExpr)
+	return !spread
+}
+
+func all(nodes []Node, f func(Node) bool) bool {
+	for _, node := range nodes {
+		if !f(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// the nodes a compilable node is made of
+func children(node Node) []Node {
+	switch node := node.(type) {
+	case *BinaryExpr:
+		return []Node{node.left, node.right}
+	case *ComparisonExpr:
+		return []Node{node.left, node.right}
+	case *LogicalExpr:
+		if node.op == "!" {
+			return []Node{node.left}
+		}
+		return []Node{node.left, node.right}
+	case *TernaryExpr:
+		return []Node{node.condition, node.then, node._else}
+	case *GroupingExpr:
+		return node.exprs
+	case *TypeOfExpr:
+		return []Node{node.operand}
+	case *VoidExpr:
+		return []Node{node.operand}
+	case *MemberExpr:
+		if node.computed {
+			return []Node{node.object, node.property}
+		}
+		return []Node{node.object}
+	case *ReturnStmt:
+		if node.value == nil {
+			return nil
+		}
+		return []Node{node.value}
+	case *ThrowStmt:
+		return []Node{node.value}
+	case *BlockStmt:
+		return node.body
+	case *WhileLoop:
+		return append([]Node{node.condition}, node.body...)
+	case *ForLoop:
+		before := node.before
+		if expr, ok := before.(*AssignmentExpr); ok && expr.op == "=" {
+			before = expr.right
+		}
+		return append([]Node{before, node.condition, node.after}, node.body...)
+	case *VarDecl:
+		return []Node{node.right}
+	case *IfStmt:
+		return append(append([]Node{node.condition}, node.body...), node.elseBody...)
+	case *ArrayLiteral:
+		return node.elements
+	case *TemplateString:
+		return node.exprs
+	case *CallExpr:
+		return append([]Node{node.caller}, node.args...)
+	case *AssignmentExpr:
+		return []Node{node.left, node.right}
+	case *IncrementExpr:
+		return []Node{node.operand}
+	}
+	return nil
+}
+
+// reports whether a statement of body is labelled
+func labelled(body []Node) bool {
+	return slices.ContainsFunc(body, func(stmt Node) bool {
+		_, ok := stmt.(*Label)
+		return ok
+	})
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// the interpreter lock, held by the goroutine that runs AS code: the main goroutine
+// takes it at startup, a task or an HTTP handler when it starts. they let go of it
+// while they block (on a channel, a WaitGroup, a Mutex, stdin, the event loop or an
+// HTTP server), so the heap, the environments and the event loop are only ever used
+// by one goroutine at a time
+var interpreterLock sync.Mutex
+
+// number of goroutines waiting for the interpreter lock
+var lockWaiters atomic.Int32
+
+func lockInterpreter() {
+	lockWaiters.Add(1)
+	interpreterLock.Lock()
+	lockWaiters.Add(-1)
+}
+
+// runs fn without the interpreter lock, fn must not touch the interpreter state
+func unlocked(fn func()) {
+	interpreterLock.Unlock()
+	defer lockInterpreter()
+	fn()
+}
+
+// lets the goroutines waiting for the interpreter lock run, called after each run
+// of a loop body so that a task that never blocks does not starve the others
+func yieldInterpreter() {
+	if lockWaiters.Load() > 0 {
+		unlocked(runtime.Gosched)
+	}
+}
+
+// returns an interpreter for a task or an HTTP handler, which run on goroutines of their own
+func (r *Interpreter) forkTask() *Interpreter {
+	fork := r.fork(nil)
+	fork.task = true
+	return fork
+}
+
+// reports whether the interpreter runs on the goroutine of a task or an HTTP handler
+// rather than on the main one, which runs the event loop
+func (r *Interpreter) onTask() bool {
+	if r.coroutine != nil {
+		return r.coroutine.task
+	}
+	return r.task
+}
+
+// go <call>: the function and the arguments are evaluated right away, the call
+// runs on a goroutine of its own. the event loop is kept alive until it returns,
+// an error it does not catch ends the program
+func (r *Interpreter) EvalGoStmt(stmt *GoStmt, env *Environment) RuntimeVal {
+	pos := stmt.call.Pos
+	fn := r.Evaluate(stmt.call.caller, env)
+	args := r.eval_args(stmt.call.args, env)
+	if !is_value(ValueType(fn), "function", "macro") {
+		env.ThrowTypeError("type", ValueType(fn), "is not a function and is not callable", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	task := r.forkTask()
+	eventLoop.hold()
+	go func() {
+		func() {
+			lockInterpreter()
+			defer interpreterLock.Unlock()
+			_, err := task.SafeCall(fn, env, args, pos)
+			ExitOnUncaught(err)
+		}()
+		// posted without the lock: when the callbacks are full, the loop that would
+		// receive them may itself be waiting for the lock
+		eventLoop.post(Task{
+			macro: MK_MACRO("#_task_done", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+				return undefined
+			}),
+			env: env,
+			pos: pos,
+			r:   r,
+		})
+	}()
+	return undefined
+}
+
+// waits until value settles when it is a promise, without the interpreter lock so that the
+// event loop can settle it; returns the error it was rejected with
+func (r *Interpreter) settled(value RuntimeVal, env *Environment, pos Pos) error {
+	state := promiseOf(value)
+	if state == nil {
+		return nil
+	}
+	if state.state == PENDING {
+		settled := make(chan struct{})
+		state.subscribe(func() { close(settled) }, r, env, pos)
+		unlocked(func() {
+			<-settled
+		})
+	}
+	if state.state != REJECTED {
+		return nil
+	}
+	_, err := r.guard(func() RuntimeVal {
+		env.throwValue(state.value, r)
+		return undefined
+	})
+	return err
+}
+
+// blocks until one of cases can go on and returns what reflect.Select does for it.
+// a task lets go of the interpreter lock meanwhile; the main goroutine runs the
+// event loop instead, since one of its timers or callbacks may be what it waits for
+func (r *Interpreter) wait(cases []reflect.SelectCase, env *Environment, pos Pos) (int, reflect.Value, bool) {
+	ready := append(slices.Clip(cases), reflect.SelectCase{Dir: reflect.SelectDefault})
+	if chosen, value, ok := reflect.Select(ready); chosen < len(cases) {
+		return chosen, value, ok
+	}
+	if r.onTask() {
+		var chosen int
+		var value reflect.Value
+		var ok bool
+		unlocked(func() {
+			chosen, value, ok = reflect.Select(cases)
+		})
+		return chosen, value, ok
+	}
+	if co := r.coroutine; co != nil {
+		// an async function resumed by the event loop hands control back to it and lets
+		// a goroutine of its own wait, the loop resumes it once a case was chosen
+		var chosen int
+		var value reflect.Value
+		var ok bool
+		var rec any
+		eventLoop.hold()
+		go func() {
+			defer func() {
+				// a send on a channel closed meanwhile panics in the coroutine
+				rec = recover()
+				eventLoop.post(Task{
+					macro: MK_MACRO("#_wait_done", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+						co.run()
+						return undefined
+					}),
+					env: env,
+					pos: pos,
+					r:   r,
+				})
+			}()
+			chosen, value, ok = reflect.Select(cases)
+		}()
+		co.suspend()
+		if rec != nil {
+			panic(rec)
+		}
+		return chosen, value, ok
+	}
+	for {
+		chosen, value, ok := eventLoop.wait(cases)
+		if chosen >= 0 {
+			return chosen, value, ok
+		}
+		if chosen == LOOP_IDLE {
+			env.throwError([]string{"all tasks are asleep, nothing is left to wake this one up (deadlock)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		}
+	}
+}
+
+// a select case that receives from ch
+func receiveCase(ch any) reflect.SelectCase {
+	return reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)}
+}
+
+// ChannelState is what a Channel instance sends its values through.
+type ChannelState struct {
+	ch     chan RuntimeVal
+	closed bool
+}
+
+// WaitGroupState counts the tasks a WaitGroup waits for.
+type WaitGroupState struct {
+	count int
+	// closed when count drops to 0
+	zero chan struct{}
+}
+
+// MutexState is held by whoever sent to ch, which has room for one.
+type MutexState struct {
+	ch chan struct{}
+}
+
+// returns the state a stdlib instance keeps in its private #state field
+func stateOf[T any](value RuntimeVal, name, class string, env *Environment, pos Pos) T {
+	if instance, ok := value.(*Instance); ok {
+		if state, ok := GetInstanceMember(instance, "#state").(*RawVal[T]); ok {
+			return state.value
+		}
+	}
+	env.ThrowTypeError(name, "expects its 1st argument to be a "+class, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	return *new(T)
+}
+
+// sends value through the channel, a send that blocks when the channel is closed throws
+func (r *Interpreter) send(state *ChannelState, value RuntimeVal, env *Environment, pos Pos) {
+	if state.closed {
+		env.throwError([]string{"send on a closed channel", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			closedSend(rec, []*ChannelState{state}, env, pos)
+		}
+	}()
+	r.wait([]reflect.SelectCase{{Dir: reflect.SelectSend, Chan: reflect.ValueOf(state.ch), Send: reflect.ValueOf(&value).Elem()}}, env, pos)
+}
+
+// called with what a send recovered: throws when it panicked because one of the
+// senders' channels was closed while it waited, panics again otherwise
+func closedSend(rec any, senders []*ChannelState, env *Environment, pos Pos) {
+	if _, ok := rec.(runtime.Error); ok && slices.ContainsFunc(senders, func(state *ChannelState) bool { return state.closed }) {
+		env.throwError([]string{"send on a closed channel", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+	}
+	panic(rec)
+}
+
+// receives a value from the channel, ok is false once it is closed and empty
+func (r *Interpreter) receive(state *ChannelState, env *Environment, pos Pos) (RuntimeVal, bool) {
+	_, value, ok := r.wait([]reflect.SelectCase{receiveCase(state.ch)}, env, pos)
+	if !ok {
+		return undefined, false
+	}
+	return value.Interface().(RuntimeVal), true
+}
+
+// an object with a property for each of keys, holding the value at the same index
+func MK_RECORD(r *Interpreter, keys []string, values ...RuntimeVal) *ObjectVal {
+	props := NewMap[RuntimeVal, Ref]()
+	for i, key := range keys {
+		props.set(MK_STRING(key), Memory.alloc(values[i]))
+	}
+	return MK_OBJECT(props, nil, r)
+}
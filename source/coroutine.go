@@ -0,0 +1,111 @@
+package main
+
+// Coroutine runs the body of an async function on its own goroutine so that
+// await can suspend it. It takes turns with the goroutine that resumes it:
+// only one of them runs at a time, so the interpreter state is never shared.
+type Coroutine struct {
+	resume chan struct{}
+	yield  chan struct{}
+	done   bool
+	// set while the coroutine runs on behalf of a task, see Interpreter.onTask
+	task bool
+}
+
+func NewCoroutine() *Coroutine {
+	return &Coroutine{
+		resume: make(chan struct{}),
+		yield:  make(chan struct{}),
+	}
+}
+
+// starts or continues the coroutine and waits until it suspends or returns
+func (co *Coroutine) run() {
+	if co.done {
+		return
+	}
+	co.resume <- struct{}{}
+	<-co.yield
+}
+
+// called on the coroutine, hands control back to run and waits to be resumed
+func (co *Coroutine) suspend() {
+	co.yield <- struct{}{}
+	<-co.resume
+}
+
+// starts body on a new goroutine that waits for the first run
+func (co *Coroutine) start(body func()) {
+	go func() {
+		<-co.resume
+		defer func() {
+			co.done = true
+			co.yield <- struct{}{}
+		}()
+		body()
+	}()
+}
+
+// returns an interpreter for a coroutine: the control flow flags and the call stack
+// are its own, the caller's frames are kept for stack traces
+func (r *Interpreter) fork(co *Coroutine) *Interpreter {
+	fork := *r
+	fork.returned_from_function = false
+	fork.terminated = false
+	fork._break = false
+	fork._continue = false
+	fork._goto = false
+	fork.label = ""
+	fork.CallStack = r.CallStack.clone()
+	fork.coroutine = co
+	fork.generator = nil
+	return &fork
+}
+
+// calls an async function: its body runs until the first await suspends it,
+// the promise settles with what the body returns or throws
+func (r *Interpreter) CallAsync(fn *FunctionVal, args []RuntimeVal, scope *Environment, env *Environment, pos Pos) *Instance {
+	promise, state := NewPromise(env, r, pos)
+	co := NewCoroutine()
+	co.task = r.onTask()
+	fork := r.fork(co)
+	co.start(func() {
+		value, err := fork.guard(func() RuntimeVal {
+			DeclareParams(fn.params, args, scope, fork)
+			return fork.pushToStack(*fn, *scope)
+		})
+		if err != nil {
+			state.settle(REJECTED, err.(*ThrownError).value, fork, env, pos)
+		} else {
+			state.resolve(value, fork, env, pos)
+		}
+	})
+	co.run()
+	return promise
+}
+
+// waits for value to settle and returns what it was fulfilled with, or throws what it was rejected with.
+// an async function is suspended meanwhile, at the top level of a script the event loop runs instead
+func (r *Interpreter) Await(value RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	state := promiseOf(value)
+	if state == nil {
+		_, state = NewPromise(env, r, pos)
+		state.resolve(value, r, env, pos)
+	}
+	if co := r.coroutine; co != nil {
+		// micro tasks run on the main goroutine
+		co.task = false
+		state.subscribe(co.run, r, env, pos)
+		co.suspend()
+	} else {
+		state.handled = true
+		for state.state == PENDING {
+			if !eventLoop.step() {
+				env.throwError([]string{"await: the promise never settles, nothing is left for the event loop to run", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+			}
+		}
+	}
+	if state.state == REJECTED {
+		env.throwValue(state.value, r)
+	}
+	return state.value
+}
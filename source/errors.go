@@ -0,0 +1,215 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// ThrownError carries a thrown value up the Go call stack until a
+// try block catches it or it reaches the top level of a script.
+//
+// errors raised by the runtime itself (TypeError, ReferenceError ...)
+// start without a value, only a name and a message; the value is created
+// from the stdlib error classes the first time an interpreter handles it.
+type ThrownError struct {
+	value RuntimeVal
+	// name of the error class ("Error" | "TypeError" | "ReferenceError" | "RangeError" | "SyntaxError")
+	name    string
+	message string
+	// source log of where the error was raised
+	location string
+	env      *Environment
+}
+
+// Error implements error.
+func (e *ThrownError) Error() string {
+	if e.value == nil {
+		return e.name + ": " + e.message
+	}
+	if stack, ok := ErrorStack(e.value); ok {
+		return stack
+	}
+	return e.value.noAnsi()
+}
+
+// creates the error value (if it doesn't exist yet) with the call stack of r
+func (e *ThrownError) materialize(r *Interpreter) RuntimeVal {
+	if e.value == nil {
+		stack := e.name + ": " + e.message + e.location + r.StackTrace(r.CallStack.length)
+		e.value = NewErrorValue(e.name, e.message, stack, e.env, r)
+	}
+	return e.value
+}
+
+// raises a runtime error that scripts can catch
+func (env *Environment) raise(name string, message []string) {
+	text := JoinSlice(message, " ")
+	location := ""
+	if index := strings.Index(text, "\r\n"); index >= 0 {
+		text, location = text[:index], text[index:]
+	}
+	panic(&ThrownError{
+		name:     name,
+		message:  strings.TrimSpace(text),
+		location: strings.TrimRight(location, " "),
+		env:      env,
+	})
+}
+
+// creates an instance of one of the stdlib error classes,
+// or a plain object when the stdlib has not declared the class (yet)
+func NewErrorValue(name, message, stack string, env *Environment, r *Interpreter) RuntimeVal {
+	if stdEnv != nil && stdEnv.variables.has(name) {
+		class_ml := stdEnv.variables.get(name)
+		if _, ok := Memory.get(class_ml).(*ClassVal); ok {
+			if env == nil {
+				env = stdEnv
+			}
+			instance := r.Instantiate(class_ml, env, []RuntimeVal{MK_STRING(message)}, Pos{})
+			SetInstanceMember(instance, "stack", MK_STRING(stack))
+			return instance
+		}
+	}
+	props := NewMap[RuntimeVal, Ref]()
+	for _, prop := range [][]string{{"name", name}, {"message", message}, {"stack", stack}} {
+		ml := Memory.alloc(MK_STRING(prop[1]))
+		props.set(MK_STRING(prop[0]), ml)
+	}
+	return MK_OBJECT(props, env, r)
+}
+
+// sets an existing member of an instance, on the instance itself or its prototype chain
+func SetInstanceMember(instance *Instance, member string, value RuntimeVal) {
+	prop := MK_STRING(member)
+	ml := instance.properties.get(prop)
+	if ml == nil {
+		ml = GetPropMlFromProto(prop, instance.prototype)
+	}
+	if ml == nil {
+		instance.properties.set(prop, Memory.alloc(value))
+		return
+	}
+	Memory.set(ml, value)
+}
+
+// returns the "stack" string property of an error value
+func ErrorStack(value RuntimeVal) (string, bool) {
+	prop := MK_STRING("stack")
+	var ml Ref
+	switch v := value.(type) {
+	case *Instance:
+		ml = v.properties.get(prop)
+		if ml == nil {
+			ml = GetPropMlFromProto(prop, v.prototype)
+		}
+	case *ObjectVal:
+		ml = v.properties.get(prop)
+	}
+	if ml == nil {
+		return "", false
+	}
+	stack, ok := Memory.get(ml).(*StringVal)
+	if !ok || len(stack.value) == 0 {
+		return "", false
+	}
+	return stack.value, true
+}
+
+// formats the functions on the lowest depth frames of the call stack, innermost first
+func (r *Interpreter) StackTrace(depth int) string {
+	trace := ""
+	for i := depth - 1; i >= 0; i-- {
+		fn := r.CallStack.stack[i]
+		name := fn.name
+		if len(name) == 0 {
+			name = "(anonymous)"
+		}
+		path := ""
+		if fn.declEnv != nil {
+			path = fn.declEnv.sourcePath
+			if fn.declEnv.parent != nil {
+				path = fn.declEnv.parent.sourcePath
+			}
+		}
+		trace += "\r\n    at " + name + " (\x1b[34m" + path + "\x1b[0m)"
+	}
+	return trace
+}
+
+// Exec evaluates node like Evaluate, but returns a value that was thrown
+// and never caught as an error instead of unwinding the caller.
+func (r *Interpreter) Exec(node Node, env *Environment) (RuntimeVal, error) {
+	return r.guard(func() RuntimeVal {
+		return r.Evaluate(node, env)
+	})
+}
+
+// SafeCall calls a function value like CallFunction, but returns a value
+// that was thrown and never caught as an error.
+func (r *Interpreter) SafeCall(fn RuntimeVal, env *Environment, args []RuntimeVal, pos Pos) (RuntimeVal, error) {
+	return r.guard(func() RuntimeVal {
+		value, _ := CallFunction(fn, env, args, r, pos)
+		return value
+	})
+}
+
+// runs eval and recovers a thrown value that nothing caught
+func (r *Interpreter) guard(eval func() RuntimeVal) (value RuntimeVal, err error) {
+	depth := r.CallStack.length
+	defer func() {
+		if rec := recover(); rec != nil {
+			thrown, ok := rec.(*ThrownError)
+			if !ok {
+				panic(rec)
+			}
+			thrown.materialize(r)
+			r.ResetState(depth)
+			value, err = undefined, thrown
+		}
+	}()
+	return eval(), nil
+}
+
+// clears the control flow flags and the frames above depth after an uncaught error
+func (r *Interpreter) ResetState(depth int) {
+	r.terminated = false
+	r.returned_from_function = false
+	r._break = false
+	r._continue = false
+	r._goto = false
+	r.label = ""
+	r.CallStack.truncate(depth)
+}
+
+// prints an error returned by Exec
+func PrintUncaught(err error) {
+	thrown, ok := err.(*ThrownError)
+	if !ok {
+		println("\x1b[31mUncaught\x1b[0m", err.Error())
+		return
+	}
+	printUncaught("Uncaught", thrown.value)
+}
+
+// prints a value nothing caught, with its stack when it is an error
+func printUncaught(label string, value RuntimeVal) {
+	if stack, ok := ErrorStack(value); ok {
+		println("\x1b[31m" + label + "\x1b[0m " + stack)
+		return
+	}
+	print(label + " \x1b[31mError\x1b[0m: ")
+	PrintRtv(value)
+	println()
+}
+
+// set when the program reported an error and went on, like a rejection that
+// nothing handled; the process then exits with 1 once the program has run
+var failure = false
+
+// terminates the process when a script did not catch a thrown value
+func ExitOnUncaught(err error) {
+	if err != nil {
+		PrintUncaught(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"container/heap"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// Timer is a callback scheduled by setTimeout or setInterval.
+type Timer struct {
+	id   int
+	when time.Time
+	// the delay of setInterval, 0 for setTimeout
+	interval time.Duration
+	task     Task
+	// order of creation, timers that are due at the same time run in that order
+	seq int
+	// position in the heap, -1 once removed
+	index int
+}
+
+// TimerHeap implements heap.Interface, the timer due first is at index 0.
+type TimerHeap []*Timer
+
+func (h TimerHeap) Len() int { return len(h) }
+
+func (h TimerHeap) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].when.Before(h[j].when)
+}
+
+func (h TimerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *TimerHeap) Push(x any) {
+	timer := x.(*Timer)
+	timer.index = len(*h)
+	*h = append(*h, timer)
+}
+
+func (h *TimerHeap) Pop() any {
+	old := *h
+	timer := old[len(old)-1]
+	old[len(old)-1] = nil
+	timer.index = -1
+	*h = old[:len(old)-1]
+	return timer
+}
+
+// EventLoop runs the micro tasks, the timers and the callbacks of pending
+// I/O of every interpreter, until none of them is left.
+type EventLoop struct {
+	microTasks *MicroTaskQueue
+	timers     TimerHeap
+	// key: timer id, for clearTimeout and clearInterval
+	active map[int]*Timer
+	nextId int
+	seq    int
+	// operations in flight that will post a callback
+	pending   int
+	callbacks chan Task
+	// told by tasks that queue a micro task or set a timer, so a loop that waits notices
+	wakeup chan struct{}
+	// rejected promises without a callback (yet)
+	rejections []*PromiseState
+}
+
+func NewEventLoop() *EventLoop {
+	return &EventLoop{
+		microTasks: &MicroTaskQueue{
+			queue:  []Task{},
+			length: 0,
+		},
+		timers:    TimerHeap{},
+		active:    map[int]*Timer{},
+		callbacks: make(chan Task, 64),
+		wakeup:    make(chan struct{}, 1),
+	}
+}
+
+var eventLoop = NewEventLoop()
+
+// schedules task after delay, again and again when repeat is set; returns the timer id
+func (l *EventLoop) setTimer(task Task, delay time.Duration, repeat bool) int {
+	if delay < 0 {
+		delay = 0
+	}
+	l.nextId++
+	l.seq++
+	timer := &Timer{
+		id:   l.nextId,
+		when: time.Now().Add(delay),
+		task: task,
+		seq:  l.seq,
+	}
+	if repeat {
+		// an interval of 0 would never let the loop wait
+		timer.interval = max(delay, time.Millisecond)
+	}
+	heap.Push(&l.timers, timer)
+	l.active[timer.id] = timer
+	l.wake()
+	return timer.id
+}
+
+// cancels a timer, unknown ids are ignored
+func (l *EventLoop) clearTimer(id int) {
+	timer, ok := l.active[id]
+	if !ok {
+		return
+	}
+	if timer.index >= 0 {
+		heap.Remove(&l.timers, timer.index)
+	}
+	delete(l.active, id)
+}
+
+// tells the loop to stay alive until the callback of an operation is posted
+func (l *EventLoop) hold() {
+	l.pending++
+}
+
+// hands the callback of an operation held with hold to the loop, from any goroutine
+func (l *EventLoop) post(task Task) {
+	l.callbacks <- task
+}
+
+// wakes up a loop that waits, it runs its micro tasks and timers again
+func (l *EventLoop) wake() {
+	select {
+	case l.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// drains the micro task queue
+func (l *EventLoop) RunMicroTasks() {
+	for l.microTasks.length > 0 {
+		l.microTasks.execCurrentTask()
+	}
+}
+
+// runs the micro tasks, then one timer or I/O callback at a time (each followed by
+// the micro tasks it queued) until nothing is left to wait for
+func (l *EventLoop) Run() {
+	for l.step() {
+	}
+}
+
+// what EventLoop.wait returns instead of the index of a case
+const (
+	// a micro task, a timer or a callback ran
+	LOOP_RAN = -1
+	// nothing is left to wait for
+	LOOP_IDLE = -2
+)
+
+// runs the queued micro tasks, or else waits for the next timer or I/O callback and runs it;
+// returns false when nothing is left to wait for
+func (l *EventLoop) step() bool {
+	chosen, _, _ := l.wait(nil)
+	return chosen != LOOP_IDLE
+}
+
+// runs the queued micro tasks, or else waits for the first of cases, the next timer
+// or I/O callback, or a task that wakes the loop up, and runs the timer or the callback.
+// returns the index of the case that is ready and what reflect.Select received from it,
+// LOOP_RAN when something else ran and LOOP_IDLE when nothing but cases is left to wait for
+func (l *EventLoop) wait(cases []reflect.SelectCase) (int, reflect.Value, bool) {
+	if l.microTasks.length > 0 {
+		l.RunMicroTasks()
+		return LOOP_RAN, reflect.Value{}, false
+	}
+	all := append(slices.Clip(cases), receiveCase(l.callbacks), receiveCase(l.wakeup))
+	if len(l.timers) > 0 {
+		wait := time.Until(l.timers[0].when)
+		if wait <= 0 {
+			l.runTimer()
+			return LOOP_RAN, reflect.Value{}, false
+		}
+		all = append(all, receiveCase(time.After(wait)))
+	} else if l.pending == 0 {
+		// nothing that could post a callback is in flight
+		all = append(all, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	var chosen int
+	var value reflect.Value
+	var ok bool
+	unlocked(func() {
+		chosen, value, ok = reflect.Select(all)
+	})
+	if chosen < len(cases) {
+		return chosen, value, ok
+	}
+	switch chosen - len(cases) {
+	case 0:
+		l.receive(value.Interface().(Task))
+	case 1:
+		// woken up
+	default:
+		// the timer is run by the next call, unless it was cleared meanwhile
+		if len(l.timers) == 0 && l.pending == 0 {
+			return LOOP_IDLE, reflect.Value{}, false
+		}
+	}
+	return LOOP_RAN, reflect.Value{}, false
+}
+
+// runs the callback of a pending operation
+func (l *EventLoop) receive(task Task) {
+	l.pending--
+	task.macro.call(task.args, task.env, task.pos, task.r)
+	Memory.collect()
+}
+
+// runs the timer due first
+func (l *EventLoop) runTimer() {
+	timer := heap.Pop(&l.timers).(*Timer)
+	if timer.interval > 0 {
+		// scheduled before the callback runs, so clearInterval in it works
+		timer.when = time.Now().Add(timer.interval)
+		heap.Push(&l.timers, timer)
+	} else {
+		delete(l.active, timer.id)
+	}
+	task := timer.task
+	task.macro.call(task.args, task.env, task.pos, task.r)
+	Memory.collect()
+}
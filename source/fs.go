@@ -1,25 +1,30 @@
 package main
 
-import "os"
+import (
+	"io/fs"
+	"os"
+)
 
 func pathExists(path string) bool {
+	if IsStdPath(path) {
+		_, err := fs.Stat(stdlibFS, stdFile(path))
+		return err == nil
+	}
 	f, err := os.Open(path)
 	f.Close()
 	return err == nil
 }
 
 func ReadTextFile(path string) string {
-	bytes, err := os.ReadFile(path)
-	if err != nil {
-		throwError(err)
+	var bytes []byte
+	var err error
+	if IsStdPath(path) {
+		bytes, err = stdlibFS.ReadFile(stdFile(path))
+	} else {
+		bytes, err = os.ReadFile(path)
 	}
-	return string(bytes)
-}
-
-func CreateTempFile(pattern string) *os.File {
-	file, err := os.CreateTemp("temp", pattern)
 	if err != nil {
 		throwError(err)
 	}
-	return file
+	return string(bytes)
 }
@@ -0,0 +1,452 @@
+package main
+
+import "runtime"
+
+// GeneratorState is the native side of an instance of the stdlib Generator or AsyncGenerator
+// class, stored in its #state field. The body of the generator function runs on a
+// coroutine that suspends at each yield until next, return or throw resumes it.
+type GeneratorState struct {
+	co *Coroutine
+	// the interpreter that runs the body
+	r *Interpreter
+	// how the body goes on when it resumes: "next", "return" or "throw"
+	mode string
+	// the value passed to next, return or throw, then the value the body yields or returns
+	value RuntimeVal
+	// what the body threw, it is thrown again by the call that resumed it
+	thrown  *ThrownError
+	started bool
+	running bool
+	done    bool
+	// runs the body on the coroutine, started by the first next so that a generator that
+	// is never used has no goroutine
+	body func()
+	// set for async generators: the body can await, next, return and throw return promises
+	async bool
+	// the calls of an async generator the body has not got to yet, the first is the one it runs for
+	requests []*generatorRequest
+}
+
+// a call of next, return or throw on an async generator
+type generatorRequest struct {
+	mode    string
+	value   RuntimeVal
+	promise *PromiseState
+}
+
+// unwinds the body of a generator when return resumes it, finally blocks
+// still run; EvalTryBlock turns it into a return from the generator
+type generatorReturn struct {
+	value RuntimeVal
+}
+
+// calls a generator function: the parameters are declared right away, the body
+// waits for the first next of the generator object that is returned
+func (r *Interpreter) CallGenerator(fn *FunctionVal, args []RuntimeVal, scope *Environment, env *Environment, pos Pos) *Instance {
+	DeclareParams(fn.params, args, scope, r)
+	name := "Generator"
+	if fn.async {
+		name = "AsyncGenerator"
+	}
+	class := stdEnv.ReferenceOf(name, pos.line, pos.col, pos.count, env.sourcePath, r)
+	generator := r.Instantiate(class, env, []RuntimeVal{}, pos)
+	state := stateOf[*GeneratorState](generator, "function*", name, env, pos)
+	state.co = NewCoroutine()
+	state.async = fn.async
+	if fn.async {
+		// await suspends the coroutine the way yield does
+		state.r = r.fork(state.co)
+	} else {
+		// await is not valid in the body, the interpreter has no coroutine to suspend
+		state.r = r.fork(nil)
+	}
+	state.r.generator = state
+	state.value = undefined
+	fork := state.r
+	state.body = func() {
+		var value RuntimeVal
+		var err error
+		defer func() {
+			if rec := recover(); rec != nil {
+				returned, ok := rec.(*generatorReturn)
+				if !ok {
+					panic(rec)
+				}
+				// return resumed it outside of any try statement
+				value, err = returned.value, nil
+			}
+			if state.async {
+				state.finish(value, err, env, pos)
+				return
+			}
+			state.value = value
+			if err != nil {
+				state.thrown = err.(*ThrownError)
+			}
+		}()
+		value, err = fork.guard(func() RuntimeVal {
+			return fork.pushToStack(*fn, *scope)
+		})
+	}
+	// cleanups run one at a time on a single goroutine, abandon waits for the interpreter
+	runtime.AddCleanup(generator, func(state *GeneratorState) { go state.abandon() }, state)
+	return generator
+}
+
+// starts the body on the first resume
+func (state *GeneratorState) begin() {
+	state.started = true
+	if state.body != nil {
+		state.co.start(state.body)
+		state.body = nil
+	}
+}
+
+// started once nothing refers to the generator object anymore: a body left suspended at a
+// yield is resumed with a return, so that it unwinds and its goroutine ends
+func (state *GeneratorState) abandon() {
+	lockInterpreter()
+	defer interpreterLock.Unlock()
+	if !state.started || state.done || state.running {
+		return
+	}
+	// it runs on a goroutine of its own, like a task
+	state.r.task = true
+	state.mode, state.value = "return", undefined
+	state.running = true
+	if state.async {
+		state.co.task = true
+		state.requests = append(state.requests, &generatorRequest{"return", undefined, &PromiseState{state: PENDING, value: undefined, handled: true}})
+	}
+	state.co.run()
+	if !state.async {
+		state.running, state.thrown = false, nil
+	}
+}
+
+// resumes the body with mode and value, returns the { value, done } record next, return and throw return
+func (state *GeneratorState) resume(mode string, value RuntimeVal, r *Interpreter, env *Environment, pos Pos) RuntimeVal {
+	if state.async {
+		return state.request(mode, value, r, env, pos)
+	}
+	if state.running {
+		env.ThrowTypeError("the generator is already running, it cannot resume itself", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	if state.co == nil || !state.started && mode != "next" {
+		// return and throw finish a generator that has not started without running its body
+		state.done = true
+	}
+	if state.done {
+		if mode == "throw" {
+			env.throwValue(value, r)
+		}
+		if mode == "next" {
+			value = undefined
+		}
+		return MK_RECORD(r, []string{"value", "done"}, value, MK_BOOL(true))
+	}
+	state.mode, state.value = mode, value
+	state.begin()
+	state.running = true
+	// the body waits the way the code that resumes it does
+	state.r.task = r.onTask()
+	state.co.run()
+	state.running = false
+	state.done = state.co.done
+	if thrown := state.thrown; thrown != nil {
+		state.thrown = nil
+		panic(thrown)
+	}
+	return MK_RECORD(r, []string{"value", "done"}, state.value, MK_BOOL(state.done))
+}
+
+// next, return or throw of an async generator: returns a promise of the { value, done } record.
+// calls made while the body runs are queued, each one resumes it after the one before
+func (state *GeneratorState) request(mode string, value RuntimeVal, r *Interpreter, env *Environment, pos Pos) RuntimeVal {
+	promise, settled := NewPromise(env, r, pos)
+	req := &generatorRequest{mode, value, settled}
+	if state.co == nil || !state.started && mode != "next" {
+		state.done = true
+	}
+	if state.done {
+		req.settleDone(r, env, pos)
+		return promise
+	}
+	state.requests = append(state.requests, req)
+	if !state.running {
+		state.begin()
+		state.running = true
+		state.mode, state.value = mode, value
+		state.co.task = r.onTask()
+		state.r.task = r.onTask()
+		state.co.run()
+	}
+	return promise
+}
+
+// settles a request the finished body of an async generator cannot run for
+func (req *generatorRequest) settleDone(r *Interpreter, env *Environment, pos Pos) {
+	switch req.mode {
+	case "throw":
+		req.promise.settle(REJECTED, req.value, r, env, pos)
+	case "return":
+		req.promise.resolve(MK_RECORD(r, []string{"value", "done"}, req.value, MK_BOOL(true)), r, env, pos)
+	default:
+		req.promise.resolve(MK_RECORD(r, []string{"value", "done"}, undefined, MK_BOOL(true)), r, env, pos)
+	}
+}
+
+// called on the coroutine when the body of an async generator returns or throws:
+// settles the request it ran for, and the ones queued after it as the generator is done
+func (state *GeneratorState) finish(value RuntimeVal, err error, env *Environment, pos Pos) {
+	state.done, state.running = true, false
+	requests := state.requests
+	state.requests = nil
+	if len(requests) == 0 {
+		return
+	}
+	if err != nil {
+		requests[0].promise.settle(REJECTED, err.(*ThrownError).value, state.r, env, pos)
+	} else {
+		requests[0].promise.resolve(MK_RECORD(state.r, []string{"value", "done"}, value, MK_BOOL(true)), state.r, env, pos)
+	}
+	for _, req := range requests[1:] {
+		req.settleDone(state.r, env, pos)
+	}
+}
+
+// called on the coroutine: hands value to the code that resumed the body and waits until it
+// is resumed again, returns how it was resumed and the value next, return or throw passed
+func (state *GeneratorState) suspend(value RuntimeVal, env *Environment, pos Pos) (string, RuntimeVal) {
+	if !state.async {
+		state.value = value
+		state.co.suspend()
+		return state.mode, state.value
+	}
+	// an async generator yields what value settles with
+	value = state.r.Await(value, env, pos)
+	req := state.requests[0]
+	state.requests = state.requests[1:]
+	req.promise.resolve(MK_RECORD(state.r, []string{"value", "done"}, value, MK_BOOL(false)), state.r, env, pos)
+	if len(state.requests) == 0 {
+		state.running = false
+		state.co.suspend()
+	}
+	req = state.requests[0]
+	return req.mode, req.value
+}
+
+// yields value: a throw is thrown and a return returns from where the body was resumed
+func (state *GeneratorState) yield(value RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	mode, sent := state.suspend(value, env, pos)
+	switch mode {
+	case "throw":
+		env.throwValue(sent, state.r)
+	case "return":
+		panic(&generatorReturn{sent})
+	}
+	return sent
+}
+
+func (r *Interpreter) Eval_yield_expr(expr *YieldExpr, env *Environment) RuntimeVal {
+	state := r.generator
+	if state == nil {
+		env.ThrowSyntaxError("illegal use of the yield keyword, yield expressions can only be used in the body of generator functions",
+			SourceLog(expr.line, expr.col, expr.count, env.sourcePath, ""))
+	}
+	var value RuntimeVal = undefined
+	if expr.operand != nil {
+		value = r.Evaluate(expr.operand, env)
+	}
+	if expr.delegate {
+		return r.yieldAll(state, value, env, expr.Pos)
+	}
+	return state.yield(value, env, expr.Pos)
+}
+
+// yield* iterable: yields the values of iterable one by one and returns the value its iterator returns.
+// next, return and throw of the generator are passed on to the iterator of an iterable instance,
+// async generators use the Symbol.asyncIterator method of iterable when it has one
+func (r *Interpreter) yieldAll(state *GeneratorState, iterable RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	var iterator RuntimeVal
+	step := r.iteratorStep
+	if state.async {
+		var sync bool
+		if iterator, sync = r.getAsyncIterator(iterable, env, pos); !sync {
+			step = r.asyncIteratorStep
+		}
+	} else {
+		iterator = r.getIterator(iterable, env, pos)
+	}
+	if iterator == nil {
+		for _, value := range r.iterableValues(iterable, -1, "yield*", env, pos) {
+			state.yield(value, env, pos)
+		}
+		return undefined
+	}
+	mode := "next"
+	var sent RuntimeVal = undefined
+	for {
+		if methodOf(iterator, MK_STRING(mode)) == nil {
+			switch mode {
+			case "return":
+				panic(&generatorReturn{sent})
+			case "throw":
+				r.closeIterator(iterator, env, pos)
+				env.ThrowTypeError("yield*: the iterator has no throw method", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+		}
+		value, done := step(iterator, mode, []RuntimeVal{sent}, env, pos)
+		if done {
+			if mode == "return" {
+				panic(&generatorReturn{value})
+			}
+			return value
+		}
+		mode, sent = state.suspend(value, env, pos)
+	}
+}
+
+//#region iterators
+
+// the key of the Symbol.iterator method of iterables
+func iteratorKey() RuntimeVal {
+	return MK_SYMBOL("iterator")
+}
+
+// the key of the Symbol.asyncIterator method of async iterables
+func asyncIteratorKey() RuntimeVal {
+	return MK_SYMBOL("asyncIterator")
+}
+
+// the method key of an object or an instance, nil when it has none; private
+// methods are found too, iterables may keep their Symbol.iterator private
+func methodOf(value RuntimeVal, key RuntimeVal) RuntimeVal {
+	// object literals keep symbol keys as they are, classes store them under their name
+	name := MK_STRING(key.noAnsi())
+	var ml Ref
+	switch v := value.(type) {
+	case *ObjectVal:
+		if ml = v.properties.get(key); ml == nil {
+			ml = v.properties.get(name)
+		}
+	case *Instance:
+		if ml = v.properties.get(key); ml == nil {
+			ml = v.properties.get(name)
+		}
+		if ml == nil {
+			ml = GetPropMlFromProto(name, v.prototype)
+		}
+	}
+	switch fn := Memory.get(ml).(type) {
+	case *FunctionVal, *Macro:
+		return fn
+	}
+	return nil
+}
+
+// calls the Symbol.iterator method of value and returns the iterator it returns,
+// nil when value is not an object or an instance with such a method
+func (r *Interpreter) getIterator(value RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	method := methodOf(value, iteratorKey())
+	if method == nil {
+		return nil
+	}
+	iterator, _ := CallFunction(method, env, []RuntimeVal{}, r, pos)
+	if methodOf(iterator, MK_STRING("next")) == nil {
+		env.ThrowTypeError("the Symbol.iterator method of type", ValueType(value), "must return an iterator, an object with a next method",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return iterator
+}
+
+// calls the method name (next, return or throw) of iterator and reads
+// the value and done of the { value, done } result it returns
+func (r *Interpreter) iteratorStep(iterator RuntimeVal, name string, args []RuntimeVal, env *Environment, pos Pos) (RuntimeVal, bool) {
+	result, _ := CallFunction(methodOf(iterator, MK_STRING(name)), env, args, r, pos)
+	return r.iteratorResult(result, name, env, pos)
+}
+
+// iteratorStep for async iterators, it waits for the promise of the result the method returns
+func (r *Interpreter) asyncIteratorStep(iterator RuntimeVal, name string, args []RuntimeVal, env *Environment, pos Pos) (RuntimeVal, bool) {
+	result, _ := CallFunction(methodOf(iterator, MK_STRING(name)), env, args, r, pos)
+	return r.iteratorResult(r.Await(result, env, pos), name, env, pos)
+}
+
+// the value and done of the result of the method name of an iterator
+func (r *Interpreter) iteratorResult(result RuntimeVal, name string, env *Environment, pos Pos) (RuntimeVal, bool) {
+	var value, done RuntimeVal = undefined, undefined
+	switch result := result.(type) {
+	case *ObjectVal, *Instance:
+		value = r.memberValue(r.MemberRef(result, MK_STRING("value"), false, pos, env), env, pos)
+		done = r.memberValue(r.MemberRef(result, MK_STRING("done"), false, pos, env), env, pos)
+	default:
+		env.ThrowTypeError("the "+name+" method of an iterator must return an object, got type", ValueType(result),
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return value, RtvToBool(done)
+}
+
+// calls the Symbol.asyncIterator method of value and returns the iterator it returns. values without
+// one fall back to their Symbol.iterator method, sync is set then; nil when value has neither
+func (r *Interpreter) getAsyncIterator(value RuntimeVal, env *Environment, pos Pos) (iterator RuntimeVal, sync bool) {
+	method := methodOf(value, asyncIteratorKey())
+	if method == nil {
+		return r.getIterator(value, env, pos), true
+	}
+	iterator, _ = CallFunction(method, env, []RuntimeVal{}, r, pos)
+	if methodOf(iterator, MK_STRING("next")) == nil {
+		env.ThrowTypeError("the Symbol.asyncIterator method of type", ValueType(value), "must return an async iterator, an object with a next method",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return iterator, false
+}
+
+// tells an iterator that is left before it is done that no more values are read, its return
+// method is called when it has one, the one of a generator runs its finally blocks.
+// returns what the method returns, nil when there is none
+func (r *Interpreter) closeIterator(iterator RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	method := methodOf(iterator, MK_STRING("return"))
+	if method == nil {
+		return nil
+	}
+	// a loop may be left by a return or a labelled break that is still unwinding, the method runs in between
+	terminated, returned, label, _goto := r.terminated, r.returned_from_function, r.label, r._goto
+	r.terminated, r.returned_from_function, r.label, r._goto = false, false, "", false
+	result, _ := CallFunction(method, env, []RuntimeVal{}, r, pos)
+	r.terminated, r.returned_from_function, r.label, r._goto = terminated, returned, label, _goto
+	return result
+}
+
+// the values of an array, a string or an iterable object or instance, at most limit of
+// them when limit is not negative; an iterator that has more values left is closed
+func (r *Interpreter) iterableValues(value RuntimeVal, limit int, name string, env *Environment, pos Pos) []RuntimeVal {
+	values := []RuntimeVal{}
+	switch v := value.(type) {
+	case *ArrayVal:
+		values = v.values()
+	case *StringVal:
+		for _, char := range v.value {
+			values = append(values, MK_STRING(string(char)))
+		}
+	default:
+		iterator := r.getIterator(value, env, pos)
+		if iterator == nil {
+			env.ThrowTypeError(name+": type", ValueType(value), "is not iterable", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		for limit < 0 || len(values) < limit {
+			value, done := r.iteratorStep(iterator, "next", []RuntimeVal{}, env, pos)
+			if done {
+				return values
+			}
+			values = append(values, value)
+		}
+		r.closeIterator(iterator, env, pos)
+	}
+	if limit >= 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
+
+//#endregion
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+)
+
+// Cell is a slot on the heap. Variables, properties and array elements refer to
+// the cell that holds their value, so an assignment through one of them is seen by all.
+type Cell struct {
+	value RuntimeVal
+}
+
+// Ref is the address of a cell, nil refers to nothing.
+type Ref = *Cell
+
+// Heap allocates the cells of every interpreter.
+//
+// references are plain Go pointers, so they are deterministic and never collide,
+// and the Go collector (a concurrent mark-and-sweep) frees a cell as soon as no
+// environment, value, call stack frame, coroutine or queued task refers to it.
+type Heap struct {
+	// when collect last gave memory back to the OS
+	freed time.Time
+}
+
+var Memory = &Heap{}
+
+// how often collect gives memory back to the OS at most, each time is a full collection
+const freeInterval = time.Second
+
+// allocates a cell that holds value
+func (h *Heap) alloc(value RuntimeVal) Ref {
+	return &Cell{value: value}
+}
+
+// returns the value a cell holds, nil when ref refers to nothing or was deleted
+func (h *Heap) get(ref Ref) RuntimeVal {
+	if ref == nil {
+		return nil
+	}
+	return ref.value
+}
+
+func (h *Heap) set(ref Ref, value RuntimeVal) {
+	if ref != nil {
+		ref.value = value
+	}
+}
+
+// empties a cell, the value is collected once nothing else refers to it
+func (h *Heap) delete(ref Ref) {
+	if ref != nil {
+		ref.value = nil
+	}
+}
+
+// called by the event loop between tasks. the Go collector frees values on its own,
+// this only gives freed memory back to the OS once the heap in use grows past the
+// --mem threshold, at most once per freeInterval
+func (h *Heap) collect() {
+	if time.Since(h.freed) < freeInterval {
+		return
+	}
+	// unlike runtime.ReadMemStats, reading a metric does not stop the world
+	inUse := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(inUse)
+	if inUse[0].Value.Uint64() > memThreshold {
+		debug.FreeOSMemory()
+		h.freed = time.Now()
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// sets the threshold of collect for the test and resets when memory was last freed
+func withThreshold(t *testing.T, threshold uint64) {
+	saved := memThreshold
+	memThreshold = threshold
+	Memory.freed = time.Time{}
+	t.Cleanup(func() {
+		memThreshold = saved
+		Memory.freed = time.Time{}
+	})
+}
+
+func TestCollectFreesAtMostOncePerInterval(t *testing.T) {
+	// any heap in use is past the threshold
+	withThreshold(t, 0)
+	Memory.collect()
+	first := Memory.freed
+	if first.IsZero() {
+		t.Fatal("collect did not give memory back to the OS past the threshold")
+	}
+	Memory.collect()
+	if !Memory.freed.Equal(first) {
+		t.Error("collect gave memory back twice within freeInterval")
+	}
+	Memory.freed = first.Add(-freeInterval)
+	Memory.collect()
+	if !Memory.freed.After(first) {
+		t.Error("collect did not give memory back once freeInterval passed")
+	}
+}
+
+func TestCollectKeepsMemoryBelowThreshold(t *testing.T) {
+	withThreshold(t, math.MaxUint64)
+	Memory.collect()
+	if !Memory.freed.IsZero() {
+		t.Error("collect gave memory back to the OS below the threshold")
+	}
+}
+
+func TestCellsHoldValues(t *testing.T) {
+	ref := Memory.alloc(MK_NUMBER(1))
+	Memory.set(ref, MK_STRING("a"))
+	if value, ok := Memory.get(ref).(*StringVal); !ok || value.value != "a" {
+		t.Errorf("the cell holds %v, expected the string a", Memory.get(ref))
+	}
+	Memory.delete(ref)
+	if Memory.get(ref) != nil || Memory.get(nil) != nil {
+		t.Error("a deleted cell or a nil reference holds a value")
+	}
+}
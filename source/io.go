@@ -10,7 +10,12 @@ import (
 
 func GetUserInput() string {
 	input_reader := bufio.NewReader(os.Stdin)
-	input, err := input_reader.ReadString('\n')
+	var input string
+	var err error
+	// tasks keep running while the user types
+	unlocked(func() {
+		input, err = input_reader.ReadString('\n')
+	})
 	if err != nil {
 		throwError(err)
 	}
@@ -20,7 +25,11 @@ func GetUserInput() string {
 func Prompt(message string, _default string) (string, error) {
 	fmt.Print(message)
 	input_reader := bufio.NewReader(os.Stdin)
-	input, err := input_reader.ReadString('\n')
+	var input string
+	var err error
+	unlocked(func() {
+		input, err = input_reader.ReadString('\n')
+	})
 	return strings.TrimSpace(input), err
 }
 
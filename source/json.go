@@ -0,0 +1,537 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// JSON maps to AS values: objects to objects, arrays to arrays and numbers,
+// strings, booleans and null to themselves
+
+//#region JSON.stringify
+
+type stringifier struct {
+	r   *Interpreter
+	env *Environment
+	pos Pos
+	// called with the key and the value of every member, nil when there is none
+	replacer RuntimeVal
+	// the only keys objects are written with, nil when any key is
+	allowed []string
+	indent  string
+	// the objects and arrays being written, a value in it refers to itself
+	stack []any
+}
+
+// the JSON text of value, ok is false for values JSON has no text for
+// (undefined, functions and symbols)
+func (r *Interpreter) stringifyJSON(value, replacer, space RuntimeVal, env *Environment, pos Pos) (string, bool) {
+	s := &stringifier{r: r, env: env, pos: pos}
+	switch replacer := replacer.(type) {
+	case *FunctionVal, *Macro:
+		s.replacer = replacer
+	case *ArrayVal:
+		s.allowed = []string{}
+		replacer.forEach(func(_ int, key RuntimeVal) {
+			switch key := key.(type) {
+			case *StringVal:
+				s.allowed = append(s.allowed, key.value)
+			case *NumberVal:
+				s.allowed = append(s.allowed, key.noAnsi())
+			}
+		})
+	}
+	switch space := space.(type) {
+	case *NumberVal:
+		s.indent = strings.Repeat(" ", int(min(max(space.value, 0), 10)))
+	case *StringVal:
+		s.indent = string([]rune(space.value)[:min(utf8.RuneCountInString(space.value), 10)])
+	}
+	out := strings.Builder{}
+	ok := s.write(&out, "", value, "")
+	return out.String(), ok
+}
+
+// writes the text of the member key of an object or an array, indent is that of
+// the line it is on; ok is false when the member is left out
+func (s *stringifier) write(out *strings.Builder, key string, value RuntimeVal, indent string) bool {
+	if toJSON := s.method(value, "toJSON"); toJSON != nil {
+		value, _ = CallFunction(toJSON, s.env, []RuntimeVal{MK_STRING(key)}, s.r, s.pos)
+	}
+	if s.replacer != nil {
+		value, _ = CallFunction(s.replacer, s.env, []RuntimeVal{MK_STRING(key), value}, s.r, s.pos)
+	}
+	switch v := value.(type) {
+	case nil, *NullVal:
+		out.WriteString("null")
+	case *BoolVal:
+		out.WriteString(strconv.FormatBool(v.value))
+	case *NumberVal:
+		out.WriteString(jsonNumber(v.value))
+	case *BigIntVal:
+		s.env.ThrowTypeError("JSON.stringify cannot serialize a bigint, convert it with Number() or to a string first", SourceLog(s.pos.line, s.pos.col, s.pos.count, s.env.sourcePath, ""))
+	case *StringVal:
+		out.WriteString(quoteJSON(v.value))
+	case *ArrayVal:
+		s.enter(v)
+		defer s.leave()
+		inner := indent + s.indent
+		out.WriteByte('[')
+		for i := 0; i < v.elements.length; i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			s.newline(out, inner)
+			// members JSON has no text for are null in arrays
+			if !s.write(out, strconv.Itoa(i), v.get(i), inner) {
+				out.WriteString("null")
+			}
+		}
+		if v.elements.length > 0 {
+			s.newline(out, indent)
+		}
+		out.WriteByte(']')
+	case *ObjectVal, *Instance:
+		s.enter(v)
+		defer s.leave()
+		inner := indent + s.indent
+		out.WriteByte('{')
+		written := 0
+		for _, member := range s.members(v) {
+			if s.allowed != nil && !slices.Contains(s.allowed, member.key) {
+				continue
+			}
+			text := strings.Builder{}
+			if !s.write(&text, member.key, member.value, inner) {
+				continue
+			}
+			if written > 0 {
+				out.WriteByte(',')
+			}
+			s.newline(out, inner)
+			out.WriteString(quoteJSON(member.key))
+			out.WriteByte(':')
+			if s.indent != "" {
+				out.WriteByte(' ')
+			}
+			out.WriteString(text.String())
+			written++
+		}
+		if written > 0 {
+			s.newline(out, indent)
+		}
+		out.WriteByte('}')
+	default:
+		// undefined, functions, classes, symbols and raw values
+		return false
+	}
+	return true
+}
+
+type member struct {
+	key   string
+	value RuntimeVal
+}
+
+// the members of an object or an instance JSON has, in the order they were added: the
+// properties with string keys, and of instances their public fields
+func (s *stringifier) members(value RuntimeVal) []member {
+	members := []member{}
+	seen := map[string]bool{}
+	add := func(properties *Map[RuntimeVal, Ref], instance *Instance) {
+		properties.forEach(func(key RuntimeVal, ml Ref) {
+			k, ok := key.(*StringVal)
+			if !ok || seen[k.value] || instance != nil && privateMember(instance, k.value) {
+				return
+			}
+			if instance != nil {
+				// methods and accessors belong to the class
+				switch Memory.get(ml).(type) {
+				case *FunctionVal, *Accessor:
+					return
+				}
+			}
+			value := s.r.memberValue(ml, s.env, s.pos)
+			seen[k.value] = true
+			members = append(members, member{k.value, value})
+		})
+	}
+	switch v := value.(type) {
+	case *ObjectVal:
+		add(v.properties, nil)
+	case *Instance:
+		// the fields of an instance are kept in its prototype chain
+		for proto := v.prototype; ; {
+			object, ok := proto.(*ObjectVal)
+			if !ok {
+				break
+			}
+			add(object.properties, v)
+			proto = object.prototype
+		}
+		add(v.properties, v)
+	}
+	return members
+}
+
+// reports whether key is a private member of the class of instance or of one it extends
+func privateMember(instance *Instance, key string) bool {
+	if strings.HasPrefix(key, "#") {
+		return true
+	}
+	for class, ok := Memory.get(instance.class).(*ClassVal); ok; class, ok = Memory.get(class.extends).(*ClassVal) {
+		if class.private[key] {
+			return true
+		}
+		if class.extends == nil {
+			break
+		}
+	}
+	return false
+}
+
+// the method name of an object or an instance, nil when it has none
+func (s *stringifier) method(value RuntimeVal, name string) RuntimeVal {
+	switch value.(type) {
+	case *ObjectVal, *Instance:
+		ml := s.r.MemberRef(value, MK_STRING(name), false, s.pos, s.env)
+		if ml == nil {
+			return nil
+		}
+		switch fn := Memory.get(ml).(type) {
+		case *FunctionVal, *Macro:
+			return fn
+		}
+	}
+	return nil
+}
+
+// throws when value is already being written, it would be written endlessly
+func (s *stringifier) enter(value RuntimeVal) {
+	id := identity(value)
+	if slices.Contains(s.stack, id) {
+		s.env.ThrowTypeError("JSON.stringify cannot convert a structure that refers to itself",
+			SourceLog(s.pos.line, s.pos.col, s.pos.count, s.env.sourcePath, ""))
+	}
+	s.stack = append(s.stack, id)
+}
+
+func (s *stringifier) leave() {
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+// starts a new line indented with indent, nothing is written when there is no indentation
+func (s *stringifier) newline(out *strings.Builder, indent string) {
+	if s.indent != "" {
+		out.WriteByte('\n')
+		out.WriteString(indent)
+	}
+}
+
+// numbers that are not finite are null
+func jsonNumber(n float64) string {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return "null"
+	}
+	return formatNumber(n)
+}
+
+// str as a JSON string, only quotes, backslashes and control characters are escaped
+func quoteJSON(str string) string {
+	out := strings.Builder{}
+	out.WriteByte('"')
+	for _, char := range str {
+		switch char {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if char < 0x20 {
+				fmt.Fprintf(&out, `\u%04x`, char)
+			} else {
+				out.WriteRune(char)
+			}
+		}
+	}
+	out.WriteByte('"')
+	return out.String()
+}
+
+//#endregion
+
+//#region JSON.parse
+
+type jsonParser struct {
+	text   string
+	offset int
+	r      *Interpreter
+	env    *Environment
+	pos    Pos
+}
+
+// the value of the JSON text, throws a SyntaxError saying where text is invalid
+func (r *Interpreter) parseJSON(text string, env *Environment, pos Pos) RuntimeVal {
+	p := &jsonParser{text: text, r: r, env: env, pos: pos}
+	value := p.value()
+	p.space()
+	if p.offset < len(p.text) {
+		p.unexpected()
+	}
+	return value
+}
+
+// throws a SyntaxError at the current offset
+func (p *jsonParser) fail(message string) {
+	line := strings.Count(p.text[:p.offset], "\n") + 1
+	column := utf8.RuneCountInString(p.text[strings.LastIndex(p.text[:p.offset], "\n")+1:p.offset]) + 1
+	p.env.ThrowSyntaxError(fmt.Sprintf("JSON.parse: %s at line %d column %d of the JSON text", message, line, column),
+		SourceLog(p.pos.line, p.pos.col, p.pos.count, p.env.sourcePath, ""))
+}
+
+func (p *jsonParser) unexpected() {
+	if p.offset >= len(p.text) {
+		p.fail("unexpected end of the JSON text")
+	}
+	char, _ := utf8.DecodeRuneInString(p.text[p.offset:])
+	p.fail(fmt.Sprintf("unexpected character %q", char))
+}
+
+func (p *jsonParser) space() {
+	for p.offset < len(p.text) && strings.IndexByte(" \t\n\r", p.text[p.offset]) >= 0 {
+		p.offset++
+	}
+}
+
+// eats char, which must come next
+func (p *jsonParser) expect(char byte) {
+	p.space()
+	if p.offset >= len(p.text) || p.text[p.offset] != char {
+		p.unexpected()
+	}
+	p.offset++
+}
+
+// reports whether char comes next and eats it if so
+func (p *jsonParser) consume(char byte) bool {
+	p.space()
+	if p.offset < len(p.text) && p.text[p.offset] == char {
+		p.offset++
+		return true
+	}
+	return false
+}
+
+func (p *jsonParser) value() RuntimeVal {
+	p.space()
+	if p.offset >= len(p.text) {
+		p.unexpected()
+	}
+	for word, value := range map[string]RuntimeVal{"true": MK_BOOL(true), "false": MK_BOOL(false), "null": null} {
+		if strings.HasPrefix(p.text[p.offset:], word) {
+			p.offset += len(word)
+			return value
+		}
+	}
+	switch char := p.text[p.offset]; {
+	case char == '{':
+		return p.object()
+	case char == '[':
+		return p.array()
+	case char == '"':
+		return MK_STRING(p.string())
+	case char == '-' || char >= '0' && char <= '9':
+		return p.number()
+	}
+	p.unexpected()
+	return nil
+}
+
+func (p *jsonParser) object() RuntimeVal {
+	p.expect('{')
+	object := MK_OBJECT(nil, nil, p.r)
+	// a key that is repeated keeps its last value
+	refs := map[string]Ref{}
+	if p.consume('}') {
+		return object
+	}
+	for {
+		p.space()
+		if p.offset >= len(p.text) || p.text[p.offset] != '"' {
+			p.unexpected()
+		}
+		key := p.string()
+		p.expect(':')
+		value := p.value()
+		if ml, ok := refs[key]; ok {
+			Memory.set(ml, value)
+		} else {
+			refs[key] = Memory.alloc(value)
+			object.properties.set(MK_STRING(key), refs[key])
+		}
+		if p.consume('}') {
+			return object
+		}
+		p.expect(',')
+	}
+}
+
+func (p *jsonParser) array() RuntimeVal {
+	p.expect('[')
+	array := MK_ARRAY()
+	if p.consume(']') {
+		return array
+	}
+	for {
+		array.Push(p.value())
+		if p.consume(']') {
+			return array
+		}
+		p.expect(',')
+	}
+}
+
+func (p *jsonParser) string() string {
+	p.offset++ // "
+	out := strings.Builder{}
+	for {
+		if p.offset >= len(p.text) {
+			p.fail("unterminated string")
+		}
+		char := p.text[p.offset]
+		switch {
+		case char == '"':
+			p.offset++
+			return out.String()
+		case char < 0x20:
+			p.fail("control character in string")
+		case char != '\\':
+			out.WriteByte(char)
+			p.offset++
+			continue
+		}
+		p.offset++ // \
+		if p.offset >= len(p.text) {
+			p.fail("unterminated string")
+		}
+		escape := p.text[p.offset]
+		p.offset++
+		switch escape {
+		case '"', '\\', '/':
+			out.WriteByte(escape)
+		case 'b':
+			out.WriteByte('\b')
+		case 'f':
+			out.WriteByte('\f')
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case 't':
+			out.WriteByte('\t')
+		case 'u':
+			char := p.hex()
+			// a surrogate pair
+			if utf16.IsSurrogate(char) && strings.HasPrefix(p.text[p.offset:], `\u`) {
+				start := p.offset
+				p.offset += 2
+				if pair := utf16.DecodeRune(char, p.hex()); pair != utf8.RuneError {
+					char = pair
+				} else {
+					p.offset = start
+				}
+			}
+			out.WriteRune(char)
+		default:
+			p.offset -= 2
+			p.fail("invalid escape sequence")
+		}
+	}
+}
+
+// the 4 hexadecimal digits of a \u escape
+func (p *jsonParser) hex() rune {
+	if p.offset+4 > len(p.text) || !isHex(p.text[p.offset:p.offset+4]) {
+		p.fail(`invalid \u escape`)
+	}
+	code, _ := strconv.ParseUint(p.text[p.offset:p.offset+4], 16, 32)
+	p.offset += 4
+	return rune(code)
+}
+
+func (p *jsonParser) number() RuntimeVal {
+	start := p.offset
+	digits := func() int {
+		from := p.offset
+		for p.offset < len(p.text) && p.text[p.offset] >= '0' && p.text[p.offset] <= '9' {
+			p.offset++
+		}
+		return p.offset - from
+	}
+	if p.text[p.offset] == '-' {
+		p.offset++
+	}
+	leading := p.offset
+	if n := digits(); n == 0 {
+		p.unexpected()
+	} else if n > 1 && p.text[leading] == '0' {
+		p.offset = leading + 1
+		p.unexpected()
+	}
+	if p.offset < len(p.text) && p.text[p.offset] == '.' {
+		p.offset++
+		if digits() == 0 {
+			p.unexpected()
+		}
+	}
+	if p.offset < len(p.text) && (p.text[p.offset] == 'e' || p.text[p.offset] == 'E') {
+		p.offset++
+		if p.offset < len(p.text) && (p.text[p.offset] == '+' || p.text[p.offset] == '-') {
+			p.offset++
+		}
+		if digits() == 0 {
+			p.unexpected()
+		}
+	}
+	n, _ := strconv.ParseFloat(p.text[start:p.offset], 64)
+	return MK_NUMBER(n)
+}
+
+// calls reviver with the key and the value of every member, the members first;
+// what it returns replaces the value, a member it returns undefined for is deleted
+func (r *Interpreter) revive(reviver RuntimeVal, key string, value RuntimeVal, env *Environment, pos Pos) RuntimeVal {
+	switch v := value.(type) {
+	case *ArrayVal:
+		for i := 0; i < v.elements.length; i++ {
+			v.set(i, r.revive(reviver, strconv.Itoa(i), v.get(i), env, pos))
+		}
+	case *ObjectVal:
+		v.properties.forEach(func(k RuntimeVal, ml Ref) {
+			revived := r.revive(reviver, k.noAnsi(), Memory.get(ml), env, pos)
+			if _, ok := revived.(*Undefined); ok {
+				v.properties.delete(k)
+			} else {
+				Memory.set(ml, revived)
+			}
+		})
+	}
+	revived, _ := CallFunction(reviver, env, []RuntimeVal{MK_STRING(key), value}, r, pos)
+	return revived
+}
+
+//#endregion
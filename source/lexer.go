@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
 )
 
 var TokenType = map[string]string{
@@ -11,14 +13,16 @@ var TokenType = map[string]string{
 	// "EOF": "End of File",
 	// literals
 	"Number":     "number",
+	"BigInt":     "bigint",
 	"String":     "string",
 	"TString":    "template-string",
+	"RegExp":     "regexp",
 	"Identifier": "identifier",
 	"Label":      "label",
 	// operators
 	"BinaryOp":     "binary-operator",
 	"Arrow":        "arrow",               // =>
-	"AssignmentOp": "assignment-operator", // =, +=, -= *= /= ??=
+	"AssignmentOp": "assignment-operator", // =, +=, -= *= /= ??= &= <<= ...
 	"ComparisonOp": "comparison-operator", // ==, ===, <=, >= < >
 	"LogicalOp":    "logical-operator",    // ==, ===, <=, >= < >
 	"IncreOp":      "increment-operator",  // ++
@@ -37,7 +41,8 @@ var TokenType = map[string]string{
 	"SQuote":       "single-quote",        // '
 	"BTick":        "back-tick",           // `
 	//
-	"Comment": "comment", // $ ...
+	"Comment":      "comment",       // $ ...
+	"BlockComment": "block-comment", // $* ... *$
 
 	"Spawn":    "spawn",
 	"Immortal": "immortal",
@@ -62,6 +67,13 @@ type Token struct {
 	line int
 	col  int
 	end  int
+	// the byte offset and length of the token in the source,
+	// quotes and delimiters included
+	offset int
+	length int
+	// comments that appear before the token (trivia),
+	// in source order and including their delimiters
+	comments []string
 }
 
 func (t Token) String() string {
@@ -78,16 +90,21 @@ var TokenSpecs []Spec = []Spec{
 	// {regexp.MustCompile(`^print`), TokenType["Print"]},
 
 	// literals
-	{regexp.MustCompile(`^[-]?\d+(\.\d+)?\b`), TokenType["Number"]},
+	{regexp.MustCompile(`^[-]?\d+(_\d+)*n\b`), TokenType["BigInt"]},
+	{regexp.MustCompile(`^0[bB][01]+(_[01]+)*n\b`), TokenType["BigInt"]},
+	{regexp.MustCompile(`^0[oO][0-7]+(_[0-7]+)*n\b`), TokenType["BigInt"]},
+	{regexp.MustCompile(`^0[xX][0-9a-fA-F]+(_[0-9a-fA-F]+)*n\b`), TokenType["BigInt"]},
+	{regexp.MustCompile(`^[-]?\d+(_\d+)*(\.\d+(_\d+)*)?([eE][-+]?\d+)?\b`), TokenType["Number"]},
 	{regexp.MustCompile(`"`), TokenType["DQuote"]},
 	{regexp.MustCompile(`'`), TokenType["SQuote"]},
 	{regexp.MustCompile("```"), "```"},
-	{regexp.MustCompile(`\$\{`), "${"},
-	{regexp.MustCompile(`\}\$`), "}$"},
+	{regexp.MustCompile(`^\$\*`), TokenType["BlockComment"]},
+	{regexp.MustCompile(`^\$[^\r\n]*`), TokenType["Comment"]},
 	{regexp.MustCompile("`"), TokenType["BTick"]},
-	{regexp.MustCompile(`0(b|B)([01]+|([01_][01])*)`), TokenType["Number"]},
-	{regexp.MustCompile(`0(o|O)[0-7]+[0-7_]*`), TokenType["Number"]},
-	{regexp.MustCompile(`0(x|X)[0-9a-fA-F]+[0-9a-fA-F_]*`), TokenType["Number"]},
+	// _ separates digits
+	{regexp.MustCompile(`^0[bB][01]+(_[01]+)*\b`), TokenType["Number"]},
+	{regexp.MustCompile(`^0[oO][0-7]+(_[0-7]+)*\b`), TokenType["Number"]},
+	{regexp.MustCompile(`^0[xX][0-9a-fA-F]+(_[0-9a-fA-F]+)*\b`), TokenType["Number"]},
 	{regexp.MustCompile(`^([a-zA-Z_#]+[a-zA-Z0-9_#]*)(\ )*(:\>)`), TokenType["Label"]},
 	{regexp.MustCompile(`^[a-zA-Z_#]+[a-zA-Z0-9_#]*`), TokenType["Identifier"]},
 	//
@@ -95,14 +112,21 @@ var TokenSpecs []Spec = []Spec{
 
 	// operators
 	{regexp.MustCompile(`\=\>`), TokenType["Arrow"]},
+	{regexp.MustCompile(`^(\>\>\>=|\<\<=|\>\>=)`), TokenType["AssignmentOp"]},
+	{regexp.MustCompile(`^(\>\>\>|\<\<|\>\>)`), TokenType["BinaryOp"]}, // shifts
 	{regexp.MustCompile(`^(===|==|!==|!=|\>=|\<=|\>|\<)`), TokenType["ComparisonOp"]},
-	{regexp.MustCompile(`^(\=|\+=|\-=|\*=|\/=|\%=|\?\?=)`), TokenType["AssignmentOp"]},
+	{regexp.MustCompile(`^(\=|\+=|\-=|\*\*=|\*=|\/=|\%=|\?\?=|\&\&=|\|\|=|\&=|\|=|\^=)`), TokenType["AssignmentOp"]},
 	{regexp.MustCompile(`^(\+\+)`), TokenType["IncreOp"]},
 	{regexp.MustCompile(`^(\-\-)`), TokenType["DecreOp"]},
+	{regexp.MustCompile(`^(\?\?)`), "??"},
+	{regexp.MustCompile(`^(\?\.)`), "?."},
 	{regexp.MustCompile(`^(\?)`), "?"},
 	{regexp.MustCompile(`^(\.\.\.)`), "..."},
+	{regexp.MustCompile(`^\.\.=?`), ".."}, // range patterns, .. and ..=
 	{regexp.MustCompile(`^(\+|\-|/|\%|\*\*|\*)`), TokenType["BinaryOp"]},
 	{regexp.MustCompile(`^(\&\&|\|\||\!)`), TokenType["LogicalOp"]},
+	{regexp.MustCompile(`^(\&|\||\^)`), TokenType["BinaryOp"]}, // bitwise
+	{regexp.MustCompile(`^~`), "~"},
 	{regexp.MustCompile(`^\(`), TokenType["OpenParen"]},
 	{regexp.MustCompile(`^\)`), TokenType["CloseParen"]},
 	{regexp.MustCompile(`^\{`), TokenType["OpenBrace"]},
@@ -113,16 +137,39 @@ var TokenSpecs []Spec = []Spec{
 	{regexp.MustCompile(`^;`), TokenType["SemiColon"]},
 	{regexp.MustCompile(`^\.`), TokenType["Dot"]},
 	{regexp.MustCompile(`^,`), TokenType["Comma"]},
-	// {regexp.MustCompile(`^\$.*(?:\$|$)`), TokenType["Comment"]},
+}
+
+// the delimiters of the AS expressions in Verdex templates, tried before TokenSpecs
+// in Verdex sources only: in AS code a comment may follow a closing brace
+var VerdexSpecs []Spec = []Spec{
+	{regexp.MustCompile(`^\$\{`), "${"},
+	{regexp.MustCompile(`^\}\$`), "}$"},
 }
 
 func Tokenize(source string, path string) *TokenArray {
+	return tokenize(source, path, false)
+}
+
+// tokenizes a Verdex source, see VerdexSpecs; a slash after < closes a tag there
+// and never starts a regular expression literal
+func TokenizeVerdex(source string, path string) *TokenArray {
+	return tokenize(source, path, true)
+}
+
+func tokenize(source string, path string, verdex bool) *TokenArray {
+	specs := TokenSpecs
+	if verdex {
+		specs = append(slices.Clip(VerdexSpecs), TokenSpecs...)
+	}
 	tokens := tokenArray()
 	position, line, column := 0, 1, 1
+	// comments waiting to be attached to the next token
+	trivia := []string{}
 
 top:
 	for position < len(source) {
 		matched := false
+		offset := position
 		remaining := source[position:]
 		var match struct {
 			length int
@@ -130,8 +177,8 @@ top:
 			src    string
 		}
 		// loop over token types to find a match
-		for i := 0; i < len(TokenSpecs); i++ {
-			pattern := TokenSpecs[i]
+		for i := 0; i < len(specs); i++ {
+			pattern := specs[i]
 			loc := pattern.exp.FindStringIndex(remaining)
 			if loc != nil && loc[0] == 0 {
 				match.length = loc[1]
@@ -145,10 +192,11 @@ top:
 					_string := ""
 					for position < len(source) && source[position] != quote {
 						char := source[position]
+						// bytes, string(char) would read a byte of UTF-8 text as a code point
+						_string += source[position : position+1]
 						position++ // eat character
-						_string += string(char)
-						if char == '\\' {
-							_string += string(source[position])
+						if char == '\\' && position < len(source) {
+							_string += source[position : position+1]
 							position++ // eat character
 						}
 					}
@@ -167,24 +215,35 @@ top:
 					match.src = _string
 					match.typ = TokenType["String"]
 				} else if match.typ == TokenType["BTick"] {
-					quote := source[position]
-					position++ // eat character
-					template_string := ""
-					for position < len(source) && source[position] != quote {
-						char := source[position]
-						position++ // eat character
-						template_string += string(char)
-						if char == '\\' {
-							template_string += string(source[position])
-							position++ // eat character
-						}
-					}
-					if position >= len(source) || source[position] != quote {
+					end := scanTemplate(source, position+1)
+					if end < 0 {
 						throwMessage(SyntaxError("unclosed template literal:" + SourceLog(line, column, 1, path, "")))
 					}
-					match.src = template_string
+					// the raw text between the back-ticks, interpolations included
+					match.src = source[position+1 : end]
+					match.length = end + 1 - position
 					match.typ = TokenType["TString"]
-				} else if match.typ == "comment" {
+				} else if match.typ == TokenType["BlockComment"] {
+					start_line, start_col := line, column
+					end := strings.Index(remaining[2:], "*$")
+					if end < 0 {
+						throwMessage(SyntaxError("unclosed block comment:" + SourceLog(start_line, start_col, 2, path, "")))
+					}
+					comment := remaining[:end+4]
+					for _, char := range comment {
+						column++
+						if char == '\n' {
+							column = 1
+							line++
+						}
+					}
+					position += len(comment)
+					trivia = append(trivia, comment)
+					continue top
+				} else if match.typ == TokenType["Comment"] {
+					position += match.length
+					column += match.length
+					trivia = append(trivia, strings.TrimRight(match.src, " \t"))
 					continue top
 				} else if match.typ == TokenType["Identifier"] {
 					keywords := []string{
@@ -240,6 +299,7 @@ top:
 						"super",
 						"new",
 						"await",
+						"yield",
 						"go",
 						"match",
 					}
@@ -249,7 +309,19 @@ top:
 							break
 						}
 					}
-				} else if is_value(match.typ, TokenType["White Space"], TokenType["Comment"]) {
+				} else if is_value(match.src, "/", "/=") && startsOperand(tokens) && !(verdex && tokens.length > 0 && tokens.at(tokens.length-1).src == "<") {
+					end := scanRegExp(source, position+1)
+					if end < 0 {
+						throwMessage(SyntaxError("unclosed regular expression literal:" + SourceLog(line, column, 1, path, "")))
+					}
+					// the flags
+					for end < len(source) && isWordChar(source[end]) {
+						end++
+					}
+					match.src = source[position:end]
+					match.length = end - position
+					match.typ = TokenType["RegExp"]
+				} else if match.typ == TokenType["White Space"] {
 					position += match.length
 					column += match.length
 					if match.src == "\n" {
@@ -269,15 +341,113 @@ top:
 			)
 		}
 		tokens.push(Token{
-			src:  match.src,
-			typ:  match.typ,
-			line: line,
-			col:  column,
-			end:  column + match.length,
+			src:      match.src,
+			typ:      match.typ,
+			line:     line,
+			col:      column,
+			end:      column + match.length,
+			offset:   offset,
+			length:   position + match.length - offset,
+			comments: trivia,
 		})
+		trivia = []string{}
 		position += match.length
 		column += match.length
+		if match.typ == TokenType["TString"] {
+			// a template literal may span lines
+			if lines := strings.Count(match.src, "\n"); lines > 0 {
+				line += lines
+				column = len(match.src) - strings.LastIndex(match.src, "\n") + 1
+			}
+		}
 	}
-	tokens.push(Token{src: "EOF", typ: TokenType["EOF"], line: line, col: column, end: column})
+	tokens.push(Token{src: "EOF", typ: TokenType["EOF"], line: line, col: column, end: column, offset: len(source), comments: trivia})
 	return tokens
 }
+
+// returns the index of the back-tick that closes the template literal
+// starting at start, or -1, skipping over the #{} interpolations
+func scanTemplate(source string, start int) int {
+	for i := start; i < len(source); i++ {
+		switch {
+		case source[i] == '\\':
+			i++
+		case source[i] == '`':
+			return i
+		case strings.HasPrefix(source[i:], "#{"):
+			i = scanInterpolation(source, i+2)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// returns the index of the brace that closes the interpolation starting at
+// start, or -1, skipping over the strings and template literals inside it
+func scanInterpolation(source string, start int) int {
+	depth := 0
+	for i := start; i < len(source); i++ {
+		switch char := source[i]; char {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case '"', '\'':
+			for i++; i < len(source) && source[i] != char; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			i = scanTemplate(source, i+1)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// reports whether the next token starts an operand, so that a / there starts
+// a regular expression literal rather than a division
+func startsOperand(tokens *TokenArray) bool {
+	if tokens.length == 0 {
+		return true
+	}
+	last := tokens.at(tokens.length - 1)
+	return !is_value(last.typ, TokenType["Number"], TokenType["BigInt"], TokenType["String"], TokenType["TString"], TokenType["RegExp"],
+		TokenType["Identifier"], TokenType["CloseParen"], TokenType["CloseBracket"], TokenType["CloseBrace"],
+		TokenType["IncreOp"], TokenType["DecreOp"], "super", "globalThis")
+}
+
+// returns the index after the slash that closes the regular expression literal
+// starting at start, or -1; a slash inside a character class does not close it
+func scanRegExp(source string, start int) int {
+	class := false
+	for i := start; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '\n', '\r':
+			return -1
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+func isWordChar(char byte) bool {
+	return char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9'
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"reflect"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var macros *Map[string, *Macro] = NewMap[string, *Macro]()
@@ -61,10 +65,9 @@ func init() {
 		"tab":       "\t",
 	}
 	macros.set("#_unicode", MK_MACRO("#_unicode", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
-		props := NewMap[RuntimeVal, string]()
+		props := NewMap[RuntimeVal, Ref]()
 		for k, v := range code_points {
-			ml := GenerateRadix(16)
-			Memory.set(ml, MK_STRING(v))
+			ml := Memory.alloc(MK_STRING(v))
 			props.set(MK_STRING(k), ml)
 		}
 		return MK_OBJECT(props, nil, nil)
@@ -76,6 +79,17 @@ func init() {
 		}
 		return MK_STRING(value.noAnsi())
 	}))
+	macros.set("#_error_stack", MK_MACRO("#_error_stack", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		if len(args) < 2 {
+			env.ThrowTypeError("#_error_stack: expected 2 arguments (name, message)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		// leaves out the constructors of the error classes
+		depth := r.CallStack.length
+		for depth > 0 && r.CallStack.stack[depth-1].name == "constructor" {
+			depth--
+		}
+		return MK_STRING(args[0].noAnsi() + ": " + args[1].noAnsi() + r.StackTrace(depth))
+	}))
 	macros.set("#_str_length", MK_MACRO("#_str_length", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 		var value RuntimeVal = undefined
 		length := 0
@@ -85,7 +99,7 @@ func init() {
 		if ValueType(value) != "string" {
 			length = -1
 		} else {
-			length = len(value.(*StringVal).value)
+			length = utf8.RuneCountInString(value.(*StringVal).value)
 		}
 		return MK_NUMBER(float64(length))
 	}))
@@ -110,7 +124,8 @@ func init() {
 			env.throwError([]string{"#_slice_str expects its 3rd argument to be of type string", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
 		}
 		value = v3
-		str := value.(*StringVal).value
+		// code points, not bytes
+		str := []rune(value.(*StringVal).value)
 		length = len(str)
 		if to > length {
 			to = length - 1
@@ -124,7 +139,22 @@ func init() {
 		if from > length {
 			from = length - 1
 		}
-		return MK_STRING(str[from : to+1])
+		return MK_STRING(string(str[from : to+1]))
+	}))
+	macros.set("#_from_code_point", MK_MACRO("#_from_code_point", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		codes, ok := argAt(args, 0).(*ArrayVal)
+		if !ok {
+			env.ThrowTypeError("#_from_code_point expects its 1st argument to be an array of numbers", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		str := strings.Builder{}
+		for _, code := range codes.values() {
+			n, ok := code.(*NumberVal)
+			if !ok || n.value < 0 || n.value > unicode.MaxRune || n.value != math.Trunc(n.value) {
+				env.ThrowRangeError("invalid code point", code.noAnsi(), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			str.WriteRune(rune(n.value))
+		}
+		return MK_STRING(str.String())
 	}))
 	macros.set("#_new_byte_array", MK_MACRO("#_new_byte_array", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 		if len(args) == 0 {
@@ -279,6 +309,9 @@ func init() {
 		}
 		return MK_ARRAY(args...)
 	}))
+	macros.set("#_runtime_command", MK_MACRO("#_runtime_command", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_STRING(command)
+	}))
 	macros.set("#_array_length", MK_MACRO("#_array_length", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
 		if len(args) < 1 {
 			env.throwError([]string{"#_array_length expects 1 argument of type (array)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
@@ -308,7 +341,11 @@ func init() {
 		if !ok {
 			env.throwError([]string{"#_http_serve_file expects it's 3rd argument to be of type (string)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
 		}
-		http.ServeFile(w.value, r.value, name.value)
+		if IsStdPath(name.value) {
+			http.ServeFileFS(w.value, r.value, stdlibFS, stdFile(name.value))
+		} else {
+			http.ServeFile(w.value, r.value, name.value)
+		}
 		return undefined
 	}))
 	macros.set("#_http_serve_dir", MK_MACRO("#_http_serve_dir", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
@@ -340,7 +377,7 @@ func init() {
 		mux.value.Handle(pattern.value, handler.value)
 		return undefined
 	}))
-	macros.set("#_http_listen_and_serve", MK_MACRO("#_http_listen_and_serve", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+	macros.set("#_http_listen_and_serve", MK_MACRO("#_http_listen_and_serve", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 		if len(args) < 2 {
 			env.throwError([]string{"#_http_listen_and_serve expects 2 arguments of type (string, raw [http handler] | null)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
 		}
@@ -363,8 +400,21 @@ func init() {
 			Addr:    pattern.value,
 			Handler: handler,
 		}
-		server.ListenAndServe()
-		// http.ListenAndServe(pattern.value, handler)
+		// the server runs in the background and keeps the event loop alive, so timers and
+		// callbacks still run; the handlers take the interpreter lock for each request
+		eventLoop.hold()
+		go func() {
+			err := server.ListenAndServe()
+			eventLoop.post(Task{
+				macro: MK_MACRO("#_server_closed", func(_ []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+					env.throwError([]string{"http: " + err.Error(), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+					return undefined
+				}),
+				env: env,
+				pos: pos,
+				r:   r,
+			})
+		}()
 		return undefined
 	}))
 	macros.set("#_serve_mux_handle_func", MK_MACRO("#_serve_mux_handle_func", func(args []RuntimeVal, env *Environment, pos Pos, runtime *Interpreter) RuntimeVal {
@@ -384,7 +434,24 @@ func init() {
 			env.throwError([]string{"#_serve_mux_handle_func expects it's 3rd argument to be of type (function)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
 		}
 		mux.value.HandleFunc(pattern.value, func(w http.ResponseWriter, r *http.Request) {
-			CallFunction(handler, env, []RuntimeVal{MK_RAW(w), MK_RAW(r)}, runtime, pos)
+			lockInterpreter()
+			defer interpreterLock.Unlock()
+			// each request runs like a task, with flags and a call stack of its own
+			task := runtime.forkTask()
+			// an uncaught error only fails the request, not the server
+			value, err := task.SafeCall(handler, env, []RuntimeVal{MK_RAW(w), MK_RAW(r)}, pos)
+			// the micro tasks it queued run before the request is answered, an async
+			// handler answers it once its promise settles
+			eventLoop.RunMicroTasks()
+			if err == nil {
+				err = task.settled(value, env, pos)
+			}
+			if err != nil {
+				PrintUncaught(err)
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			// the values of a request are garbage once it is served
+			Memory.collect()
 		})
 		return undefined
 	}))
@@ -525,10 +592,9 @@ func init() {
 				return MK_NUMBER(float64(now.Weekday()))
 			}),
 		}
-		props := NewMap[RuntimeVal, string]()
+		props := NewMap[RuntimeVal, Ref]()
 		for _, m := range date {
-			ml := GenerateRadix(16)
-			Memory.set(ml, m)
+			ml := Memory.alloc(m)
 			props.set(MK_STRING(m.name), ml)
 		}
 		return MK_OBJECT(props, nil, nil)
@@ -542,7 +608,7 @@ func init() {
 		println(time.Since(bench))
 		return MK_UD()
 	}))
-	parse_method_mem_loc := GenerateRadix(16)
+	parse_method_mem_loc := Memory.alloc(undefined)
 	macros.set("#_new_parser", MK_MACRO("#_new_parser", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 		if len(args) < 2 {
 			env.throwError([]string{"#_new_parser expects 2 arguments of type (string, string)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
@@ -555,8 +621,8 @@ func init() {
 		if !ok {
 			env.throwError([]string{"#_new_parser expects it's 2nd argument to be of type (string)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
 		}
-		parser := NewParser(path.value, sourceType.value, "")
-		props := NewMap[RuntimeVal, string]()
+		parser := NewParser(path.value, sourceType.value, "", Tokenize)
+		props := NewMap[RuntimeVal, Ref]()
 		Memory.set(parse_method_mem_loc, MK_MACRO("parse", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 			if len(args) < 1 {
 				env.throwError([]string{"Parser.parse (#_new_parser().parse) expects 1 argument of type (boolean)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
@@ -616,10 +682,9 @@ func init() {
 		return MK_STRING(asx_parser.CompileASX(mod.value))
 	}))
 	macros.set("#_get_asx_routes", MK_MACRO("#_get_asx_routes", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
-		props := NewMap[RuntimeVal, string]()
+		props := NewMap[RuntimeVal, Ref]()
 		routeTable.forEach(func(key, comp string) {
-			ml := GenerateRadix(16)
-			Memory.set(ml, MK_STRING(comp))
+			ml := Memory.alloc(MK_STRING(comp))
 			props.set(MK_STRING(key), ml)
 		})
 		return MK_OBJECT(props, nil, r)
@@ -698,10 +763,485 @@ func init() {
 		RunScript(path.value)
 		return undefined
 	}))
+	macros.set("#_run_tests", MK_MACRO("#_run_tests", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		paths, ok := argAt(args, 0).(*ArrayVal)
+		if !ok {
+			env.throwError([]string{"#_run_tests expects it's 1st argument to be of type (array)", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		}
+		files := []string{}
+		paths.forEach(func(_ int, path RuntimeVal) {
+			if path, ok := path.(*StringVal); ok {
+				files = append(files, path.value)
+			}
+		})
+		RunTests(files)
+		return undefined
+	}))
 	macros.set("#_start_repl", MK_MACRO("#_start_repl", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
 		REPL()
 		return undefined
 	}))
+	macros.set("#_set_timer", MK_MACRO("#_set_timer", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		callback := argAt(args, 0)
+		if !is_value(ValueType(callback), "function", "macro") {
+			env.ThrowTypeError("the callback must be of type 'function', but it was given one of type", ValueType(callback), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		delay := 0.0
+		if n, ok := argAt(args, 1).(*NumberVal); ok && !math.IsNaN(n.value) {
+			delay = n.value
+		}
+		callback_args := []RuntimeVal{}
+		if array, ok := argAt(args, 2).(*ArrayVal); ok {
+			array.forEach(func(_ int, value RuntimeVal) {
+				callback_args = append(callback_args, value)
+			})
+		}
+		repeat := RtvToBool(argAt(args, 3))
+		task := Task{
+			macro: MK_MACRO("#_timer", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+				value, _ := CallFunction(callback, env, args, r, pos)
+				return value
+			}),
+			args: callback_args,
+			env:  env,
+			pos:  pos,
+			r:    r,
+		}
+		id := eventLoop.setTimer(task, time.Duration(delay*float64(time.Millisecond)), repeat)
+		return MK_NUMBER(float64(id))
+	}))
+	macros.set("#_clear_timer", MK_MACRO("#_clear_timer", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		if id, ok := argAt(args, 0).(*NumberVal); ok {
+			eventLoop.clearTimer(int(id.value))
+		}
+		return undefined
+	}))
+	macros.set("#_queue_microtask", MK_MACRO("#_queue_microtask", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		callback := argAt(args, 0)
+		if !is_value(ValueType(callback), "function", "macro") {
+			env.ThrowTypeError("queueMicrotask expects an argument of type 'function', but it was given one of type", ValueType(callback), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		r.microTaskQueue.queueMicroTask(Task{
+			macro: MK_MACRO("#_microtask", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+				value, _ := CallFunction(callback, env, args, r, pos)
+				return value
+			}),
+			args: []RuntimeVal{},
+			env:  env,
+			pos:  pos,
+			r:    r,
+		})
+		return undefined
+	}))
+	macros.set("#_promise_state", MK_MACRO("#_promise_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&PromiseState{state: PENDING, value: undefined})
+	}))
+	macros.set("#_promise_init", MK_MACRO("#_promise_init", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := promiseArg(args, "#_promise_init", env, pos)
+		executor := argAt(args, 1)
+		if !is_value(ValueType(executor), "function", "macro") {
+			env.ThrowTypeError("Promise expects an argument of type 'function', but it was given one of type", ValueType(executor), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		resolve, reject := state.resolvers()
+		if _, err := r.SafeCall(executor, env, []RuntimeVal{resolve, reject}, pos); err != nil {
+			reject.call([]RuntimeVal{err.(*ThrownError).value}, env, pos, r)
+		}
+		return undefined
+	}))
+	macros.set("#_promise_then", MK_MACRO("#_promise_then", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := promiseArg(args, "#_promise_then", env, pos)
+		return state.then(argAt(args, 1), argAt(args, 2), r, env, pos)
+	}))
+	macros.set("#_promise_finally", MK_MACRO("#_promise_finally", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := promiseArg(args, "#_promise_finally", env, pos)
+		return state.finally(argAt(args, 1), r, env, pos)
+	}))
+	macros.set("#_promise_resolve", MK_MACRO("#_promise_resolve", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		value := argAt(args, 0)
+		if promiseOf(value) != nil {
+			return value
+		}
+		promise, state := NewPromise(env, r, pos)
+		state.resolve(value, r, env, pos)
+		return promise
+	}))
+	macros.set("#_promise_reject", MK_MACRO("#_promise_reject", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		promise, state := NewPromise(env, r, pos)
+		state.settle(REJECTED, argAt(args, 0), r, env, pos)
+		return promise
+	}))
+	macros.set("#_promise_all", MK_MACRO("#_promise_all", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return PromiseAll(argAt(args, 0), r, env, pos)
+	}))
+	macros.set("#_promise_race", MK_MACRO("#_promise_race", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return PromiseRace(argAt(args, 0), r, env, pos)
+	}))
+	macros.set("#_promise_any", MK_MACRO("#_promise_any", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return PromiseAny(argAt(args, 0), r, env, pos)
+	}))
+	macros.set("#_promise_all_settled", MK_MACRO("#_promise_all_settled", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return PromiseAllSettled(argAt(args, 0), r, env, pos)
+	}))
+	macros.set("#_promise_inspect", MK_MACRO("#_promise_inspect", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := promiseArg(args, "#_promise_inspect", env, pos)
+		switch state.state {
+		case FULFILLED:
+			return MK_STRING("Promise { " + state.value.String(1, "") + " }")
+		case REJECTED:
+			return MK_STRING("Promise { \x1b[31m<rejected>\x1b[0m " + state.value.String(1, "") + " }")
+		}
+		return MK_STRING("Promise { \x1b[36m<pending>\x1b[0m }")
+	}))
+	macros.set("#_generator_state", MK_MACRO("#_generator_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&GeneratorState{})
+	}))
+	macros.set("#_generator_resume", MK_MACRO("#_generator_resume", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*GeneratorState](argAt(args, 0), "#_generator_resume", "Generator", env, pos)
+		return state.resume(argAt(args, 1).noAnsi(), argAt(args, 2), r, env, pos)
+	}))
+	macros.set("#_generator_inspect", MK_MACRO("#_generator_inspect", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*GeneratorState](argAt(args, 0), "#_generator_inspect", "Generator", env, pos)
+		class := "Generator"
+		if state.async {
+			class = "AsyncGenerator"
+		}
+		switch {
+		case state.done:
+			return MK_STRING(class + " { \x1b[90m<closed>\x1b[0m }")
+		case state.running:
+			return MK_STRING(class + " { \x1b[36m<running>\x1b[0m }")
+		}
+		return MK_STRING(class + " { \x1b[36m<suspended>\x1b[0m }")
+	}))
+	macros.set("#_channel_state", MK_MACRO("#_channel_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&ChannelState{})
+	}))
+	macros.set("#_channel_init", MK_MACRO("#_channel_init", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_init", "Channel", env, pos)
+		capacity := 0.0
+		switch v := argAt(args, 1).(type) {
+		case *Undefined:
+		case *NumberVal:
+			capacity = v.value
+		default:
+			env.ThrowTypeError("Channel expects a capacity of type 'number', but it was given one of type", ValueType(v), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		if capacity < 0 || capacity != math.Trunc(capacity) {
+			env.ThrowRangeError("the capacity of a Channel must be a positive integer or 0, got", sprint(capacity), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		state.ch = make(chan RuntimeVal, int(capacity))
+		return undefined
+	}))
+	macros.set("#_channel_send", MK_MACRO("#_channel_send", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_send", "Channel", env, pos)
+		r.send(state, argAt(args, 1), env, pos)
+		return undefined
+	}))
+	macros.set("#_channel_receive", MK_MACRO("#_channel_receive", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_receive", "Channel", env, pos)
+		value, ok := r.receive(state, env, pos)
+		return MK_RECORD(r, []string{"value", "done"}, value, MK_BOOL(!ok))
+	}))
+	macros.set("#_channel_close", MK_MACRO("#_channel_close", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_close", "Channel", env, pos)
+		if state.closed {
+			env.throwError([]string{"close of a closed channel", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		}
+		state.closed = true
+		close(state.ch)
+		return undefined
+	}))
+	macros.set("#_channel_size", MK_MACRO("#_channel_size", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_size", "Channel", env, pos)
+		return MK_NUMBER(float64(len(state.ch)))
+	}))
+	macros.set("#_channel_capacity", MK_MACRO("#_channel_capacity", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*ChannelState](argAt(args, 0), "#_channel_capacity", "Channel", env, pos)
+		return MK_NUMBER(float64(cap(state.ch)))
+	}))
+	macros.set("#_channel_select", MK_MACRO("#_channel_select", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		list, ok := argAt(args, 0).(*ArrayVal)
+		if !ok {
+			env.ThrowTypeError("#_channel_select expects its 1st argument to be an array of cases", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		cases := []reflect.SelectCase{}
+		senders := []*ChannelState{}
+		list.forEach(func(i int, value RuntimeVal) {
+			// a Channel is received from, [channel, value] is sent to
+			if send, ok := value.(*ArrayVal); ok {
+				state := stateOf[*ChannelState](send.get(0), "select", "Channel or a [Channel, value] pair", env, pos)
+				if state.closed {
+					env.throwError([]string{"send on a closed channel", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+				}
+				value := send.get(1)
+				if value == nil {
+					value = undefined
+				}
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(state.ch), Send: reflect.ValueOf(&value).Elem()})
+				senders = append(senders, state)
+				return
+			}
+			state := stateOf[*ChannelState](value, "select", "Channel or a [Channel, value] pair", env, pos)
+			cases = append(cases, receiveCase(state.ch))
+		})
+		defer func() {
+			if rec := recover(); rec != nil {
+				closedSend(rec, senders, env, pos)
+			}
+		}()
+		var chosen int
+		var value reflect.Value
+		if RtvToBool(argAt(args, 1)) {
+			chosen, value, ok = r.wait(cases, env, pos)
+		} else {
+			// like a select with a default case, which is index -1
+			chosen, value, ok = reflect.Select(append(cases, reflect.SelectCase{Dir: reflect.SelectDefault}))
+		}
+		var received RuntimeVal = undefined
+		if chosen == len(cases) {
+			chosen, ok = -1, false
+		} else if cases[chosen].Dir == reflect.SelectSend {
+			ok = true
+		} else if ok {
+			received = value.Interface().(RuntimeVal)
+		}
+		return MK_RECORD(r, []string{"index", "value", "ok"}, MK_NUMBER(float64(chosen)), received, MK_BOOL(ok))
+	}))
+	macros.set("#_wait_group_state", MK_MACRO("#_wait_group_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&WaitGroupState{zero: make(chan struct{})})
+	}))
+	macros.set("#_wait_group_add", MK_MACRO("#_wait_group_add", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*WaitGroupState](argAt(args, 0), "#_wait_group_add", "WaitGroup", env, pos)
+		delta, ok := argAt(args, 1).(*NumberVal)
+		if !ok || delta.value != math.Trunc(delta.value) {
+			env.ThrowTypeError("WaitGroup.add expects an integer, but it was given a value of type", ValueType(argAt(args, 1)), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		state.count += int(delta.value)
+		if state.count < 0 {
+			state.count = 0
+			env.ThrowRangeError("the counter of a WaitGroup dropped below 0", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		if state.count == 0 {
+			close(state.zero)
+			state.zero = make(chan struct{})
+		}
+		return undefined
+	}))
+	macros.set("#_wait_group_wait", MK_MACRO("#_wait_group_wait", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*WaitGroupState](argAt(args, 0), "#_wait_group_wait", "WaitGroup", env, pos)
+		if state.count > 0 {
+			r.wait([]reflect.SelectCase{receiveCase(state.zero)}, env, pos)
+		}
+		return undefined
+	}))
+	macros.set("#_mutex_state", MK_MACRO("#_mutex_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&MutexState{ch: make(chan struct{}, 1)})
+	}))
+	macros.set("#_mutex_lock", MK_MACRO("#_mutex_lock", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*MutexState](argAt(args, 0), "#_mutex_lock", "Mutex", env, pos)
+		r.wait([]reflect.SelectCase{{Dir: reflect.SelectSend, Chan: reflect.ValueOf(state.ch), Send: reflect.ValueOf(struct{}{})}}, env, pos)
+		return undefined
+	}))
+	macros.set("#_mutex_try_lock", MK_MACRO("#_mutex_try_lock", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*MutexState](argAt(args, 0), "#_mutex_try_lock", "Mutex", env, pos)
+		select {
+		case state.ch <- struct{}{}:
+			return MK_BOOL(true)
+		default:
+			return MK_BOOL(false)
+		}
+	}))
+	macros.set("#_mutex_unlock", MK_MACRO("#_mutex_unlock", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*MutexState](argAt(args, 0), "#_mutex_unlock", "Mutex", env, pos)
+		select {
+		case <-state.ch:
+		default:
+			env.throwError([]string{"unlock of an unlocked Mutex", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")})
+		}
+		return undefined
+	}))
+	macros.set("#_collection_state", MK_MACRO("#_collection_state", func(args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(NewCollectionState(argAt(args, 0).noAnsi()))
+	}))
+	macros.set("#_collection_init", MK_MACRO("#_collection_init", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_init", "collection", env, pos)
+		add := func(value RuntimeVal) {
+			switch {
+			case state.weak() && state.set():
+				// the value must not hold the key
+				state.put(value, MK_BOOL(true), env, pos)
+				return
+			case state.set():
+				state.put(value, value, env, pos)
+				return
+			}
+			pair, ok := value.(*ArrayVal)
+			if !ok {
+				env.ThrowTypeError("the entries of a "+state.kind+" must be [key, value] arrays, got type", ValueType(value), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			state.put(pair.get(0), pair.get(1), env, pos)
+		}
+		from := argAt(args, 1)
+		switch from.(type) {
+		case *Undefined, *NullVal:
+			return undefined
+		}
+		// another Map or Set is read without going through its iterator, which gives the same
+		if instance, ok := from.(*Instance); ok {
+			if other, ok := GetInstanceMember(instance, "#state").(*RawVal[*CollectionState]); ok && !other.value.weak() {
+				other.value.forEach(func(key, value RuntimeVal) {
+					if other.value.set() {
+						add(value)
+					} else {
+						add(MK_ARRAY(key, value))
+					}
+				})
+				return undefined
+			}
+		}
+		name := "a " + state.kind + " is made from an iterable of [key, value] entries"
+		if state.set() {
+			name = "a " + state.kind + " is made from an iterable of values"
+		}
+		for _, value := range r.iterableValues(from, -1, name, env, pos) {
+			add(value)
+		}
+		return undefined
+	}))
+	macros.set("#_collection_get", MK_MACRO("#_collection_get", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_get", "collection", env, pos)
+		return state.get(argAt(args, 1), env, pos)
+	}))
+	macros.set("#_collection_set", MK_MACRO("#_collection_set", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_set", "collection", env, pos)
+		state.put(argAt(args, 1), argAt(args, 2), env, pos)
+		return undefined
+	}))
+	macros.set("#_collection_has", MK_MACRO("#_collection_has", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_has", "collection", env, pos)
+		return MK_BOOL(state.has(argAt(args, 1), env, pos))
+	}))
+	macros.set("#_collection_delete", MK_MACRO("#_collection_delete", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_delete", "collection", env, pos)
+		return MK_BOOL(state.delete(argAt(args, 1), env, pos))
+	}))
+	macros.set("#_collection_clear", MK_MACRO("#_collection_clear", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_clear", "collection", env, pos)
+		state.clear()
+		return undefined
+	}))
+	macros.set("#_collection_size", MK_MACRO("#_collection_size", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_size", "collection", env, pos)
+		return MK_NUMBER(float64(state.size()))
+	}))
+	macros.set("#_collection_for_each", MK_MACRO("#_collection_for_each", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_for_each", "collection", env, pos)
+		callback := argAt(args, 1)
+		state.forEach(func(key, value RuntimeVal) {
+			CallFunction(callback, env, []RuntimeVal{value, key, args[0]}, r, pos)
+		})
+		return undefined
+	}))
+	macros.set("#_collection_debug", MK_MACRO("#_collection_debug", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*CollectionState](argAt(args, 0), "#_collection_debug", "collection", env, pos)
+		return MK_STRING(state.String())
+	}))
+	macros.set("#_iterator_state", MK_MACRO("#_iterator_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&IteratorState{})
+	}))
+	macros.set("#_collection_iterator", MK_MACRO("#_collection_iterator", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		it := stateOf[*IteratorState](argAt(args, 0), "#_collection_iterator", "CollectionIterator", env, pos)
+		it.collection = stateOf[*CollectionState](argAt(args, 1), "#_collection_iterator", "collection", env, pos)
+		it.kind = argAt(args, 2).noAnsi()
+		return undefined
+	}))
+	macros.set("#_iterator_next", MK_MACRO("#_iterator_next", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		it := stateOf[*IteratorState](argAt(args, 0), "#_iterator_next", "CollectionIterator", env, pos)
+		value, ok := it.next()
+		return MK_RECORD(r, []string{"value", "done"}, value, MK_BOOL(!ok))
+	}))
+	macros.set("#_regexp_state", MK_MACRO("#_regexp_state", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_RAW(&RegExpState{})
+	}))
+	macros.set("#_regexp_init", MK_MACRO("#_regexp_init", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*RegExpState](argAt(args, 0), "#_regexp_init", "RegExp", env, pos)
+		var pattern, flags string
+		switch from := argAt(args, 1).(type) {
+		case *StringVal:
+			pattern = from.value
+		case *Undefined:
+		default:
+			// another RegExp, its flags are kept unless others are given
+			other, _ := regExpOf(from)
+			if other == nil {
+				env.ThrowTypeError("RegExp expects a string or a RegExp, got type", ValueType(from), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			pattern, flags = other.source, other.flags
+		}
+		if _, ok := argAt(args, 2).(*Undefined); !ok {
+			flags = stringArg(args, 2, "RegExp", env, pos)
+		}
+		compiled, err := compileRegExp(pattern, flags)
+		if err != "" {
+			env.ThrowSyntaxError("invalid regular expression /"+pattern+"/"+flags+":", err, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		*state = *compiled
+		return undefined
+	}))
+	macros.set("#_regexp_exec", MK_MACRO("#_regexp_exec", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*RegExpState](argAt(args, 0), "#_regexp_exec", "RegExp", env, pos)
+		str := stringArg(args, 1, "RegExp.exec", env, pos)
+		loc := state.exec(args[0].(*Instance), str)
+		if loc == nil {
+			return null
+		}
+		return state.result(str, loc, r)
+	}))
+	macros.set("#_regexp_source", MK_MACRO("#_regexp_source", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*RegExpState](argAt(args, 0), "#_regexp_source", "RegExp", env, pos)
+		return MK_STRING(state.source)
+	}))
+	macros.set("#_regexp_flags", MK_MACRO("#_regexp_flags", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*RegExpState](argAt(args, 0), "#_regexp_flags", "RegExp", env, pos)
+		return MK_STRING(state.flags)
+	}))
+	macros.set("#_math", MK_MACRO("#_math", func(_ []RuntimeVal, _ *Environment, _ Pos, r *Interpreter) RuntimeVal {
+		return MK_MATH(r)
+	}))
+	macros.set("#_number", MK_MACRO("#_number", func(args []RuntimeVal, _ *Environment, _ Pos, r *Interpreter) RuntimeVal {
+		namespace := MK_NUMBER_NAMESPACE(r)
+		// the members are added to the Number function
+		if fn, ok := argAt(args, 0).(*FunctionVal); ok {
+			fn.properties.copy(namespace.properties)
+			return fn
+		}
+		return namespace
+	}))
+	macros.set("#_to_number", MK_MACRO("#_to_number", func(args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		return MK_NUMBER(toNumber(args))
+	}))
+	macros.set("#_to_bigint", MK_MACRO("#_to_bigint", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		return toBigInt(argAt(args, 0), env, pos)
+	}))
+	macros.set("#_json_parse", MK_MACRO("#_json_parse", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		value := r.parseJSON(stringArg(args, 0, "JSON.parse", env, pos), env, pos)
+		switch reviver := argAt(args, 1).(type) {
+		case *FunctionVal, *Macro:
+			return r.revive(reviver, "", value, env, pos)
+		}
+		return value
+	}))
+	macros.set("#_json_stringify", MK_MACRO("#_json_stringify", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		text, ok := r.stringifyJSON(argAt(args, 0), argAt(args, 1), argAt(args, 2), env, pos)
+		if !ok {
+			return undefined
+		}
+		return MK_STRING(text)
+	}))
+	macros.set("#_regexp_debug", MK_MACRO("#_regexp_debug", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		state := stateOf[*RegExpState](argAt(args, 0), "#_regexp_debug", "RegExp", env, pos)
+		return MK_STRING(state.String())
+	}))
 }
 
 func createHttpHeaderObject(header http.Header, r *Interpreter) RuntimeVal {
@@ -797,11 +1337,19 @@ func createHttpHeaderObject(header http.Header, r *Interpreter) RuntimeVal {
 		return createHttpHeaderObject(header.Clone(), r)
 	}))
 
-	object := NewMap[RuntimeVal, string]()
+	object := NewMap[RuntimeVal, Ref]()
 	props.forEach(func(key string, value RuntimeVal) {
-		ml := GenerateRadix(16)
-		Memory.set(ml, value)
+		ml := Memory.alloc(value)
 		object.set(MK_STRING(key), ml)
 	})
 	return MK_OBJECT(object, nil, r)
 }
+
+// returns the state of the promise passed as the first argument of a macro
+func promiseArg(args []RuntimeVal, name string, env *Environment, pos Pos) *PromiseState {
+	state := promiseOf(argAt(args, 0))
+	if state == nil {
+		env.ThrowTypeError(name, "expects its 1st argument to be a Promise", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return state
+}
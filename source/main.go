@@ -1,8 +1,6 @@
 package main
 
-import (
-	"os"
-)
+import "os"
 
 var AS = []string{
 	`          _____                    _____          `,
@@ -29,37 +27,17 @@ var AS = []string{
 	`                                                  `,
 }
 
-var tempDirCreated = false
-
-func initialize() {
-	if !pathExists("temp") {
-		println("creating temp directory...")
-		err := os.Mkdir("temp", os.ModeDir)
-		if err != nil {
-			println("\x1b[35mError\x1b[0m: error creating temp directory")
-		} else {
-			println("directory created: temp")
-		}
-	} else {
-		tempDirCreated = true
-	}
-}
-
 func REPL() {
-	var file *os.File
-	var err error
-	if !tempDirCreated {
-		file, err = os.CreateTemp("", "repl-")
-		if err != nil {
-			throwError(err)
-		}
-	} else {
-		file = CreateTempFile("repl-")
+	// the input is kept in a file so errors can show their source
+	file, err := os.CreateTemp("", "repl-*.as")
+	if err != nil {
+		throwError(err)
 	}
+	defer os.Remove(file.Name())
 	defer file.Close()
 	display := [...]string{
-		"ArachnoScript REPL - \x1b[32mv0.1\x1b[0m",
-		"ARE v0.1",
+		"ArachnoScript REPL - \x1b[32mv" + version + "\x1b[0m",
+		"ARE v" + version,
 		"enter .peace to exit the repl.",
 	}
 	for _, line := range AS {
@@ -77,9 +55,11 @@ func REPL() {
 		if input == ".peace" {
 			break
 		}
-		var Parser *Parser = NewParser(file.Name(), "program", input)
+		var Parser *Parser = NewParser(file.Name(), "program", input, Tokenize)
 		program := Parser.Parse(true)
-		runtime.Evaluate(program, stdEnv)
+		if _, err := runtime.Exec(program, stdEnv); err != nil {
+			PrintUncaught(err)
+		}
 	}
 }
 
@@ -90,29 +70,31 @@ func RunScript(path string) {
 	if !pathExists(path) {
 		throwMessage("path: \x1b[31m" + path + "\x1b[0m; does not exist")
 	}
-	parser := NewParser(path, "program", "")
+	parser := NewParser(path, "program", "", Tokenize)
 	program := parser.Parse(true)
 	runtime := NewRuntime()
 	env := NewEnv(stdEnv, "program", path)
-	runtime.Evaluate(program, env)
+	_, err := runtime.Exec(program, env)
+	ExitOnUncaught(err)
 }
 
 var arguments = os.Args[1:]
 
-var exec_path = RealPath(os.Args[0])
-
 func main() {
-	initialize()
-	RunSTD("../stdlib/main.as")
+	// the main goroutine runs AS code, see interpreterLock
+	lockInterpreter()
+	RunCLI(arguments)
 }
 
 var stdEnv *Environment
 
-func RunSTD(path string) {
-	path = RelativePathToFile(exec_path, path)
-	parser := NewParser(path, "program", "")
+// loads the standard library, its main.as runs the command
+func RunSTD() {
+	path := existingPath(StdPath("main.as"))
+	parser := NewParser(path, "program", "", Tokenize)
 	program := parser.Parse(true)
 	runtime := NewRuntime()
 	stdEnv = CreateScriptEnv(runtime, path)
-	runtime.Evaluate(program, stdEnv)
+	_, err := runtime.Exec(program, stdEnv)
+	ExitOnUncaught(err)
 }
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the tests run the runtime as a command: started with ARE_MAIN set,
+// the test binary runs main instead of the tests
+func TestMain(m *testing.M) {
+	if os.Getenv("ARE_MAIN") != "" {
+		main()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runs the runtime with args in dir, returns what it printed and its exit code
+func are(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "ARE_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	if exit, ok := err.(*exec.ExitError); ok {
+		return string(out), exit.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), EXIT_OK
+}
+
+// writes files, by name, to a new directory and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// checks the exit code of a run and that its output contains want
+func expect(t *testing.T, out string, code int, wantCode int, want string) {
+	t.Helper()
+	if code != wantCode || !strings.Contains(out, want) {
+		t.Errorf("expected exit code %d and an output with %q, got %d:\n%s", wantCode, want, code, out)
+	}
+}
+
+func TestVersionAndHelp(t *testing.T) {
+	out, code := are(t, ".", "--version")
+	expect(t, out, code, EXIT_OK, "are v"+version)
+	out, code = are(t, ".", "-h")
+	expect(t, out, code, EXIT_OK, "usage: are [flags] <command>")
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"--unknown"},
+		{"--mem", "lots"},
+		{"--mem"},
+		{"unknown"},
+		{"run"},
+		{"check"},
+		{"fmt", "-w"},
+		{"ast"},
+		{"tokens", "a.as", "b.as"},
+	} {
+		out, code := are(t, ".", args...)
+		if code != EXIT_USAGE || !strings.Contains(out, "are --help") {
+			t.Errorf("are %s: expected exit code %d and a usage error, got %d:\n%s", strings.Join(args, " "), EXIT_USAGE, code, out)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"args.as":       `Console.log(runtime.command, runtime.args[1], runtime.args[2])`,
+		"throws.as":     `throw new Error("boom")`,
+		"rejects.as":    `Promise.reject(new Error("lost"))`,
+		"catches.as":    `Promise.reject(new Error("lost")).catch((e) => { Console.log("caught " + e.message) })`,
+		"syntax.as":     `spawn = 1`,
+		"with space.as": `Console.log("ran")`,
+	})
+	out, code := are(t, dir, "run", "args.as", "a", "b")
+	expect(t, out, code, EXIT_OK, "run a b")
+	out, code = are(t, dir, "--", "args.as", "a", "b")
+	expect(t, out, code, EXIT_OK, "run a b")
+	out, code = are(t, dir, "throws.as")
+	expect(t, out, code, EXIT_ERROR, "boom")
+	out, code = are(t, dir, "rejects.as")
+	expect(t, out, code, EXIT_ERROR, "lost")
+	out, code = are(t, dir, "catches.as")
+	expect(t, out, code, EXIT_OK, "caught lost")
+	out, code = are(t, dir, "syntax.as")
+	expect(t, out, code, EXIT_ERROR, "SyntaxError")
+	out, code = are(t, dir, "run", "missing.as")
+	expect(t, out, code, EXIT_ERROR, "does not exist")
+	out, code = are(t, dir, "--vm", "run", "with space.as")
+	expect(t, out, code, EXIT_OK, "ran")
+}
+
+func TestCheckFmtAstTokens(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"ok.as":     "if (true) {\nConsole.log(\"{\")   \n}\n",
+		"broken.as": "if (true) {\n",
+	})
+	out, code := are(t, dir, "check", "ok.as")
+	expect(t, out, code, EXIT_OK, "")
+	out, code = are(t, dir, "check", "ok.as", "broken.as")
+	expect(t, out, code, EXIT_ERROR, "broken.as")
+	formatted := "if (true) {\n  Console.log(\"{\")\n}\n"
+	out, code = are(t, dir, "fmt", "ok.as")
+	expect(t, out, code, EXIT_OK, formatted)
+	out, code = are(t, dir, "fmt", "-w", "ok.as")
+	expect(t, out, code, EXIT_OK, "")
+	if written, _ := os.ReadFile(filepath.Join(dir, "ok.as")); string(written) != formatted {
+		t.Errorf("fmt -w wrote %q, expected %q", written, formatted)
+	}
+	out, code = are(t, dir, "ast", "ok.as")
+	expect(t, out, code, EXIT_OK, "If Statement")
+	out, code = are(t, dir, "tokens", "ok.as")
+	expect(t, out, code, EXIT_OK, "1:1\t")
+}
+
+func TestTestCommand(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"passing/a.test.as":   `if (1 + 1 != 2) { throw "math" }`,
+		"passing/helper.as":   `throw "not a test file"`,
+		"failing/b.test.as":   `throw new Error("failed check")`,
+		"failing/c.test.as":   `Promise.reject(new Error("unhandled"))`,
+		"failing/d.test.as":   `Console.log("fine")`,
+		"failing/nested/e.as": `throw "not a test file"`,
+	})
+	out, code := are(t, dir, "test", "passing")
+	expect(t, out, code, EXIT_OK, "1 passed, 0 failed")
+	out, code = are(t, filepath.Join(dir, "passing"), "test")
+	expect(t, out, code, EXIT_OK, "1 passed, 0 failed")
+	out, code = are(t, dir, "test", "failing")
+	expect(t, out, code, EXIT_ERROR, "1 passed, 2 failed")
+	if !strings.Contains(out, "failed check") || !strings.Contains(out, "unhandled") {
+		t.Errorf("the failures are not reported:\n%s", out)
+	}
+}
+
+func TestStdlib(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"std/main.as":     `import { greeting } from "std:greeting"` + "\n" + `#_print(greeting())`,
+		"std/greeting.as": `export function greeting() { return "custom stdlib" }`,
+		"imports.as":      `import { runtime } from "std:runtime"` + "\n" + `import "std:http.as"` + "\n" + `Console.log("imported " + runtime.command)`,
+	})
+	out, code := are(t, dir, "imports.as")
+	expect(t, out, code, EXIT_OK, "imported run")
+	out, code = are(t, dir, "--stdlib", "std", "imports.as")
+	expect(t, out, code, EXIT_OK, "custom stdlib")
+	out, code = are(t, dir, "--stdlib=missing", "imports.as")
+	expect(t, out, code, EXIT_ERROR, "does not exist")
+}
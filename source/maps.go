@@ -2,12 +2,15 @@ package main
 
 import (
 	"reflect"
+	"slices"
 	"sync"
 )
 
 type Map[K comparable, V any] struct {
-	mu     sync.RWMutex
-	_map   map[K]V
+	mu   sync.RWMutex
+	_map map[K]V
+	// the keys in the order they were added, which forEach follows
+	keys   []K
 	length int
 }
 
@@ -19,41 +22,66 @@ func (m *Map[K, V]) copy(from *Map[K, V]) *Map[K, V] {
 }
 
 func (m *Map[K, V]) delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m._map[key]; !ok {
+		return
+	}
 	delete(m._map, key)
+	i := slices.Index(m.keys, key)
+	// a new slice, a forEach running over the old one is not disturbed
+	m.keys = append(m.keys[:i:i], m.keys[i+1:]...)
+	m.length = len(m._map)
 }
 
 func (m *Map[K, V]) has(key K) bool {
-	for k := range m._map {
-		if reflect.DeepEqual(k, key) {
-			return true
-		}
-	}
-	return false
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.lookup(key)
+	return ok
 }
 
 func (m *Map[K, V]) get(key K) V {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	for k := range m._map {
-		if reflect.DeepEqual(k, key) {
-			return m._map[k]
+	value, _ := m.lookup(key)
+	return value
+}
+
+// runtime values are pointers and are compared deeply, keys that
+// are strings or the same pointer are found directly
+func (m *Map[K, V]) lookup(key K) (V, bool) {
+	if value, ok := m._map[key]; ok {
+		return value, true
+	}
+	if _, ok := any(key).(string); !ok {
+		for k, value := range m._map {
+			if reflect.DeepEqual(k, key) {
+				return value, true
+			}
 		}
 	}
-	return m._map[key]
+	var zero V
+	return zero, false
 }
 
 func (m *Map[K, V]) set(key K, value V) *Map[K, V] {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if _, ok := m._map[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
 	m._map[key] = value
 	m.length = len(m._map)
 	return m
 }
 
 func MapEntries[K, V comparable](_map *Map[K, V]) [][]any {
+	_map.mu.RLock()
+	defer _map.mu.RUnlock()
 	var slice [][]any
-	for k, v := range _map._map {
-		slice = append(slice, []any{k, v})
+	for _, k := range _map.keys {
+		slice = append(slice, []any{k, _map._map[k]})
 	}
 	return slice
 }
@@ -67,11 +95,19 @@ func MapEntries[K, V comparable](_map *Map[K, V]) [][]any {
 
 type callback[K comparable, V any] func(key K, value V)
 
+// the callback runs without the lock, so it may set and delete keys
 func (m *Map[K, V]) forEach(callback callback[K, V]) {
 	m.mu.RLock()
-	defer m.mu.RUnlock()
-	for key, value := range m._map {
-		callback(key, value)
+	keys := slices.Clone(m.keys)
+	m.mu.RUnlock()
+	for _, key := range keys {
+		m.mu.RLock()
+		value, ok := m._map[key]
+		m.mu.RUnlock()
+		// keys deleted by callback are skipped
+		if ok {
+			callback(key, value)
+		}
 	}
 }
 
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"cmp"
+	"strings"
+)
+
+// a variable a pattern binds, declared in the scope of the arm once the arm matches
+type binding struct {
+	name  string
+	value RuntimeVal
+}
+
+func (r *Interpreter) Eval_match_expr(expr *MatchExpr, env *Environment) RuntimeVal {
+	match_against := r.Evaluate(expr.match, env)
+	for i := 0; i < len(expr.cases); i++ {
+		_case := expr.cases[i]
+		bindings, ok := r.matchPattern(_case.pattern, match_against, nil, env)
+		if !ok {
+			continue
+		}
+		scope := NewEnv(env, "block", env.sourcePath)
+		pos := getPosFromNode(_case.pattern)
+		for _, b := range bindings {
+			scope.DeclareVar(b.name, b.value, "mutable", pos.line, pos.col, pos.count, env.sourcePath, r)
+		}
+		if _case.guard != nil && !RtvToBool(r.Evaluate(_case.guard, scope)) {
+			continue
+		}
+		return r.Evaluate(_case.body, scope)
+	}
+	env.throwError([]string{"non-exhaustive match, no arm matches the value", match_against.noAnsi(),
+		SourceLog(expr.line, expr.col, expr.count, env.sourcePath, "")})
+	return undefined
+}
+
+// reports whether value matches pattern, and returns bindings with the variables it binds added
+func (r *Interpreter) matchPattern(pattern *Pattern, value RuntimeVal, bindings []binding, env *Environment) ([]binding, bool) {
+	ok := false
+	switch pattern.kind {
+	case "wildcard":
+		ok = true
+	case "value":
+		ok = RtvAreEqual(value, r.Evaluate(pattern.value, env))
+	case "type":
+		ok = ValueType(value) == pattern.typ
+	case "range":
+		ok = r.inRange(pattern, value, env)
+	case "or":
+		for _, alternative := range pattern.patterns {
+			// an alternative that does not match binds nothing
+			var matched []binding
+			if matched, ok = r.matchPattern(alternative, value, bindings, env); ok {
+				bindings = matched
+				break
+			}
+		}
+	case "array":
+		bindings, ok = r.matchArray(pattern, value, bindings, env)
+	case "object":
+		bindings, ok = r.matchObject(pattern, value, bindings, env)
+	case "class":
+		instance, isInstance := value.(*Instance)
+		class, isClass := r.Evaluate(pattern.value, env).(*ClassVal)
+		if !isClass {
+			pos := getPosFromNode(pattern.value)
+			env.ThrowTypeError(pattern.value.(*Identifier).Symbol, "is not a class and cannot be used in a class pattern",
+				SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+		}
+		if isInstance && InstanceOf(instance, class) {
+			bindings, ok = r.matchObject(pattern, value, bindings, env)
+		}
+	}
+	if ok && pattern.bind != "" {
+		bindings = append(bindings, binding{pattern.bind, value})
+	}
+	return bindings, ok
+}
+
+// numbers are compared as numbers and strings as strings, anything else is out of range
+func (r *Interpreter) inRange(pattern *Pattern, value RuntimeVal, env *Environment) bool {
+	if pattern.value != nil {
+		if order, ok := compareValues(value, r.Evaluate(pattern.value, env)); !ok || order < 0 {
+			return false
+		}
+	}
+	if pattern.to != nil {
+		order, ok := compareValues(value, r.Evaluate(pattern.to, env))
+		return ok && (order < 0 || pattern.inclusive && order == 0)
+	}
+	return true
+}
+
+// orders two numbers, two bigints or two strings, ok is false for values of any other types
+func compareValues(a, b RuntimeVal) (int, bool) {
+	switch a := a.(type) {
+	case *NumberVal:
+		if b, ok := b.(*NumberVal); ok {
+			return cmp.Compare(a.value, b.value), true
+		}
+	case *BigIntVal:
+		if b, ok := b.(*BigIntVal); ok {
+			return a.value.Cmp(b.value), true
+		}
+	case *StringVal:
+		if b, ok := b.(*StringVal); ok {
+			return strings.Compare(a.value, b.value), true
+		}
+	}
+	return 0, false
+}
+
+func (r *Interpreter) matchArray(pattern *Pattern, value RuntimeVal, bindings []binding, env *Environment) ([]binding, bool) {
+	array, ok := value.(*ArrayVal)
+	if !ok {
+		return bindings, false
+	}
+	length := array.elements.length
+	if length < len(pattern.patterns) || !pattern.hasRest && length != len(pattern.patterns) {
+		return bindings, false
+	}
+	for i, element := range pattern.patterns {
+		if bindings, ok = r.matchPattern(element, array.get(i), bindings, env); !ok {
+			return bindings, false
+		}
+	}
+	if pattern.rest != "" {
+		rest := MK_ARRAY()
+		for i := len(pattern.patterns); i < length; i++ {
+			rest.Push(array.get(i))
+		}
+		bindings = append(bindings, binding{pattern.rest, rest})
+	}
+	return bindings, true
+}
+
+// matches the properties of objects and instances, a property that is missing never matches
+func (r *Interpreter) matchObject(pattern *Pattern, value RuntimeVal, bindings []binding, env *Environment) ([]binding, bool) {
+	var own *Map[RuntimeVal, Ref]
+	switch v := value.(type) {
+	case *ObjectVal:
+		own = v.properties
+	case *Instance:
+		own = v.properties
+	default:
+		return bindings, false
+	}
+	ok := false
+	for i, key := range pattern.keys {
+		ml := r.MemberRef(value, MK_STRING(key), false, pattern.Pos, env)
+		if ml == nil || Memory.get(ml) == nil {
+			return bindings, false
+		}
+		if bindings, ok = r.matchPattern(pattern.patterns[i], r.memberValue(ml, env, pattern.Pos), bindings, env); !ok {
+			return bindings, false
+		}
+	}
+	if pattern.rest != "" {
+		rest := MK_OBJECT(nil, nil, r)
+		own.forEach(func(key RuntimeVal, ml Ref) {
+			if k, ok := key.(*StringVal); !ok || !is_value(k.value, pattern.keys...) {
+				rest.properties.set(key, Memory.alloc(Memory.get(ml)))
+			}
+		})
+		bindings = append(bindings, binding{pattern.rest, rest})
+	}
+	return bindings, true
+}
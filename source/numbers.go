@@ -0,0 +1,323 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// the text of a number: integers up to 1e21 are written in full, like
+// 100000000, larger and very small numbers with an exponent, like 1e+21
+func formatNumber(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	}
+	if abs := math.Abs(n); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		text := strconv.FormatFloat(n, 'g', -1, 64)
+		// 1e-07 is 1e-7
+		mantissa, exponent, _ := strings.Cut(text, "e")
+		return mantissa + "e" + exponent[:1] + strings.TrimLeft(exponent[1:], "0")
+	}
+	// -0 is 0
+	return strconv.FormatFloat(n+0, 'f', -1, 64)
+}
+
+// the value of a number literal: 0x, 0o and 0b prefix hexadecimal, octal and
+// binary integers, _ separates digits
+func parseNumberLiteral(src string) float64 {
+	src = strings.ReplaceAll(src, "_", "")
+	if len(src) > 2 && src[0] == '0' {
+		base := map[byte]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}[src[1]]
+		if base != 0 {
+			// as exactly as a float64 holds it, however many digits it has
+			integer, _ := new(big.Int).SetString(src[2:], base)
+			n, _ := new(big.Float).SetInt(integer).Float64()
+			return n
+		}
+	}
+	n, _ := strconv.ParseFloat(src, 64)
+	return n
+}
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// the text of n in radix, which is between 2 and 36
+func formatRadix(n float64, radix int) string {
+	if radix == 10 || math.IsNaN(n) || math.IsInf(n, 0) {
+		return formatNumber(n)
+	}
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	integer, fraction := math.Modf(n)
+	text := new(big.Int)
+	new(big.Float).SetFloat64(integer).Int(text)
+	out := sign + text.Text(radix)
+	if fraction == 0 {
+		return out
+	}
+	out += "."
+	// enough digits for the 52 bits of the fraction in any radix
+	for i := 0; i < 52 && fraction != 0; i++ {
+		fraction *= float64(radix)
+		digit, rest := math.Modf(fraction)
+		out += string(digits[int(digit)])
+		fraction = rest
+	}
+	return out
+}
+
+// the number the start of str is written as in radix, NaN when it starts with no digit of it;
+// radix 0 is 10, or 16 for a str starting with 0x
+func parseInt(str string, radix int) float64 {
+	str = strings.TrimSpace(str)
+	sign := 1.0
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		if str[0] == '-' {
+			sign = -1
+		}
+		str = str[1:]
+	}
+	lower := strings.ToLower(str)
+	if (radix == 0 || radix == 16) && strings.HasPrefix(lower, "0x") {
+		str, lower, radix = str[2:], lower[2:], 16
+	}
+	if radix == 0 {
+		radix = 10
+	}
+	if radix < 2 || radix > 36 {
+		return math.NaN()
+	}
+	end := 0
+	for end < len(lower) {
+		if i := strings.IndexByte(digits[:radix], lower[end]); i < 0 {
+			break
+		}
+		end++
+	}
+	if end == 0 {
+		return math.NaN()
+	}
+	integer, _ := new(big.Int).SetString(lower[:end], radix)
+	n, _ := new(big.Float).SetInt(integer).Float64()
+	return sign * n
+}
+
+var floatPrefix = regexp.MustCompile(`^[+-]?(Infinity|(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?)`)
+
+// the number the start of str is written as, NaN when it starts with none
+func parseFloat(str string) float64 {
+	prefix := floatPrefix.FindString(strings.TrimSpace(str))
+	if prefix == "" {
+		return math.NaN()
+	}
+	n, _ := strconv.ParseFloat(strings.Replace(prefix, "Infinity", "Inf", 1), 64)
+	return n
+}
+
+// the generator of Math.random, Math.seed replaces it with one that repeats its numbers
+var random = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))}
+
+//#region Number methods
+
+// a method every number has, n is the number it is called on
+type numberMethod func(n float64, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal
+
+// the native methods of numbers, they are looked up when a property of a number is read
+var numberMethods map[string]numberMethod
+
+// the method of n named name bound to n, nil when numbers have no such method
+func (n *NumberVal) method(name string) *Macro {
+	method, ok := numberMethods[name]
+	if !ok {
+		return nil
+	}
+	return MK_MACRO(name, func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return method(n.value, args, env, pos, r)
+	})
+}
+
+func init() {
+	numberMethods = map[string]numberMethod{
+		"toFixed": func(n float64, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			fractionDigits := numberArg(args, 0, 0, "number.toFixed", env, pos)
+			if fractionDigits < 0 || fractionDigits > 100 {
+				env.ThrowRangeError("number.toFixed expects between 0 and 100 digits, got", formatNumber(fractionDigits), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			if math.IsNaN(n) || math.IsInf(n, 0) || math.Abs(n) >= 1e21 {
+				return MK_STRING(formatNumber(n))
+			}
+			return MK_STRING(strconv.FormatFloat(n, 'f', int(fractionDigits), 64))
+		},
+		"toString": func(n float64, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			radix := numberArg(args, 0, 10, "number.toString", env, pos)
+			if radix < 2 || radix > 36 || radix != math.Trunc(radix) {
+				env.ThrowRangeError("number.toString expects a radix between 2 and 36, got", formatNumber(radix), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			return MK_STRING(formatRadix(n, int(radix)))
+		},
+	}
+}
+
+//#endregion
+
+//#region Math and Number
+
+// a function of Math that takes one number
+func mathFunction(name string, fn func(float64) float64) *Macro {
+	return MK_MACRO(name, func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		return MK_NUMBER(fn(numberArg(args, 0, math.NaN(), "Math."+name, env, pos)))
+	})
+}
+
+// the numbers a function of Math was called with
+func numberArgs(args []RuntimeVal, name string, env *Environment, pos Pos) []float64 {
+	numbers := make([]float64, len(args))
+	for i := range args {
+		numbers[i] = numberArg(args, i, math.NaN(), name, env, pos)
+	}
+	return numbers
+}
+
+// the Math object
+func MK_MATH(r *Interpreter) *ObjectVal {
+	members := map[string]RuntimeVal{
+		"PI":      MK_NUMBER(math.Pi),
+		"E":       MK_NUMBER(math.E),
+		"LN2":     MK_NUMBER(math.Ln2),
+		"LN10":    MK_NUMBER(math.Ln10),
+		"LOG2E":   MK_NUMBER(math.Log2E),
+		"LOG10E":  MK_NUMBER(math.Log10E),
+		"SQRT2":   MK_NUMBER(math.Sqrt2),
+		"SQRT1_2": MK_NUMBER(math.Sqrt2 / 2),
+	}
+	for name, fn := range map[string]func(float64) float64{
+		"floor": math.Floor, "ceil": math.Ceil, "trunc": math.Trunc, "abs": math.Abs,
+		"sqrt": math.Sqrt, "cbrt": math.Cbrt, "exp": math.Exp, "expm1": math.Expm1,
+		"log": math.Log, "log2": math.Log2, "log10": math.Log10, "log1p": math.Log1p,
+		"sin": math.Sin, "cos": math.Cos, "tan": math.Tan, "asin": math.Asin, "acos": math.Acos, "atan": math.Atan,
+		"sinh": math.Sinh, "cosh": math.Cosh, "tanh": math.Tanh, "asinh": math.Asinh, "acosh": math.Acosh, "atanh": math.Atanh,
+		// halves are rounded up, -2.5 to -2
+		"round": func(x float64) float64 {
+			rounded := math.Floor(x)
+			if x-rounded >= 0.5 {
+				rounded++
+			}
+			return rounded
+		},
+		"sign": func(x float64) float64 {
+			if x == 0 || math.IsNaN(x) {
+				return x
+			}
+			return math.Copysign(1, x)
+		},
+	} {
+		members[name] = mathFunction(name, fn)
+	}
+	members["pow"] = MK_MACRO("pow", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		return MK_NUMBER(math.Pow(numberArg(args, 0, math.NaN(), "Math.pow", env, pos), numberArg(args, 1, math.NaN(), "Math.pow", env, pos)))
+	})
+	members["atan2"] = MK_MACRO("atan2", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		return MK_NUMBER(math.Atan2(numberArg(args, 0, math.NaN(), "Math.atan2", env, pos), numberArg(args, 1, math.NaN(), "Math.atan2", env, pos)))
+	})
+	members["hypot"] = MK_MACRO("hypot", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		sum := 0.0
+		for _, n := range numberArgs(args, "Math.hypot", env, pos) {
+			sum += n * n
+		}
+		return MK_NUMBER(math.Sqrt(sum))
+	})
+	// without arguments, min is Infinity and max -Infinity; NaN wins over any number
+	members["min"] = MK_MACRO("min", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		result := math.Inf(1)
+		for _, n := range numberArgs(args, "Math.min", env, pos) {
+			result = math.Min(result, n)
+		}
+		return MK_NUMBER(result)
+	})
+	members["max"] = MK_MACRO("max", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		result := math.Inf(-1)
+		for _, n := range numberArgs(args, "Math.max", env, pos) {
+			result = math.Max(result, n)
+		}
+		return MK_NUMBER(result)
+	})
+	// a number from 0 up to 1, excluded
+	members["random"] = MK_MACRO("random", func(_ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+		random.Lock()
+		defer random.Unlock()
+		return MK_NUMBER(random.Float64())
+	})
+	// the numbers random returns after seed is called with the same seed are the same
+	members["seed"] = MK_MACRO("seed", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		seed := math.Float64bits(numberArg(args, 0, 0, "Math.seed", env, pos))
+		random.Lock()
+		defer random.Unlock()
+		random.Rand = rand.New(rand.NewPCG(seed, seed))
+		return undefined
+	})
+	return record(members, r)
+}
+
+// the Number object
+func MK_NUMBER_NAMESPACE(r *Interpreter) *ObjectVal {
+	members := map[string]RuntimeVal{
+		"EPSILON":           MK_NUMBER(math.Nextafter(1, 2) - 1),
+		"MAX_SAFE_INTEGER":  MK_NUMBER(1<<53 - 1),
+		"MIN_SAFE_INTEGER":  MK_NUMBER(-(1<<53 - 1)),
+		"MAX_VALUE":         MK_NUMBER(math.MaxFloat64),
+		"MIN_VALUE":         MK_NUMBER(math.SmallestNonzeroFloat64),
+		"NaN":               MK_NUMBER(math.NaN()),
+		"POSITIVE_INFINITY": MK_NUMBER(math.Inf(1)),
+		"NEGATIVE_INFINITY": MK_NUMBER(math.Inf(-1)),
+	}
+	members["parseInt"] = MK_MACRO("parseInt", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		str := stringArg(args, 0, "Number.parseInt", env, pos)
+		return MK_NUMBER(parseInt(str, int(numberArg(args, 1, 0, "Number.parseInt", env, pos))))
+	})
+	members["parseFloat"] = MK_MACRO("parseFloat", func(args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+		return MK_NUMBER(parseFloat(stringArg(args, 0, "Number.parseFloat", env, pos)))
+	})
+	// values that are not numbers are none of these
+	test := func(name string, fn func(float64) bool) {
+		members[name] = MK_MACRO(name, func(args []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			n, ok := argAt(args, 0).(*NumberVal)
+			return MK_BOOL(ok && fn(n.value))
+		})
+	}
+	test("isNaN", math.IsNaN)
+	test("isFinite", func(n float64) bool { return !math.IsNaN(n) && !math.IsInf(n, 0) })
+	test("isInteger", func(n float64) bool { return !math.IsInf(n, 0) && n == math.Trunc(n) })
+	test("isSafeInteger", func(n float64) bool { return n == math.Trunc(n) && math.Abs(n) <= 1<<53-1 })
+	return record(members, r)
+}
+
+// an object with the members, in the order of their names
+func record(members map[string]RuntimeVal, r *Interpreter) *ObjectVal {
+	keys := []string{}
+	for key := range members {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	values := []RuntimeVal{}
+	for _, key := range keys {
+		values = append(values, members[key])
+	}
+	return MK_RECORD(r, keys, values...)
+}
+
+//#endregion
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -19,11 +21,38 @@ type Pos struct {
 }
 
 type Parser struct {
+	// set while the value of a match pattern is parsed, | separates alternatives there
+	inPattern bool
+	// set while the body of a generator function is parsed, yield is valid there
+	generator  bool
 	tokens     *TokenArray
 	tokenIndex uint
 	program    *Program
 	scriptType string
 	sourcePath string
+	// the labels and goto statements of the function being parsed
+	labels []*ParsedLabel
+	gotos  []ParsedGoto
+	// the blocks around the statement being parsed
+	blocks     []int
+	blockCount int
+}
+
+// a label of the function being parsed, active while the statement
+// it labels is parsed
+type ParsedLabel struct {
+	name   string
+	loop   bool
+	block  int
+	active bool
+	Pos
+}
+
+// a goto statement with the blocks around it
+type ParsedGoto struct {
+	name   string
+	blocks []int
+	Pos
 }
 
 // Program (AST)
@@ -80,6 +109,7 @@ type WhileLoop struct {
 	condition Node
 	body      []Node
 	do        bool
+	label     string
 	Pos
 }
 
@@ -164,6 +194,7 @@ type ForLoop struct {
 	condition Node
 	after     Node
 	body      []Node
+	label     string
 	Pos
 }
 
@@ -187,7 +218,9 @@ type ForIteratorLoop struct {
 	right Node
 	_type string
 	op    string // ("in" | "of")
+	await bool   // for await (... of ...)
 	body  []Node
+	label string
 	Pos
 }
 
@@ -208,6 +241,7 @@ func (stmt *ForIteratorLoop) String() string {
 type FunctionDecl struct {
 	name      DynamicNode
 	async     bool
+	generator bool // function*
 	anonymous bool
 	_type     string // ("arrow" | "")
 	body      []Node
@@ -221,15 +255,33 @@ func (stmt *FunctionDecl) node() {}
 // String implements Node.
 func (stmt *FunctionDecl) String() string {
 	return fmt.Sprintf(
-		"Node \x1b[32mFunction Declaration\x1b[0m {\r\n  name: %+v\r\n  parameters: %+v\r\n  async: %t\r\n  type: %s\r\n  body: %+v\r\n}",
+		"Node \x1b[32mFunction Declaration\x1b[0m {\r\n  name: %+v\r\n  parameters: %+v\r\n  async: %t\r\n  generator: %t\r\n  type: %s\r\n  body: %+v\r\n}",
 		stmt.name,
 		stmt.params,
 		stmt.async,
+		stmt.generator,
 		stmt._type,
 		stmt.body,
 	)
 }
 
+// Yield Expression (AST)
+type YieldExpr struct {
+	// nil for a yield without a value
+	operand Node
+	// yield*, the values of operand are yielded one by one
+	delegate bool
+	Pos
+}
+
+// node implements Node.
+func (expr *YieldExpr) node() {}
+
+// String implements Node.
+func (expr *YieldExpr) String() string {
+	return fmt.Sprintf("Node \x1b[32mYield Expression\x1b[0m {\r\n  operand: %+v\r\n  delegate: %t\r\n}", expr.operand, expr.delegate)
+}
+
 // Return Statement (AST)
 type ReturnStmt struct {
 	value Node
@@ -246,6 +298,7 @@ func (stmt *ReturnStmt) String() string {
 
 // Break Statement (AST)
 type BreakStmt struct {
+	label string
 	Pos
 }
 
@@ -259,6 +312,7 @@ func (stmt *BreakStmt) String() string {
 
 // Continue Statement (AST)
 type ContinueStmt struct {
+	label string
 	Pos
 }
 
@@ -273,6 +327,8 @@ func (stmt *ContinueStmt) String() string {
 // Label (AST)
 type Label struct {
 	name string
+	// the statement after the label, nil at the end of a block
+	body Node
 	Pos
 }
 
@@ -281,7 +337,21 @@ func (stmt *Label) node() {}
 
 // String implements Node.
 func (stmt *Label) String() string {
-	return fmt.Sprintf("Node \x1b[32mLabel\x1b[0m { pos: %+v }", stmt.Pos)
+	return fmt.Sprintf("Node \x1b[32mLabel\x1b[0m {\r\n  name: %s\r\n  body: %+v\r\n  pos: %+v }", stmt.name, stmt.body, stmt.Pos)
+}
+
+// Go Statement (AST)
+type GoStmt struct {
+	call *CallExpr
+	Pos
+}
+
+// node implements Node.
+func (stmt *GoStmt) node() {}
+
+// String implements Node.
+func (stmt *GoStmt) String() string {
+	return fmt.Sprintf("Node \x1b[32mGoStmt\x1b[0m { call: %s, pos: %+v }", stmt.call.String(), stmt.Pos)
 }
 
 // Goto Statement (AST)
@@ -295,7 +365,7 @@ func (stmt *GotoStmt) node() {}
 
 // String implements Node.
 func (stmt *GotoStmt) String() string {
-	return fmt.Sprintf("Node \x1b[32mGotoStmt\x1b[0m { pos: %+v }", stmt.Pos)
+	return fmt.Sprintf("Node \x1b[32mGotoStmt\x1b[0m { label: %s, pos: %+v }", stmt.label.name, stmt.Pos)
 }
 
 type ClassProperty struct {
@@ -325,7 +395,9 @@ func (stmt *ClassProperty) String() string {
 type ClassMethod struct {
 	private bool
 	static  bool
-	name    struct {
+	// ("get" | "set") for accessors, empty for methods
+	kind string
+	name struct {
 		dynamic bool
 		node    Node
 	}
@@ -339,14 +411,29 @@ func (stmt *ClassMethod) node() {}
 // String implements Node.
 func (stmt *ClassMethod) String() string {
 	return fmt.Sprintf(
-		"Node \x1b[32mClass Method\x1b[0m {\r\n  private: %t\r\n  static: %t\r\n  name: %+v\r\n  decl: %+v\r\n}",
+		"Node \x1b[32mClass Method\x1b[0m {\r\n  private: %t\r\n  static: %t\r\n  kind: %s\r\n  name: %+v\r\n  decl: %+v\r\n}",
 		stmt.private,
 		stmt.static,
+		stmt.kind,
 		stmt.name,
 		stmt.decl,
 	)
 }
 
+// static { ... } in a class body
+type StaticBlock struct {
+	body []Node
+	Pos
+}
+
+// node implements Node.
+func (stmt *StaticBlock) node() {}
+
+// String implements Node.
+func (stmt *StaticBlock) String() string {
+	return fmt.Sprintf("Node \x1b[32mStatic Block\x1b[0m {\r\n  body: %+v\r\n}", stmt.body)
+}
+
 type CtorParam struct {
 	private bool
 	public  bool
@@ -391,10 +478,12 @@ func (stmt *Constructor) String() string {
 
 // Class Declaration (AST)
 type ClassDecl struct {
-	name        string
-	anonymous   bool
-	properties  []*ClassProperty
-	methods     []*ClassMethod
+	name       string
+	anonymous  bool
+	properties []*ClassProperty
+	methods    []*ClassMethod
+	// static properties, methods and blocks, in the order they are declared
+	statics     []Node
 	extends     string
 	constructor *Constructor
 	Pos
@@ -545,7 +634,11 @@ func (expr *InstanceofExpr) String() string {
 
 // Template String (AST)
 type TemplateString struct {
-	str []Node
+	// the text around the interpolations with its escapes replaced,
+	// nil where an escape is invalid
+	cooked []*string
+	raw    []string
+	exprs  []Node
 	Pos
 }
 
@@ -555,8 +648,44 @@ func (expr *TemplateString) node() {}
 // String implements Node.
 func (expr *TemplateString) String() string {
 	return fmt.Sprintf(
-		"Node \x1b[32mTemplate String\x1b[0m {\r\n  operand: %+v\r\n  pos: %+v }",
-		expr.str,
+		"Node \x1b[32mTemplate String\x1b[0m {\r\n  raw: %q\r\n  exprs: %+v\r\n  pos: %+v }",
+		expr.raw,
+		expr.exprs,
+		expr.Pos,
+	)
+}
+
+// Regular Expression Literal (AST)
+type RegExpLiteral struct {
+	pattern string
+	flags   string
+	Pos
+}
+
+// node implements Node.
+func (expr *RegExpLiteral) node() {}
+
+// String implements Node.
+func (expr *RegExpLiteral) String() string {
+	return fmt.Sprintf("Node \x1b[32mRegExp Literal\x1b[0m { pattern: %q, flags: %q }", expr.pattern, expr.flags)
+}
+
+// Tagged Template (AST)
+type TaggedTemplate struct {
+	tag      Node
+	template *TemplateString
+	Pos
+}
+
+// node implements Node.
+func (expr *TaggedTemplate) node() {}
+
+// String implements Node.
+func (expr *TaggedTemplate) String() string {
+	return fmt.Sprintf(
+		"Node \x1b[32mTagged Template\x1b[0m {\r\n  tag: %+v\r\n  template: %+v\r\n  pos: %+v }",
+		expr.tag,
+		expr.template,
 		expr.Pos,
 	)
 }
@@ -579,11 +708,51 @@ func (expr *RestOrSpreadExpr) String() string {
 	)
 }
 
+// an arm of a match expression, pattern [if guard] => body
 type Match struct {
-	match Node
+	pattern *Pattern
+	// nil when the arm has no guard
+	guard Node
 	body  Node
 }
 
+// Pattern of a match arm (AST)
+type Pattern struct {
+	// ("wildcard" | "value" | "range" | "type" | "object" | "array" | "class" | "or")
+	kind string
+	// the variable the value is bound to when it matches, empty for none
+	bind string
+	// value: compared with the value; range: the bounds, nil when open; class: the class
+	value Node
+	to    Node
+	// range: whether to is included
+	inclusive bool
+	// type: what typeof gives for the value
+	typ string
+	// object and class: the keys of the properties
+	keys []string
+	// object and class: the patterns of the properties; array: of the elements; or: the alternatives
+	patterns []*Pattern
+	// object and array: ...rest binds the properties or the elements that are left
+	hasRest bool
+	rest    string
+	Pos
+}
+
+// node implements Node.
+func (pattern *Pattern) node() {}
+
+// String implements Node.
+func (pattern *Pattern) String() string {
+	return fmt.Sprintf(
+		"Node \x1b[32mPattern\x1b[0m {\r\n  kind: %s\r\n  bind: %s\r\n  patterns: %+v\r\n  pos: %+v }",
+		pattern.kind,
+		pattern.bind,
+		pattern.patterns,
+		pattern.Pos,
+	)
+}
+
 // Match Expression (AST)
 type MatchExpr struct {
 	cases []Match
@@ -647,6 +816,19 @@ func (expr *NewExpr) String() string {
 	return fmt.Sprintf("Node \x1b[32mNew Expression\x1b[0m {\r\n  operand: %+v\r\n}", expr.operand)
 }
 
+// super in super.method(), the object of a member expression (AST).
+type SuperRef struct {
+	Pos
+}
+
+// node implements Node.
+func (expr *SuperRef) node() {}
+
+// String implements Node.
+func (expr *SuperRef) String() string {
+	return fmt.Sprintf("Node \x1b[32mSuper\x1b[0m { pos: %+v }", expr.Pos)
+}
+
 // Super Expression (AST).
 type SuperExpr struct {
 	args []Node
@@ -679,6 +861,8 @@ func (expr *AwaitExpr) String() string {
 type CallExpr struct {
 	caller Node
 	args   []Node
+	// f?.()
+	optional bool
 	Pos
 }
 
@@ -791,6 +975,8 @@ type MemberExpr struct {
 	object   Node
 	property Node
 	computed bool
+	// a?.b and a?.[k]
+	optional bool
 	Pos
 }
 
@@ -802,6 +988,35 @@ func (expr *MemberExpr) String() string {
 	return fmt.Sprintf("Node \x1b[32mMember Expression\x1b[0m {\r\n  object: %+v\r\n  propery: %+v\r\n  computed: %t\r\n}", expr.object, expr.property, expr.computed)
 }
 
+// a member and call chain with ?. in it (AST), the whole chain is undefined
+// when the object of a ?. is null or undefined
+type OptionalChain struct {
+	expr Node
+	Pos
+}
+
+// node implements Node.
+func (expr *OptionalChain) node() {}
+
+// String implements Node.
+func (expr *OptionalChain) String() string {
+	return fmt.Sprintf("Node \x1b[32mOptional Chain\x1b[0m {\r\n  expr: %+v\r\n}", expr.expr)
+}
+
+// Bitwise Not Expression (AST), ~operand
+type BitwiseNotExpr struct {
+	operand Node
+	Pos
+}
+
+// node implements Node.
+func (expr *BitwiseNotExpr) node() {}
+
+// String implements Node.
+func (expr *BitwiseNotExpr) String() string {
+	return fmt.Sprintf("Node \x1b[32mBitwise Not Expression\x1b[0m {\r\n  operand: %+v\r\n}", expr.operand)
+}
+
 // Void Expression (AST)
 type VoidExpr struct {
 	operand Node
@@ -923,6 +1138,20 @@ func (n *Number) String() string {
 	return fmt.Sprintf("Node \x1b[32mNumber\x1b[0m { Value: \x1b[33m%v\x1b[0m }", n.Value)
 }
 
+// BigInt Values (AST)
+type BigInt struct {
+	Value *big.Int
+	Pos
+}
+
+// node implements Node.
+func (n *BigInt) node() {}
+
+// String implements Node.
+func (n *BigInt) String() string {
+	return fmt.Sprintf("Node \x1b[32mBigInt\x1b[0m { Value: \x1b[33m%vn\x1b[0m }", n.Value)
+}
+
 // Strings (AST)
 type String struct {
 	Value string
@@ -959,6 +1188,21 @@ func (p *Parser) at(index uint) Token {
 	return p.tokens.at(index)
 }
 
+var namePattern = regexp.MustCompile(`^[a-zA-Z_#]+[a-zA-Z0-9_#]*$`)
+
+// lets a keyword be a property name after a dot (promise.catch)
+func (p *Parser) keywordAsName() {
+	p.keywordAsNameAt(0)
+}
+
+// turns the keyword at offset into an identifier
+func (p *Parser) keywordAsNameAt(offset uint) {
+	tk := p.at(offset)
+	if tk.typ == tk.src && namePattern.MatchString(tk.src) {
+		p.tokens.elements[p.tokenIndex+offset].typ = TokenType["Identifier"]
+	}
+}
+
 func (p *Parser) nextToken() {
 	p.tokenIndex++
 }
@@ -990,7 +1234,9 @@ func (p *Parser) throwSyntaxError(message string) {
 
 // #region Parser
 
-func NewParser(path string, scriptType string, source string) *Parser {
+// a parser for the file at path, source is read from it when empty; tokenize
+// is Tokenize for AS code, TokenizeVerdex for Verdex sources
+func NewParser(path string, scriptType string, source string, tokenize func(source string, path string) *TokenArray) *Parser {
 	if !IsAbs(path) {
 		path = AbsPath(path)
 	}
@@ -998,7 +1244,7 @@ func NewParser(path string, scriptType string, source string) *Parser {
 	if len(source) == 0 {
 		source = ReadTextFile(path)
 	}
-	tokens = Tokenize(source, path)
+	tokens = tokenize(source, path)
 	return &Parser{
 		sourcePath: path,
 		tokens:     tokens,
@@ -1015,6 +1261,7 @@ func (p *Parser) Parse(main bool) *Program {
 	for p.not_eof() {
 		p.program.body = append(p.program.body, p.parse_stmt())
 	}
+	p.checkGotos()
 	return p.program
 }
 
@@ -1044,6 +1291,10 @@ func (p *Parser) parse_stmt() Node {
 		return p.parse_continue_stmt()
 	case TokenType["Label"]:
 		return p.parse_label()
+	case "goto":
+		return p.parse_goto_stmt()
+	case "go":
+		return p.parse_go_stmt()
 	case "class":
 		return p.parse_class_decl(false)
 	case "import":
@@ -1246,14 +1497,32 @@ func (p *Parser) parse_class_decl(expr bool) Node {
 	hasConstructor := false
 	methods := []*ClassMethod{}
 	properties := []*ClassProperty{}
+	statics := []Node{}
 	var constructor *Constructor
 	for p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
-		if prop, ok := p.parse_class_prop(); ok {
-			properties = append(properties, prop)
+		if p.at(0).typ == "static" && p.at(1).typ == TokenType["OpenBrace"] {
+			pos := getPosofToken(p.eat())
+			statics = append(statics, &StaticBlock{
+				body: p.parse_function_body(false),
+				Pos:  pos,
+			})
+		} else if prop, ok := p.parse_class_prop(); ok {
+			if prop.static {
+				statics = append(statics, prop)
+			} else {
+				properties = append(properties, prop)
+			}
 		} else if method, ok := p.parse_class_method(); ok {
-			methods = append(methods, method)
+			if method.static {
+				statics = append(statics, method)
+			} else {
+				methods = append(methods, method)
+			}
 		} else if ctor, ok := p.parse_class_ctor(hasConstructor); ok {
 			constructor = ctor
+			hasConstructor = true
+		} else {
+			p.throwUnexpectedTokenError(p.at(0))
 		}
 	}
 	p.expect(TokenType["CloseBrace"])
@@ -1261,6 +1530,7 @@ func (p *Parser) parse_class_decl(expr bool) Node {
 		name:        name,
 		properties:  properties,
 		methods:     methods,
+		statics:     statics,
 		extends:     extends,
 		constructor: constructor,
 		Pos:         pos,
@@ -1307,7 +1577,7 @@ func (p *Parser) parse_class_ctor(hasConstructor bool) (*Constructor, bool) {
 		}
 	}
 	p.expect(TokenType["CloseParen"])
-	body := p.parse_block()
+	body := p.parse_function_body(false)
 	return &Constructor{
 		name:      "constructor",
 		async:     false,
@@ -1322,27 +1592,60 @@ func (p *Parser) parse_class_ctor(hasConstructor bool) (*Constructor, bool) {
 func (p *Parser) parse_class_method() (*ClassMethod, bool) {
 	private := false
 	static := false
-	tk_len := 0
-	if is_value(p.at(uint(tk_len)).typ, "private", "public") {
-		private = p.at(uint(tk_len)).typ == "private"
+	kind := ""
+	var tk_len uint = 0
+	if is_value(p.at(tk_len).typ, "private", "public") {
+		private = p.at(tk_len).typ == "private"
 		tk_len++
 	}
-	if p.at(uint(tk_len)).typ != "function" {
-		return &ClassMethod{}, false
+	if p.at(tk_len).typ == "static" {
+		static = true
+		tk_len++
 	}
-	pos := Pos{}
-	for i := 0; i < tk_len; i++ {
-		tk := p.eat()
-		if i == 0 {
-			pos = getPosofToken(tk)
+	// keywords can name methods, function delete() and delete()
+	for i := tk_len; i < tk_len+3; i++ {
+		if p.at(i+1).typ == TokenType["OpenParen"] && !is_value(p.at(i).typ, "function", "async", "constructor") {
+			p.keywordAsNameAt(i)
 		}
 	}
-	decl := *p.parse_function_decl(false, true, false)
+	// function name(), async function name(), get name(), set name(), name() or async name(),
+	// and *name() or async *name() for generators
+	shorthand := true
+	star := func(offset uint) bool {
+		tk := p.at(offset)
+		return tk.typ == TokenType["BinaryOp"] && tk.src == "*" &&
+			is_value(p.at(offset+1).typ, TokenType["Identifier"], TokenType["OpenBracket"])
+	}
+	switch tk := p.at(tk_len); {
+	case tk.typ == "function" || tk.typ == "async" && p.at(tk_len+1).typ == "function":
+		shorthand = false
+	case is_value(tk.src, "get", "set") && tk.typ == TokenType["Identifier"] &&
+		is_value(p.at(tk_len+1).typ, TokenType["Identifier"], TokenType["OpenBracket"]):
+		kind = tk.src
+		tk_len++
+	case tk.typ == "async" && p.at(tk_len+1).typ == TokenType["Identifier"] && p.at(tk_len+2).typ == TokenType["OpenParen"]:
+	case tk.typ == TokenType["Identifier"] && p.at(tk_len+1).typ == TokenType["OpenParen"]:
+	case star(tk_len), tk.typ == "async" && star(tk_len+1):
+	default:
+		return &ClassMethod{}, false
+	}
+	pos := getPosofToken(p.at(0))
+	for i := uint(0); i < tk_len; i++ {
+		p.eat()
+	}
+	decl := *p.parse_function_decl(false, true, shorthand)
 	name := decl.name
-	// decl.anonymous = true
+	if kind != "" && decl.async {
+		p.throwSyntaxError("an accessor cannot be async:" + SourceLog(pos.line, pos.col, pos.count, p.sourcePath, ""))
+	}
+	if kind == "get" && len(decl.params) != 0 || kind == "set" && len(decl.params) != 1 {
+		count := map[string]string{"get": "no parameters", "set": "exactly one parameter"}[kind]
+		p.throwSyntaxError("a " + kind + "ter must have " + count + ":" + SourceLog(pos.line, pos.col, pos.count, p.sourcePath, ""))
+	}
 	return &ClassMethod{
 		private: private,
 		static:  static,
+		kind:    kind,
 		name:    name,
 		decl:    decl,
 		Pos:     pos,
@@ -1359,6 +1662,10 @@ func (p *Parser) parse_class_prop() (*ClassProperty, bool) {
 		private = p.at(tk_len).typ == "private"
 		tk_len++
 	}
+	if p.at(tk_len).typ == "static" {
+		static = true
+		tk_len++
+	}
 	if is_value(p.at(tk_len).typ, "default") {
 		_default = true
 		tk_len++
@@ -1401,18 +1708,142 @@ func (p *Parser) parse_label() Node {
 	tk := p.expect(TokenType["Label"])
 	tk.src = strings.Replace(strings.ReplaceAll(tk.src, " ", ""), ":>", "", 1)
 	pos := getPosofToken(tk)
+	for _, label := range p.labels {
+		if label.name == tk.src {
+			p.throwSyntaxError("the label " + tk.src + " has already been declared in this function:" +
+				SourceLog(pos.line, pos.col, pos.count, p.sourcePath, ""))
+		}
+	}
+	label := &ParsedLabel{
+		name:   tk.src,
+		loop:   is_value(p.at(0).typ, "while", "do", "for"),
+		block:  p.block(),
+		active: true,
+		Pos:    pos,
+	}
+	p.labels = append(p.labels, label)
+	var body Node
+	if p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
+		body = p.parse_stmt()
+	}
+	label.active = false
+	switch loop := body.(type) {
+	case *WhileLoop:
+		loop.label = tk.src
+	case *ForLoop:
+		loop.label = tk.src
+	case *ForIteratorLoop:
+		loop.label = tk.src
+	}
 	return &Label{
 		name: tk.src,
+		body: body,
 		Pos:  pos,
 	}
 }
 
+// parses the label after break or continue, it has to be on the same line
+func (p *Parser) parse_jump_label(keyword Token, loop bool) string {
+	if p.at(0).typ != TokenType["Identifier"] || p.at(0).line != keyword.line {
+		return ""
+	}
+	tk := p.eat()
+	for _, label := range p.labels {
+		if label.name == tk.src && label.active {
+			if loop && !label.loop {
+				line, col, count := p.getTkPos(tk)
+				p.throwSyntaxError("continue: the label " + tk.src + " does not label a loop:" +
+					SourceLog(line, col, count, p.sourcePath, ""))
+			}
+			return tk.src
+		}
+	}
+	line, col, count := p.getTkPos(tk)
+	p.throwSyntaxError(keyword.src + ": there is no statement labelled " + tk.src + " around it in this function:" +
+		SourceLog(line, col, count, p.sourcePath, ""))
+	return ""
+}
+
 func (p *Parser) parse_continue_stmt() Node {
-	return &ContinueStmt{getPosofToken(p.expect("continue"))}
+	tk := p.expect("continue")
+	stmt := &ContinueStmt{p.parse_jump_label(tk, true), getPosofToken(tk)}
+	p.eatSemiColon()
+	return stmt
 }
 
 func (p *Parser) parse_break_stmt() Node {
-	return &BreakStmt{getPosofToken(p.expect("break"))}
+	tk := p.expect("break")
+	stmt := &BreakStmt{p.parse_jump_label(tk, false), getPosofToken(tk)}
+	p.eatSemiColon()
+	return stmt
+}
+
+func (p *Parser) parse_goto_stmt() Node {
+	pos := getPosofToken(p.expect("goto"))
+	tk := p.expect(TokenType["Identifier"])
+	p.eatSemiColon()
+	p.gotos = append(p.gotos, ParsedGoto{
+		name:   tk.src,
+		blocks: slices.Clone(p.blocks),
+		Pos:    getPosofToken(tk),
+	})
+	return &GotoStmt{
+		label: Label{name: tk.src, Pos: getPosofToken(tk)},
+		Pos:   pos,
+	}
+}
+
+// go <call>, the call runs as a task of its own
+func (p *Parser) parse_go_stmt() Node {
+	tk := p.expect("go")
+	pos := getPosofToken(tk)
+	call, ok := p.parse_expr().(*CallExpr)
+	if !ok {
+		line, col, count := p.getTkPos(tk)
+		p.throwSyntaxError("go expects a function call:" + SourceLog(line, col, count, p.sourcePath, ""))
+	}
+	p.eatSemiColon()
+	return &GoStmt{call, pos}
+}
+
+// the block the statement being parsed is in, 0 at the top level
+func (p *Parser) block() int {
+	if len(p.blocks) == 0 {
+		return 0
+	}
+	return p.blocks[len(p.blocks)-1]
+}
+
+// a goto statement jumps to a label of the same function, declared in
+// its block or in a block around it
+func (p *Parser) checkGotos() {
+	for _, _goto := range p.gotos {
+		declared, reachable := false, false
+		for _, label := range p.labels {
+			if label.name == _goto.name {
+				declared = true
+				reachable = label.block == 0 || slices.Contains(_goto.blocks, label.block)
+			}
+		}
+		if !declared {
+			p.throwSyntaxError("goto: the label " + _goto.name + " is not declared in this function:" +
+				SourceLog(_goto.line, _goto.col, _goto.count, p.sourcePath, ""))
+		}
+		if !reachable {
+			p.throwSyntaxError("goto: cannot jump into the block of the label " + _goto.name + ":" +
+				SourceLog(_goto.line, _goto.col, _goto.count, p.sourcePath, ""))
+		}
+	}
+}
+
+// parses the body of a function, its labels are its own
+func (p *Parser) parse_function_body(generator bool) []Node {
+	labels, gotos, inGenerator := p.labels, p.gotos, p.generator
+	p.labels, p.gotos, p.generator = nil, nil, generator
+	body := p.parse_block()
+	p.checkGotos()
+	p.labels, p.gotos, p.generator = labels, gotos, inGenerator
+	return body
 }
 
 func (p *Parser) parse_return_stmt() *ReturnStmt {
@@ -1441,6 +1872,12 @@ func (p *Parser) parse_function_decl(expr bool, method bool, no_leading_keyword
 			p.expect("function")
 		}
 	}
+	// function* name() and *name() in classes
+	generator := false
+	if p.at(0).typ == TokenType["BinaryOp"] && p.at(0).src == "*" {
+		generator = true
+		p.eat()
+	}
 	var name DynamicNode
 	anonymous := false
 	if method {
@@ -1481,10 +1918,11 @@ func (p *Parser) parse_function_decl(expr bool, method bool, no_leading_keyword
 	}
 	pos := getPosofToken(tk)
 	params := p.parse_args(true)
-	body := p.parse_block()
+	body := p.parse_function_body(generator)
 	return &FunctionDecl{
 		name:      name,
 		async:     async,
+		generator: generator,
 		_type:     "",
 		body:      body,
 		params:    params,
@@ -1533,12 +1971,19 @@ func (p *Parser) parse_restorspread_expr() Node {
 
 func (p *Parser) parse_for_loop() Node {
 	pos := getPosofToken(p.expect("for"))
+	await := false
+	if p.at(0).typ == "await" {
+		p.eat()
+		await = true
+	}
 	p.expect(TokenType["OpenParen"])
 	if is_value(p.at(0).typ, "spawn", "static", "immortal", "var") {
-		return p.parse_for_iterators_loop(pos)
-	} else {
-		return p.parse_traditional_for_loop(pos)
+		return p.parse_for_iterators_loop(pos, await)
+	} else if await {
+		p.throwSyntaxError("for await loops iterate over the values of an iterable, like for await (spawn value of iterable):" +
+			SourceLog(pos.line, pos.col, pos.count, p.sourcePath, ""))
 	}
+	return p.parse_traditional_for_loop(pos)
 }
 
 func (p *Parser) parse_traditional_for_loop(pos Pos) Node {
@@ -1558,7 +2003,7 @@ func (p *Parser) parse_traditional_for_loop(pos Pos) Node {
 	}
 }
 
-func (p *Parser) parse_for_iterators_loop(pos Pos) Node {
+func (p *Parser) parse_for_iterators_loop(pos Pos, await bool) Node {
 	keyword := p.eat() // keyword
 	if !is_value(keyword.typ, "spawn", "var") {
 		p.expect(TokenType["Spawn"])
@@ -1584,6 +2029,10 @@ func (p *Parser) parse_for_iterators_loop(pos Pos) Node {
 	if !is_value(p.at(0).typ, "of", "in") {
 		p.throwUnexpectedTokenError(p.at(0))
 	}
+	if await && p.at(0).typ != "of" {
+		p.throwSyntaxError("for await loops iterate over the values of an iterable, use of instead of in:" +
+			SourceLog(p.at(0).line, p.at(0).col, len(p.at(0).src), p.sourcePath, ""))
+	}
 	op := p.eat().src
 	right := p.parse_nested_expr()
 	p.expect(TokenType["CloseParen"])
@@ -1593,6 +2042,7 @@ func (p *Parser) parse_for_iterators_loop(pos Pos) Node {
 		right: right,
 		_type: _type,
 		op:    op,
+		await: await,
 		body:  body,
 		Pos:   pos,
 	}
@@ -1721,6 +2171,9 @@ func (p *Parser) parse_if_stmt() *IfStmt {
 
 func (p *Parser) parse_block() []Node {
 	p.expect(TokenType["OpenBrace"])
+	p.blockCount++
+	p.blocks = append(p.blocks, p.blockCount)
+	defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
 	block := []Node{}
 	for p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
 		block = append(block, p.parse_stmt())
@@ -1786,6 +2239,9 @@ func (p *Parser) parse_decl_expr() *AssignmentExpr {
 
 // #region Expressions
 func (p *Parser) parse_globalThis() Node {
+	if p.at(0).typ == "yield" {
+		return p.parse_yield_expr()
+	}
 	if p.at(0).typ != "globalThis" {
 		return p.parse_top_expr()
 	}
@@ -1804,6 +2260,8 @@ func getPosFromNode(node Node) Pos {
 		pos = l.Pos
 	case *Number:
 		pos = l.Pos
+	case *BigInt:
+		pos = l.Pos
 	case *String:
 		pos = l.Pos
 	case *BinaryExpr:
@@ -1844,6 +2302,8 @@ func getPosFromNode(node Node) Pos {
 		pos = l.Pos
 	case *FunctionDecl:
 		pos = l.Pos
+	case *GoStmt:
+		pos = l.Pos
 	case *GotoStmt:
 		pos = l.Pos
 	case *GroupingExpr:
@@ -1868,6 +2328,16 @@ func getPosFromNode(node Node) Pos {
 		pos = l.Pos
 	case *SuperExpr:
 		pos = l.Pos
+	case *SuperRef:
+		pos = l.Pos
+	case *OptionalChain:
+		pos = l.Pos
+	case *BitwiseNotExpr:
+		pos = l.Pos
+	case *Pattern:
+		pos = l.Pos
+	case *StaticBlock:
+		pos = l.Pos
 	case *ThrowStmt:
 		pos = l.Pos
 	case *TryCatch:
@@ -1900,6 +2370,12 @@ func getPosFromNode(node Node) Pos {
 		pos = l.Pos
 	case *TemplateString:
 		pos = l.Pos
+	case *TaggedTemplate:
+		pos = l.Pos
+	case *RegExpLiteral:
+		pos = l.Pos
+	case *YieldExpr:
+		pos = l.Pos
 	case *InstanceofExpr:
 		pos = l.Pos
 	case *TernaryExpr:
@@ -1924,6 +2400,9 @@ func (p *Parser) parse_nested_expr() Node {
 }
 
 func (p *Parser) parse_assignment_expr() Node {
+	if p.at(0).typ == "yield" {
+		return p.parse_yield_expr()
+	}
 	left := p.parse_top_expr()
 	pos := getPosFromNode(left)
 	if p.at(0).typ != TokenType["AssignmentOp"] {
@@ -1934,6 +2413,25 @@ func (p *Parser) parse_assignment_expr() Node {
 	return &AssignmentExpr{left, right, op.src, pos}
 }
 
+// yield, yield value or yield* iterable
+func (p *Parser) parse_yield_expr() Node {
+	tk := p.eat()
+	if !p.generator {
+		p.throwSyntaxError("illegal use of the yield keyword, yield expressions can only be used in the body of generator functions:" +
+			SourceLog(tk.line, tk.col, len(tk.src), p.sourcePath, ""))
+	}
+	expr := &YieldExpr{Pos: getPosofToken(tk)}
+	if p.at(0).typ == TokenType["BinaryOp"] && p.at(0).src == "*" {
+		p.eat()
+		expr.delegate = true
+	}
+	if expr.delegate || !is_value(p.at(0).typ, TokenType["CloseParen"], TokenType["CloseBracket"], TokenType["CloseBrace"],
+		TokenType["Comma"], TokenType["SemiColon"], TokenType["Colon"], TokenType["EOF"]) {
+		expr.operand = p.parse_nested_expr()
+	}
+	return expr
+}
+
 func (p *Parser) parse_top_expr() Node {
 	return p.parse_ternary_expr()
 }
@@ -1965,13 +2463,22 @@ func (p *Parser) parse_match_expr() Node {
 	p.expect(TokenType["OpenBrace"])
 	for p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
 		_case := Match{}
-		_case.match = p.parse_nested_expr()
+		if p.at(0).typ == "default" {
+			_case.pattern = &Pattern{kind: "wildcard", Pos: getPosofToken(p.eat())}
+		} else {
+			_case.pattern = p.parse_pattern(true)
+		}
+		if p.at(0).typ == "if" {
+			p.eat()
+			_case.guard = p.parse_nested_expr()
+		}
 		p.expect(TokenType["Arrow"])
 		if p.at(0).typ == TokenType["OpenBrace"] {
 			_case.body = p.parse_block_stmt()
 		} else {
 			_case.body = p.parse_nested_expr()
 		}
+		p.eatComma()
 		cases = append(cases, _case)
 	}
 	p.expect(TokenType["CloseBrace"])
@@ -1982,9 +2489,187 @@ func (p *Parser) parse_match_expr() Node {
 	}
 }
 
+// alternative ("|" alternative)* ["as" name]; an arm's own pattern is top, at the top
+// an expression is compared with the value, in a destructuring pattern only a literal is
+func (p *Parser) parse_pattern(top bool) *Pattern {
+	pos := getPosofToken(p.at(0))
+	pattern := p.parse_pattern_alternative(top)
+	if p.atOperator("|") {
+		pattern = &Pattern{
+			kind:     "or",
+			patterns: []*Pattern{pattern},
+			Pos:      pos,
+		}
+		for p.atOperator("|") {
+			p.eat()
+			pattern.patterns = append(pattern.patterns, p.parse_pattern_alternative(top))
+		}
+	}
+	if p.at(0).typ == "as" {
+		p.eat()
+		if pattern.bind != "" {
+			pattern = &Pattern{kind: "or", patterns: []*Pattern{pattern}, Pos: pos}
+		}
+		pattern.bind = p.expect(TokenType["Identifier"]).src
+	}
+	return pattern
+}
+
+func (p *Parser) parse_pattern_alternative(top bool) *Pattern {
+	tk := p.at(0)
+	pos := getPosofToken(tk)
+	switch {
+	case tk.typ == TokenType["Identifier"] && tk.src == "_":
+		p.eat()
+		return &Pattern{kind: "wildcard", Pos: pos}
+	case tk.typ == TokenType["OpenBrace"]:
+		return p.parse_object_pattern(&Pattern{kind: "object", Pos: pos})
+	case tk.typ == TokenType["OpenBracket"]:
+		return p.parse_array_pattern()
+	case tk.typ == "typeof" && p.at(1).typ == TokenType["String"]:
+		p.eat()
+		return &Pattern{kind: "type", typ: p.eat().src, Pos: pos}
+	case tk.typ == TokenType["Identifier"] && p.at(1).typ == TokenType["OpenBrace"]:
+		// Class { properties }
+		class := &Identifier{p.eat().src, pos}
+		return p.parse_object_pattern(&Pattern{kind: "class", value: class, Pos: pos})
+	case tk.typ == "..":
+		return p.parse_range_pattern(nil, top, pos)
+	case tk.typ == TokenType["Identifier"] && !isLiteralName(tk.src) && (!top || p.endsPattern(1)):
+		// a lone identifier binds the value, (identifier) compares it with the variable
+		p.eat()
+		return &Pattern{kind: "wildcard", bind: tk.src, Pos: pos}
+	}
+	value := p.parse_pattern_value(top)
+	if p.at(0).typ == ".." {
+		return p.parse_range_pattern(value, top, pos)
+	}
+	return &Pattern{kind: "value", value: value, Pos: pos}
+}
+
+// reports whether the token at offset is one that can follow a pattern
+func (p *Parser) endsPattern(offset uint) bool {
+	tk := p.at(offset)
+	return tk.typ == TokenType["BinaryOp"] && tk.src == "|" || is_value(tk.typ, TokenType["Arrow"], "if", "as",
+		TokenType["Comma"], TokenType["CloseBracket"], TokenType["CloseBrace"])
+}
+
+// true, false, null and undefined are compared in destructuring patterns rather than bound
+func isLiteralName(name string) bool {
+	return is_value(name, "true", "false", "null", "undefined")
+}
+
+// the value of a value pattern or the bound of a range: any expression at the top,
+// a literal or a parenthesised expression in a destructuring pattern
+func (p *Parser) parse_pattern_value(top bool) Node {
+	tk := p.at(0)
+	if tk.typ == TokenType["OpenParen"] {
+		// not the parameters of an arrow function, (limit) => ...
+		p.eat()
+		value := p.parse_nested_expr()
+		p.expect(TokenType["CloseParen"])
+		return value
+	}
+	if top {
+		inPattern := p.inPattern
+		p.inPattern = true
+		value := p.parse_nested_expr()
+		p.inPattern = inPattern
+		return value
+	}
+	switch {
+	case is_value(tk.typ, TokenType["Number"], TokenType["BigInt"], TokenType["String"], TokenType["TString"]),
+		tk.typ == TokenType["Identifier"] && isLiteralName(tk.src):
+		return p.parse_primary_expr()
+	}
+	p.throwSyntaxError("invalid pattern, expected a literal, an identifier, a destructuring pattern or _:" +
+		SourceLog(tk.line, tk.col, len(tk.src), p.sourcePath, ""))
+	return nil
+}
+
+// from..to (to excluded) or from..=to, either bound may be left out
+func (p *Parser) parse_range_pattern(from Node, top bool, pos Pos) *Pattern {
+	inclusive := p.expect("..").src == "..="
+	var to Node
+	if !p.endsPattern(0) {
+		to = p.parse_pattern_value(top)
+	}
+	if from == nil && to == nil || inclusive && to == nil {
+		p.throwSyntaxError("invalid range pattern, expected an upper bound:" +
+			SourceLog(pos.line, pos.col, pos.count, p.sourcePath, ""))
+	}
+	return &Pattern{
+		kind:      "range",
+		value:     from,
+		to:        to,
+		inclusive: inclusive,
+		Pos:       pos,
+	}
+}
+
+// { key, key: pattern, "key": pattern, ...rest }
+func (p *Parser) parse_object_pattern(pattern *Pattern) *Pattern {
+	p.expect(TokenType["OpenBrace"])
+	for p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
+		if p.at(0).typ == "..." {
+			p.eat()
+			pattern.hasRest = true
+			pattern.rest = p.expect(TokenType["Identifier"]).src
+		} else {
+			tk := p.at(0)
+			if !is_value(tk.typ, TokenType["Identifier"], TokenType["String"], TokenType["Number"]) {
+				p.throwUnexpectedTokenError(tk)
+			}
+			p.eat()
+			var property *Pattern
+			if p.at(0).typ == TokenType["Colon"] {
+				p.eat()
+				property = p.parse_pattern(false)
+			} else if tk.typ == TokenType["Identifier"] {
+				// { key } binds key
+				property = &Pattern{kind: "wildcard", bind: tk.src, Pos: getPosofToken(tk)}
+			} else {
+				p.expect(TokenType["Colon"])
+			}
+			pattern.keys = append(pattern.keys, tk.src)
+			pattern.patterns = append(pattern.patterns, property)
+		}
+		if p.at(0).typ != TokenType["CloseBrace"] {
+			p.expect(TokenType["Comma"])
+		}
+	}
+	p.expect(TokenType["CloseBrace"])
+	return pattern
+}
+
+// [pattern, pattern, ...rest]
+func (p *Parser) parse_array_pattern() *Pattern {
+	pattern := &Pattern{kind: "array", Pos: getPosofToken(p.expect(TokenType["OpenBracket"]))}
+	for p.not_eof() && p.at(0).typ != TokenType["CloseBracket"] {
+		if p.at(0).typ == "..." {
+			p.eat()
+			pattern.hasRest = true
+			// [first, ..._] only checks the length
+			if rest := p.expect(TokenType["Identifier"]).src; rest != "_" {
+				pattern.rest = rest
+			}
+		} else {
+			if pattern.hasRest {
+				p.throwUnexpectedTokenError(p.at(0))
+			}
+			pattern.patterns = append(pattern.patterns, p.parse_pattern(false))
+		}
+		if p.at(0).typ != TokenType["CloseBracket"] {
+			p.expect(TokenType["Comma"])
+		}
+	}
+	p.expect(TokenType["CloseBracket"])
+	return pattern
+}
+
 func (p *Parser) parse_from_expr() Node {
 	if p.at(0).typ != "from" {
-		return p.parse_logical_expr()
+		return p.parse_nullish_expr()
 	}
 	pos := getPosofToken(p.eat())
 	path := p.expect(TokenType["String"]).src
@@ -1994,6 +2679,22 @@ func (p *Parser) parse_from_expr() Node {
 	}
 }
 
+// a ?? b
+func (p *Parser) parse_nullish_expr() Node {
+	left := p.parse_logical_expr()
+	for p.at(0).typ == "??" {
+		p.eat()
+		right := p.parse_logical_expr()
+		left = &LogicalExpr{
+			left:  left,
+			right: right,
+			op:    "??",
+			Pos:   getPosFromNode(left),
+		}
+	}
+	return left
+}
+
 func (p *Parser) parse_logical_expr() Node {
 	if p.at(0).src == "!" {
 		tk := p.eat()
@@ -2007,7 +2708,7 @@ func (p *Parser) parse_logical_expr() Node {
 			Pos:   pos,
 		}
 	}
-	left := p.parse_instanceof_expr()
+	left := p.parse_bitwise_expr(0)
 	if p.at(0).typ != TokenType["LogicalOp"] {
 		return left
 	}
@@ -2022,6 +2723,34 @@ func (p *Parser) parse_logical_expr() Node {
 	}
 }
 
+// the bitwise operators, from the one that binds the loosest
+var bitwiseOps = []string{"|", "^", "&"}
+
+// a | b, a ^ b and a & b
+func (p *Parser) parse_bitwise_expr(level int) Node {
+	if level == len(bitwiseOps) {
+		return p.parse_instanceof_expr()
+	}
+	op := bitwiseOps[level]
+	left := p.parse_bitwise_expr(level + 1)
+	for p.atOperator(op) && !(op == "|" && p.inPattern) {
+		p.eat()
+		right := p.parse_bitwise_expr(level + 1)
+		left = &BinaryExpr{
+			left,
+			right,
+			op,
+			getPosFromNode(left),
+		}
+	}
+	return left
+}
+
+// reports whether the current token is the binary operator op
+func (p *Parser) atOperator(op string) bool {
+	return p.at(0).typ == TokenType["BinaryOp"] && p.at(0).src == op
+}
+
 func (p *Parser) parse_instanceof_expr() Node {
 	left := p.parse_super_expr()
 	if p.NotAt("instanceof") {
@@ -2037,7 +2766,8 @@ func (p *Parser) parse_instanceof_expr() Node {
 }
 
 func (p *Parser) parse_super_expr() Node {
-	if p.at(0).typ != "super" {
+	// super.method() is parsed by parse_primary_expr
+	if p.at(0).typ != "super" || p.at(1).typ != TokenType["OpenParen"] {
 		return p.parse_await_expr()
 	}
 	pos := getPosofToken(p.eat())
@@ -2080,12 +2810,34 @@ func (p *Parser) parse_new_expr() Node {
 	}
 	pos := getPosofToken(p.eat())
 	operand := p.parse_class_expr()
+	if node, ok := bindNew(operand, pos); ok {
+		return node
+	}
 	return &NewExpr{
 		operand: operand,
 		Pos:     pos,
 	}
 }
 
+// new binds to the first call of a member chain,
+// new Promise(fn).then(cb) is (new Promise(fn)).then(cb)
+func bindNew(node Node, pos Pos) (Node, bool) {
+	switch node := node.(type) {
+	case *CallExpr:
+		if caller, ok := bindNew(node.caller, pos); ok {
+			node.caller = caller
+			return node, true
+		}
+		return &NewExpr{operand: node, Pos: pos}, true
+	case *MemberExpr:
+		if object, ok := bindNew(node.object, pos); ok {
+			node.object = object
+			return node, true
+		}
+	}
+	return node, false
+}
+
 func (p *Parser) parse_class_expr() Node {
 	if p.NotAt("class") {
 		return p.parse_fn_expr()
@@ -2094,12 +2846,27 @@ func (p *Parser) parse_class_expr() Node {
 }
 
 func (p *Parser) parse_fn_expr() Node {
-	if p.at(0).typ != "function" {
+	if p.at(0).typ == "async" && p.at(1).typ != "function" {
+		return p.parse_async_arrow_fn()
+	}
+	if p.at(0).typ != "function" && p.at(0).typ != "async" {
 		return p.parse_in_expr()
 	}
 	return p.parse_function_decl(true, false, false)
 }
 
+// async (params) => { body }
+func (p *Parser) parse_async_arrow_fn() Node {
+	tk := p.eat() // async
+	fn, ok := p.parse_in_expr().(*FunctionDecl)
+	if !ok || fn._type != "arrow" {
+		p.throwSyntaxError("the async keyword must be followed by a function or an arrow function: " +
+			SourceLog(tk.line, tk.col, len(tk.src), p.sourcePath, ""))
+	}
+	fn.async = true
+	return fn
+}
+
 func (p *Parser) parse_in_expr() Node {
 	left := p.parse_comparison_expr()
 	if p.at(0).typ != "in" {
@@ -2112,7 +2879,7 @@ func (p *Parser) parse_in_expr() Node {
 }
 
 func (p *Parser) parse_comparison_expr() Node {
-	left := p.parse_additive_expr()
+	left := p.parse_shift_expr()
 	if p.at(0).typ != TokenType["ComparisonOp"] {
 		return left
 	}
@@ -2133,6 +2900,22 @@ func getPosofToken(token Token) Pos {
 	return Pos{line, col, count}
 }
 
+// a << b, a >> b and a >>> b
+func (p *Parser) parse_shift_expr() Node {
+	left := p.parse_additive_expr()
+	for p.atOperator("<<") || p.atOperator(">>") || p.atOperator(">>>") {
+		op := p.eat().src
+		right := p.parse_additive_expr()
+		left = &BinaryExpr{
+			left,
+			right,
+			op,
+			getPosFromNode(left),
+		}
+	}
+	return left
+}
+
 func (p *Parser) parse_additive_expr() Node {
 	left := p.parse_multiplicative_expr()
 	for p.at(0).src == "+" ||
@@ -2153,10 +2936,10 @@ func (p *Parser) parse_additive_expr() Node {
 }
 
 func (p *Parser) parse_multiplicative_expr() Node {
-	left := p.parse_member_expr()
-	for is_value(p.at(0).src, "*", "/", "%", "**") {
+	left := p.parse_exponent_expr()
+	for is_value(p.at(0).src, "*", "/", "%") {
 		op := p.eat().src
-		right := p.parse_member_expr()
+		right := p.parse_exponent_expr()
 		left = &BinaryExpr{
 			left,
 			right,
@@ -2170,11 +2953,47 @@ func (p *Parser) parse_multiplicative_expr() Node {
 	return left
 }
 
+// ** binds tighter than *, / and % and groups to the right: 2 ** 3 ** 2 is 2 ** 9
+func (p *Parser) parse_exponent_expr() Node {
+	left := p.parse_member_expr()
+	if p.at(0).src != "**" {
+		return left
+	}
+	p.eat()
+	right := p.parse_exponent_expr()
+	return &BinaryExpr{
+		left,
+		right,
+		"**",
+		getPosFromNode(left),
+	}
+}
+
 func (p *Parser) parse_member_expr() Node {
 	object := p.parse_call_expr(nil)
-	for p.not_eof() && is_value(p.at(0).typ, TokenType["Dot"], TokenType["OpenBracket"]) {
+	chain_pos := getPosFromNode(object)
+	optional_chain := false
+	for p.not_eof() && is_value(p.at(0).typ, TokenType["Dot"], TokenType["OpenBracket"], "?.") {
 		computed := false
-		tk := p.eat() // dot (.) or bracket ([)
+		optional := false
+		tk := p.eat() // dot (.), bracket ([) or ?.
+		if tk.typ == "?." {
+			optional = true
+			optional_chain = true
+			if p.at(0).typ == TokenType["OpenParen"] {
+				// f?.()
+				object = &CallExpr{
+					caller:   object,
+					args:     p.parse_args(false),
+					optional: true,
+					Pos:      getPosFromNode(object),
+				}
+				continue
+			}
+			if p.at(0).typ == TokenType["OpenBracket"] {
+				tk = p.eat()
+			}
+		}
 		if tk.typ == TokenType["OpenBracket"] {
 			computed = true
 		}
@@ -2182,6 +3001,7 @@ func (p *Parser) parse_member_expr() Node {
 		if computed {
 			property = p.parse_nested_expr()
 		} else {
+			p.keywordAsName()
 			property = p.parse_call_expr(nil)
 		}
 		call := false
@@ -2196,6 +3016,7 @@ func (p *Parser) parse_member_expr() Node {
 						property: property.caller,
 						object:   object,
 						computed: computed,
+						optional: optional,
 						Pos:      getPosFromNode(object),
 					},
 					Pos: getPosFromNode(property),
@@ -2215,16 +3036,184 @@ func (p *Parser) parse_member_expr() Node {
 				object:   object,
 				property: property,
 				computed: computed,
+				optional: optional,
 				Pos:      pos,
 			}
 		}
 		if computed {
 			p.expect(TokenType["CloseBracket"])
+			// o[key]()
+			for p.at(0).typ == TokenType["OpenParen"] {
+				object = &CallExpr{
+					caller: object,
+					args:   p.parse_args(false),
+					Pos:    getPosFromNode(object),
+				}
+			}
+		}
+	}
+	if optional_chain {
+		object = &OptionalChain{
+			expr: object,
+			Pos:  chain_pos,
+		}
+	}
+	for p.at(0).typ == TokenType["TString"] {
+		object = &TaggedTemplate{
+			tag:      object,
+			template: p.parse_template(true),
+			Pos:      getPosFromNode(object),
 		}
 	}
 	return object
 }
 
+// parses a template literal, the escapes of a tagged one may be invalid
+func (p *Parser) parse_template(tagged bool) *TemplateString {
+	tk := p.eat()
+	template := &TemplateString{Pos: getPosofToken(tk)}
+	raw := strings.ReplaceAll(tk.src, "\r\n", "\n")
+	// where the text after the back-tick starts in the file
+	line, col := tk.line, tk.col+1
+	for {
+		start := -1
+		for i := 0; i < len(raw); i++ {
+			if raw[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(raw[i:], "#{") {
+				start = i
+				break
+			}
+		}
+		text := raw
+		if start >= 0 {
+			text = raw[:start]
+		}
+		cooked, ok := cookTemplate(text)
+		if !ok && !tagged {
+			p.throwSyntaxError("invalid escape sequence in template literal:" +
+				SourceLog(tk.line, tk.col, tk.end-tk.col, p.sourcePath, ""))
+		}
+		if ok {
+			template.cooked = append(template.cooked, &cooked)
+		} else {
+			template.cooked = append(template.cooked, nil)
+		}
+		template.raw = append(template.raw, text)
+		if start < 0 {
+			return template
+		}
+		line, col = advance(line, col, raw[:start+2])
+		end := scanInterpolation(raw, start+2)
+		template.exprs = append(template.exprs, p.parse_interpolation(raw[start+2:end], line, col))
+		line, col = advance(line, col, raw[start+2:end+1])
+		raw = raw[end+1:]
+	}
+}
+
+// parses the expression of a #{} interpolation that starts at line and col
+func (p *Parser) parse_interpolation(source string, line, col int) Node {
+	tokens := Tokenize(source, p.sourcePath)
+	for i := range tokens.elements {
+		tk := &tokens.elements[i]
+		if tk.line == 1 {
+			tk.col += col - 1
+			tk.end += col - 1
+		}
+		tk.line += line - 1
+	}
+	parser := &Parser{tokens: tokens, scriptType: p.scriptType, sourcePath: p.sourcePath}
+	if !parser.not_eof() {
+		p.throwSyntaxError("empty interpolation in template literal:" +
+			SourceLog(line, col-2, 2, p.sourcePath, ""))
+	}
+	expr := parser.parse_expr()
+	if parser.not_eof() {
+		parser.throwUnexpectedTokenError(parser.at(0))
+	}
+	return expr
+}
+
+// returns the line and column after text that starts at line and col
+func advance(line, col int, text string) (int, int) {
+	if lines := strings.Count(text, "\n"); lines > 0 {
+		return line + lines, len(text) - strings.LastIndex(text, "\n")
+	}
+	return line, col + len(text)
+}
+
+// replaces the escape sequences of the text of a template literal,
+// reports false when one of them is invalid
+func cookTemplate(raw string) (string, bool) {
+	var cooked strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			cooked.WriteByte(raw[i])
+			continue
+		}
+		i++
+		if i >= len(raw) {
+			return "", false
+		}
+		switch char := raw[i]; char {
+		case 'n':
+			cooked.WriteByte('\n')
+		case 't':
+			cooked.WriteByte('\t')
+		case 'r':
+			cooked.WriteByte('\r')
+		case 'b':
+			cooked.WriteByte('\b')
+		case 'f':
+			cooked.WriteByte('\f')
+		case 'v':
+			cooked.WriteByte('\v')
+		case '\n':
+			// a line continuation
+		case '0':
+			if i+1 < len(raw) && raw[i+1] >= '0' && raw[i+1] <= '9' {
+				return "", false
+			}
+			cooked.WriteByte(0)
+		case 'x':
+			if i+2 >= len(raw) {
+				return "", false
+			}
+			code, err := strconv.ParseUint(raw[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", false
+			}
+			cooked.WriteRune(rune(code))
+			i += 2
+		case 'u':
+			hex := ""
+			if strings.HasPrefix(raw[i+1:], "{") {
+				end := strings.IndexByte(raw[i:], '}')
+				if end < 0 {
+					return "", false
+				}
+				hex = raw[i+2 : i+end]
+				i += end
+			} else if i+4 < len(raw) {
+				hex = raw[i+1 : i+5]
+				i += 4
+			}
+			code, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || code > 0x10FFFF {
+				return "", false
+			}
+			cooked.WriteRune(rune(code))
+		default:
+			if char >= '1' && char <= '9' {
+				return "", false
+			}
+			// \`, \#, \$, \\ and the others stand for themselves
+			cooked.WriteByte(char)
+		}
+	}
+	return cooked.String(), true
+}
+
 func (p *Parser) parse_call_expr(caller Node) Node {
 	if caller == nil {
 		caller = p.parse_object()
@@ -2251,6 +3240,11 @@ func (p *Parser) parse_object() Node {
 	for p.not_eof() && p.at(0).typ != TokenType["CloseBrace"] {
 		var key Node
 		dynamic_key := false
+		// *name() { ... }, a generator method
+		generator := p.at(0).typ == TokenType["BinaryOp"] && p.at(0).src == "*"
+		if generator {
+			p.eat()
+		}
 		if p.at(0).typ == TokenType["OpenBracket"] {
 			p.eat()
 			key = p.parse_top_expr()
@@ -2270,8 +3264,15 @@ func (p *Parser) parse_object() Node {
 			}
 		}
 		var value Node
-		if p.IsAt(TokenType["OpenParen"]) {
-			decl := p.parse_function_decl(false, false, true)
+		if generator || p.IsAt(TokenType["OpenParen"]) {
+			var decl *FunctionDecl
+			if generator {
+				decl = &FunctionDecl{generator: true, Pos: getPosofToken(p.at(0))}
+				decl.params = p.parse_args(true)
+				decl.body = p.parse_function_body(true)
+			} else {
+				decl = p.parse_function_decl(false, false, true)
+			}
 			if dynamic_key {
 				decl.name.dynamic = true
 			}
@@ -2328,8 +3329,9 @@ func (p *Parser) parse_primary_expr() Node {
 	pos := getPosofToken(p.at(0))
 	switch p.at(0).typ {
 	case TokenType["Number"]:
-		float, _ := strconv.ParseFloat(p.eat().src, 64)
-		return &Number{float, pos}
+		return &Number{parseNumberLiteral(p.eat().src), pos}
+	case TokenType["BigInt"]:
+		return &BigInt{parseBigIntLiteral(p.eat().src), pos}
 	case TokenType["String"]:
 		return &String{p.eat().src, pos}
 	case TokenType["Identifier"]:
@@ -2344,6 +3346,9 @@ func (p *Parser) parse_primary_expr() Node {
 			}
 		}
 		return expr
+	case "super":
+		p.eat()
+		return &SuperRef{pos}
 	case "typeof":
 		pos := getPosofToken(p.eat())
 		operand := p.parse_object()
@@ -2352,6 +3357,10 @@ func (p *Parser) parse_primary_expr() Node {
 		pos := getPosofToken(p.eat())
 		operand := p.parse_object()
 		return &VoidExpr{operand, pos}
+	case "~":
+		pos := getPosofToken(p.eat())
+		operand := p.parse_member_expr()
+		return &BitwiseNotExpr{operand, pos}
 	case TokenType["IncreOp"], TokenType["DecreOp"]:
 		var op string = p.eat().src // (++ | --)
 		var operand Node = p.parse_nested_expr()
@@ -2367,7 +3376,7 @@ func (p *Parser) parse_primary_expr() Node {
 			p.at(1).typ == TokenType["Arrow"] {
 			p.eat() // )
 			p.eat() //=>
-			body := p.parse_block()
+			body := p.parse_function_body(false)
 			return &FunctionDecl{
 				name: struct {
 					dynamic bool
@@ -2389,7 +3398,7 @@ func (p *Parser) parse_primary_expr() Node {
 		p.expect(TokenType["CloseParen"])
 		if p.at(0).typ == TokenType["Arrow"] {
 			p.eat()
-			body := p.parse_block()
+			body := p.parse_function_body(false)
 			return &FunctionDecl{
 				name: struct {
 					dynamic bool
@@ -2434,39 +3443,17 @@ func (p *Parser) parse_primary_expr() Node {
 		}
 		return member
 	case TokenType["TString"]:
-		str := []Node{}
+		return p.parse_template(false)
+	case TokenType["RegExp"]:
 		tk := p.eat()
-		exp := regexp.MustCompile(`(\#\{)`)
-		locs := exp.FindAllStringIndex(tk.src, -1)
-		substrs := []string{}
-		for i := 0; i < len(locs); i++ {
-			loc := locs[i]
-			exp := regexp.MustCompile(`(\})`)
-			to := exp.FindStringIndex(tk.src)[0]
-			from := tk.src[loc[0]+2:]
-			substrs = append(substrs, from[:to])
-		}
-		new_string := tk.src
-		for i := 0; i < len(substrs); i++ {
-			substr := substrs[i]
-			new_string = strings.Replace(new_string, substr, "#{...}", 1)
-		}
-		exp2 := regexp.MustCompile(`(\#\{\.\.\.\})`)
-		locs2 := exp2.FindAllStringIndex(tk.src, -1)
-		pos2 := 0
-		for i := pos2; i < len(locs2); i++ {
-			upto := locs2[i][0]
-			pos2 = locs2[i][1]
-			str = append(str, &String{
-				Value: new_string[upto:],
-				Pos:   pos,
-			})
-		}
-		println(str)
-		return &TemplateString{
-			Pos: pos,
-			str: str,
+		end := strings.LastIndex(tk.src, "/")
+		literal := &RegExpLiteral{tk.src[1:end], tk.src[end+1:], pos}
+		// invalid patterns are reported before the program runs
+		if _, err := compileRegExp(literal.pattern, literal.flags); err != "" {
+			p.throwSyntaxError("invalid regular expression " + tk.src + ": " + err +
+				SourceLog(tk.line, tk.col, tk.end-tk.col, p.sourcePath, ""))
 		}
+		return literal
 
 	default:
 		p.throwUnexpectedTokenError(p.at(0))
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"path"
 	"path/filepath"
 	"strings"
 )
@@ -21,7 +22,7 @@ func RealPath(path string) string {
 }
 
 func IsAbs(path string) bool {
-	return filepath.IsAbs(path)
+	return IsStdPath(path) || filepath.IsAbs(path)
 }
 
 func RelativePath(base, target string) string {
@@ -39,17 +40,24 @@ func RelativePath(base, target string) string {
 }
 
 func AbsPath(path string) string {
+	if IsStdPath(path) {
+		return path
+	}
 	target, _ := filepath.Abs(path)
 	return target
 }
 
+// resolves target relative to the directory of file
 func RelativePathToFile(file, target string) string {
-	file_path := AbsPath(file)
-	index := strings.LastIndex(file_path, "\\")
-	substr := ""
-	for i := 0; i <= index; i++ {
-		ch := file_path[i]
-		substr += string(ch)
-	}
-	return substr + filepath.Clean(target)
+	if IsStdPath(target) {
+		return target
+	}
+	if IsAbs(target) {
+		return filepath.Clean(target)
+	}
+	if IsStdPath(file) {
+		// a file next to file in the standard library
+		return STD_PREFIX + path.Join(path.Dir(strings.TrimPrefix(file, STD_PREFIX)), filepath.ToSlash(target))
+	}
+	return filepath.Join(filepath.Dir(AbsPath(file)), target)
 }
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// RegExpState is the native side of an instance of the stdlib RegExp class,
+// stored in its #state field. Patterns run on Go's regexp package (RE2), so
+// the syntax it has no support for, backreferences and lookarounds, is rejected.
+type RegExpState struct {
+	source string
+	flags  string
+	global bool
+	sticky bool
+	// runs the pattern from the start of a string
+	re *regexp.Regexp
+	// runs it after the code point it starts with, a search that starts inside
+	// a string runs this from the code point before so that ^ and \b see it
+	after *regexp.Regexp
+}
+
+// compiles pattern, err is a message saying why it is invalid
+func compileRegExp(pattern, flags string) (*RegExpState, string) {
+	state := &RegExpState{source: pattern, flags: flags}
+	modes := ""
+	for _, flag := range flags {
+		if strings.Count(flags, string(flag)) > 1 {
+			return nil, fmt.Sprintf("duplicate flag '%c'", flag)
+		}
+		switch flag {
+		case 'g':
+			state.global = true
+		case 'y':
+			state.sticky = true
+		case 'i', 'm', 's':
+			modes += string(flag)
+		case 'u':
+			// patterns always match code points
+		default:
+			return nil, fmt.Sprintf("invalid flag '%c'", flag)
+		}
+	}
+	translated, err := translatePattern(pattern)
+	if err != "" {
+		return nil, err
+	}
+	// compiled on its own first so that errors quote the pattern as it was written
+	if _, e := regexp.Compile(translated); e != nil {
+		return nil, strings.TrimPrefix(e.Error(), "error parsing regexp: ")
+	}
+	prefix := ""
+	if modes != "" {
+		prefix = "(?" + modes + ")"
+	}
+	if state.sticky {
+		// a sticky match starts where the search does
+		prefix += `\A`
+	}
+	state.re = regexp.MustCompile(prefix + "(?:" + translated + ")")
+	state.after = regexp.MustCompile(prefix + "(?s:.)(?:" + translated + ")")
+	return state, ""
+}
+
+// rewrites the escapes of pattern RE2 spells differently and reports the syntax it has no support for
+func translatePattern(pattern string) (string, string) {
+	result := strings.Builder{}
+	for i := 0; i < len(pattern); i++ {
+		char := pattern[i]
+		if char == '(' && strings.HasPrefix(pattern[i:], "(?") {
+			for _, lookaround := range []string{"(?=", "(?!", "(?<=", "(?<!"} {
+				if strings.HasPrefix(pattern[i:], lookaround) {
+					return "", "lookaround assertions like " + lookaround + ") are not supported"
+				}
+			}
+		}
+		if char != '\\' || i+1 == len(pattern) {
+			result.WriteByte(char)
+			continue
+		}
+		i++
+		switch escape := pattern[i]; {
+		case escape >= '1' && escape <= '9':
+			return "", "backreferences like \\" + pattern[i:i+1] + " are not supported"
+		case escape == 'k' && strings.HasPrefix(pattern[i+1:], "<"):
+			return "", "backreferences like \\" + pattern[i:i+1+max(strings.IndexByte(pattern[i:], '>'), 1)] + " are not supported"
+		case escape == 'u' && strings.HasPrefix(pattern[i+1:], "{"):
+			// \u{1F600}
+			end := strings.IndexByte(pattern[i:], '}')
+			if end < 0 {
+				return "", "unterminated \\u{ escape"
+			}
+			result.WriteString(`\x` + pattern[i+1:i+end+1])
+			i += end
+		case escape == 'u' && i+4 < len(pattern) && isHex(pattern[i+1:i+5]):
+			// \u00e9
+			result.WriteString(`\x{` + pattern[i+1:i+5] + "}")
+			i += 4
+		case escape == '/':
+			result.WriteByte('/')
+		default:
+			result.WriteByte('\\')
+			result.WriteByte(escape)
+		}
+	}
+	return result.String(), ""
+}
+
+func isHex(text string) bool {
+	return strings.Trim(text, "0123456789abcdefABCDEF") == ""
+}
+
+// the leftmost match of the pattern in str at or after the byte offset from, nil when there is none;
+// the indexes are byte offsets of str in the layout of regexp.FindStringSubmatchIndex
+func (state *RegExpState) find(str string, from int) []int {
+	if from > len(str) {
+		return nil
+	}
+	if from == 0 {
+		return state.re.FindStringSubmatchIndex(str)
+	}
+	_, size := utf8.DecodeLastRuneInString(str[:from])
+	before := from - size
+	loc := state.after.FindStringSubmatchIndex(str[before:])
+	if loc == nil {
+		return nil
+	}
+	// the match starts after the code point (?s:.) matched
+	_, size = utf8.DecodeRuneInString(str[before+loc[0]:])
+	loc[0] += size
+	for i := range loc {
+		if loc[i] >= 0 {
+			loc[i] += before
+		}
+	}
+	return loc
+}
+
+// the byte offset after the code point at offset of str, where a search goes on after an empty match
+func nextCodePoint(str string, offset int) int {
+	if offset >= len(str) {
+		return offset + 1
+	}
+	_, size := utf8.DecodeRuneInString(str[offset:])
+	return offset + size
+}
+
+// every match of the pattern in str, from the start of it
+func (state *RegExpState) findAll(str string) [][]int {
+	matches := [][]int{}
+	for from := 0; ; {
+		loc := state.find(str, from)
+		if loc == nil {
+			return matches
+		}
+		matches = append(matches, loc)
+		from = loc[1]
+		if loc[0] == loc[1] {
+			from = nextCodePoint(str, from)
+		}
+	}
+}
+
+// the groups loc holds, undefined for the ones that did not take part in the match
+func (state *RegExpState) captures(str string, loc []int) []RuntimeVal {
+	captures := make([]RuntimeVal, len(loc)/2)
+	for i := range captures {
+		if loc[2*i] < 0 {
+			captures[i] = undefined
+		} else {
+			captures[i] = MK_STRING(str[loc[2*i]:loc[2*i+1]])
+		}
+	}
+	return captures
+}
+
+// the named groups of a match as an object, undefined when the pattern has no named groups
+func (state *RegExpState) groups(captures []RuntimeVal, r *Interpreter) RuntimeVal {
+	keys, values := []string{}, []RuntimeVal{}
+	for i, name := range state.re.SubexpNames() {
+		if name != "" {
+			keys = append(keys, name)
+			values = append(values, captures[i])
+		}
+	}
+	if len(keys) == 0 {
+		return undefined
+	}
+	return MK_RECORD(r, keys, values...)
+}
+
+// what exec returns for the match at loc: its text, its index, the captures
+// with the whole match first, the named groups and the string searched
+func (state *RegExpState) result(str string, loc []int, r *Interpreter) RuntimeVal {
+	captures := state.captures(str, loc)
+	return MK_RECORD(r, []string{"value", "index", "captures", "groups", "input"},
+		captures[0], MK_NUMBER(float64(runeIndex(str, loc[0]))), MK_ARRAY(captures...), state.groups(captures, r), MK_STRING(str))
+}
+
+// the next match of regexp in str: global and sticky regexps search from their lastIndex
+// and move it past the match, or back to 0 when there is none; nil when nothing matches
+func (state *RegExpState) exec(regexp *Instance, str string) []int {
+	if !state.global && !state.sticky {
+		return state.find(str, 0)
+	}
+	lastIndex := 0
+	if n, ok := GetInstanceMember(regexp, "lastIndex").(*NumberVal); ok && n.value > 0 {
+		lastIndex = int(n.value)
+	}
+	var loc []int
+	if lastIndex <= utf8.RuneCountInString(str) {
+		loc = state.find(str, byteOffset(str, lastIndex))
+	}
+	if loc == nil {
+		SetInstanceMember(regexp, "lastIndex", MK_NUMBER(0))
+		return nil
+	}
+	SetInstanceMember(regexp, "lastIndex", MK_NUMBER(float64(runeIndex(str, loc[1]))))
+	return loc
+}
+
+// expands the $ patterns of a replacement string: $$, $& (the match), $` and $'
+// (the text before and after it), $1 to $99 (a group) and $<name> (a named group)
+func (state *RegExpState) expand(replacement, str string, loc []int) string {
+	result := strings.Builder{}
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] != '$' || i+1 == len(replacement) {
+			result.WriteByte(replacement[i])
+			continue
+		}
+		switch next := replacement[i+1]; {
+		case next == '$':
+			result.WriteByte('$')
+			i++
+		case next == '&':
+			result.WriteString(str[loc[0]:loc[1]])
+			i++
+		case next == '`':
+			result.WriteString(str[:loc[0]])
+			i++
+		case next == '\'':
+			result.WriteString(str[loc[1]:])
+			i++
+		case next >= '0' && next <= '9':
+			group, length := int(next-'0'), 1
+			// $12 is group 12 when the pattern has that many
+			if i+2 < len(replacement) && replacement[i+2] >= '0' && replacement[i+2] <= '9' {
+				if two := group*10 + int(replacement[i+2]-'0'); two < len(loc)/2 {
+					group, length = two, 2
+				}
+			}
+			if group == 0 || group >= len(loc)/2 {
+				result.WriteByte('$')
+				continue
+			}
+			if loc[2*group] >= 0 {
+				result.WriteString(str[loc[2*group]:loc[2*group+1]])
+			}
+			i += length
+		case next == '<':
+			end := strings.IndexByte(replacement[i:], '>')
+			index := -1
+			if end > 0 {
+				index = state.re.SubexpIndex(replacement[i+2 : i+end])
+			}
+			if index < 0 {
+				result.WriteByte('$')
+				continue
+			}
+			if loc[2*index] >= 0 {
+				result.WriteString(str[loc[2*index]:loc[2*index+1]])
+			}
+			i += end
+		default:
+			result.WriteByte('$')
+		}
+	}
+	return result.String()
+}
+
+// replaces the first match of the pattern in str, or every one when it is global; the replacement
+// is a string with $ patterns or a function called with the match, its index, the captures
+// and the named groups
+func (r *Interpreter) replaceRegExp(state *RegExpState, regexp *Instance, str string, args []RuntimeVal, name string, env *Environment, pos Pos) string {
+	replacement := func(loc []int) string {
+		if fn, ok := argAt(args, 1).(*FunctionVal); ok {
+			captures := state.captures(str, loc)
+			rv, _ := CallFunction(fn, env, []RuntimeVal{captures[0], MK_NUMBER(float64(runeIndex(str, loc[0]))),
+				MK_ARRAY(captures[1:]...), state.groups(captures, r)}, r, pos)
+			return rv.noAnsi()
+		}
+		return state.expand(stringArg(args, 1, name, env, pos), str, loc)
+	}
+	var matches [][]int
+	if state.global {
+		matches = state.findAll(str)
+		SetInstanceMember(regexp, "lastIndex", MK_NUMBER(0))
+	} else if loc := state.exec(regexp, str); loc != nil {
+		matches = [][]int{loc}
+	}
+	result := strings.Builder{}
+	offset := 0
+	for _, loc := range matches {
+		result.WriteString(str[offset:loc[0]])
+		result.WriteString(replacement(loc))
+		offset = loc[1]
+	}
+	result.WriteString(str[offset:])
+	return result.String()
+}
+
+// splits str around the matches of the pattern, the captures of a match are added
+// between the parts it separates; an empty match does not split at the ends of str
+func (state *RegExpState) split(str string, limit float64) *ArrayVal {
+	array := MK_ARRAY()
+	push := func(value RuntimeVal) bool {
+		if float64(array.elements.length) >= limit {
+			return false
+		}
+		array.Push(value)
+		return true
+	}
+	if str == "" {
+		if state.find(str, 0) == nil {
+			push(MK_STRING(str))
+		}
+		return array
+	}
+	start := 0
+	for from := 0; from < len(str); {
+		loc := state.find(str, from)
+		if loc == nil || loc[0] >= len(str) {
+			break
+		}
+		if loc[1] == start {
+			// an empty match where the part starts
+			from = nextCodePoint(str, loc[0])
+			continue
+		}
+		if !push(MK_STRING(str[start:loc[0]])) {
+			return array
+		}
+		for _, capture := range state.captures(str, loc)[1:] {
+			if !push(capture) {
+				return array
+			}
+		}
+		start = loc[1]
+		from = start
+	}
+	push(MK_STRING(str[start:]))
+	return array
+}
+
+// how a RegExp is printed
+func (state *RegExpState) String() string {
+	source := state.source
+	if source == "" {
+		source = "(?:)"
+	}
+	return "/" + source + "/" + state.flags
+}
+
+// the state of value when it is a RegExp, nil otherwise
+func regExpOf(value RuntimeVal) (*RegExpState, *Instance) {
+	if instance, ok := value.(*Instance); ok {
+		if state, ok := GetInstanceMember(instance, "#state").(*RawVal[*RegExpState]); ok {
+			return state.value, instance
+		}
+	}
+	return nil, nil
+}
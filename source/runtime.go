@@ -3,17 +3,16 @@ package main
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"math/rand"
-	"os"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
-//#region Memory
-
-// key / value map for storing the reference and values of variables
-var Memory *Map[string, RuntimeVal] = NewMap[string, RuntimeVal]()
-
 var (
 	undefined = MK_UD()
 	null      = MK_NULL()
@@ -26,10 +25,19 @@ type Interpreter struct {
 	terminated             bool
 	_break                 bool
 	_continue              bool
-	MemThreshold           uint64
+	_goto                  bool
+	label                  string // where a break, continue or goto statement is going
 	CallStack              *Stack
 	microTaskQueue         *MicroTaskQueue
-	exports                *Map[RuntimeVal, string]
+	exports                *Map[RuntimeVal, Ref]
+	// set when the interpreter runs the body of an async function
+	coroutine *Coroutine
+	// set when the interpreter runs the body of a generator function
+	generator *GeneratorState
+	// set when the interpreter runs on a goroutine of its own, see go statements
+	task bool
+	// the blocks compiled for the VM, by program so that they go with its AST
+	chunks *sync.Map
 }
 
 //#region Methods
@@ -39,6 +47,8 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 	// Literals
 	case *Number:
 		return &NumberVal{node.Value}
+	case *BigInt:
+		return MK_BIGINT(node.Value)
 	case *String:
 		return &StringVal{node.Value}
 	case *ObjectLiteral:
@@ -58,8 +68,12 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 		return r.Eval_typeof(node, env)
 	case *VoidExpr:
 		return r.Eval_void_expr(node, env)
+	case *BitwiseNotExpr:
+		return r.Eval_bitwise_not(node, env)
 	case *MemberExpr:
 		return r.Eval_member_expr(node, env)
+	case *OptionalChain:
+		return r.Eval_optional_chain(node, env)
 	case *InExpr:
 		return r.Eval_in_expr(node, env)
 	case *IncrementExpr:
@@ -80,6 +94,8 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 		return r.Eval_new_expr(node, env)
 	case *SuperExpr:
 		return r.Eval_super_expr(node, env)
+	case *SuperRef:
+		return r.Eval_super_ref(node, env)
 	case *LogicalExpr:
 		return r.Eval_logical_expr(node, env)
 	case *FromExpr:
@@ -92,6 +108,14 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 		return r.Eval_instanceof_expr(node, env)
 	case *TernaryExpr:
 		return r.Eval_ternary_expr(node, env)
+	case *TemplateString:
+		return r.Eval_template_string(node, env)
+	case *TaggedTemplate:
+		return r.Eval_tagged_template(node, env)
+	case *RegExpLiteral:
+		return r.Eval_regexp_literal(node, env)
+	case *YieldExpr:
+		return r.Eval_yield_expr(node, env)
 	// Statements
 	case *Program:
 		return r.EvalProgram(node, env)
@@ -123,7 +147,11 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 	case *ContinueStmt:
 		return r.EvalContinueStmt(node, env)
 	case *Label:
-		return undefined
+		return r.EvalLabel(node, env)
+	case *GotoStmt:
+		return r.EvalGotoStmt(node, env)
+	case *GoStmt:
+		return r.EvalGoStmt(node, env)
 	case *ClassDecl:
 		decl, _ := r.EvalClassDecl(node, env)
 		return decl
@@ -143,16 +171,26 @@ func (r *Interpreter) Evaluate(node Node, env *Environment) RuntimeVal {
 
 func (r *Interpreter) EvalProgram(p *Program, env *Environment) *ObjectVal {
 	ud_ref = GetUDRef(r)
+	r.chunks = &sync.Map{}
 	// sync code
 	r.EvalBlock(p.body, env)
-	// micro tasks
-	for r.microTaskQueue.length > 0 {
-		r.microTaskQueue.execCurrentTask()
+	if p.main {
+		// micro tasks, timers and I/O
+		eventLoop.Run()
+		eventLoop.ReportUnhandledRejections()
+	} else {
+		// a module only runs its micro tasks, the main program keeps the loop alive
+		eventLoop.RunMicroTasks()
 	}
 	return MK_OBJECT(r.exports, env, r)
 }
 
 func (r *Interpreter) EvalBlock(body []Node, env *Environment) RuntimeVal {
+	if useVM && len(body) > 0 && !r.terminated {
+		if chunk := CompileBlock(r.chunks, body); chunk != nil {
+			return r.RunChunk(chunk, env)
+		}
+	}
 	var lastEval RuntimeVal = undefined
 	for i := 0; i < len(body); i++ {
 		if r.terminated {
@@ -160,19 +198,34 @@ func (r *Interpreter) EvalBlock(body []Node, env *Environment) RuntimeVal {
 		}
 		stmt := body[i]
 		lastEval = r.Evaluate(stmt, env)
+		if r._goto {
+			// the label is in this block or in a block around it
+			if j := labelIndex(body, r.label); j >= 0 {
+				r._goto, r.terminated, r.label = false, false, ""
+				i = j - 1
+			}
+		}
 	}
 	return lastEval
 }
 
+// returns the index of the statement labelled name in body, or -1
+func labelIndex(body []Node, name string) int {
+	return slices.IndexFunc(body, func(stmt Node) bool {
+		label, ok := stmt.(*Label)
+		return ok && label.name == name
+	})
+}
+
 func (r *Interpreter) EvalVarDecl(decl *VarDecl, env *Environment) RuntimeVal {
 	rhs := r.Evaluate(decl.right, env)
 	r.DeclareVar(decl, rhs, env)
 	return undefined
 }
 
-func (r *Interpreter) DeclareVar(decl *VarDecl, rhs RuntimeVal, env *Environment) *Map[string, string] {
+func (r *Interpreter) DeclareVar(decl *VarDecl, rhs RuntimeVal, env *Environment) *Map[string, Ref] {
 	// key: ident, value: ref
-	decls := NewMap[string, string]()
+	decls := NewMap[string, Ref]()
 	switch left := decl.left.(type) {
 	case *Identifier:
 		ref, _ := env.DeclareVarRef(
@@ -226,18 +279,11 @@ func (r *Interpreter) EvalWhileLoop(stmt *WhileLoop, env *Environment) RuntimeVa
 	var condition RuntimeVal
 	if do {
 	start:
-		if r._break {
-			r._break = false
-			r.terminated = false
-			return undefined
-		}
-		if r._continue {
-			r._continue = false
-			r.terminated = false
-			return undefined
-		}
 		scope := NewEnv(env, "loop", env.sourcePath)
-		r.EvalBlock(stmt.body, scope)
+		lastEval := r.EvalBlock(stmt.body, scope)
+		if r.exitLoop(stmt.label) {
+			return lastEval
+		}
 		// before condition check
 		condition = r.Evaluate(stmt.condition, env)
 		if RtvToBool(condition) {
@@ -246,18 +292,11 @@ func (r *Interpreter) EvalWhileLoop(stmt *WhileLoop, env *Environment) RuntimeVa
 	} else {
 		condition = r.Evaluate(stmt.condition, env)
 		for RtvToBool(condition) {
-			if r._break {
-				r.terminated = false
-				r._break = false
-				return undefined
-			}
-			if r._continue {
-				r._continue = false
-				r.terminated = false
-				return undefined
-			}
 			scope := NewEnv(env, "loop", env.sourcePath)
-			r.EvalBlock(stmt.body, scope)
+			lastEval := r.EvalBlock(stmt.body, scope)
+			if r.exitLoop(stmt.label) {
+				return lastEval
+			}
 			// keep at end of loop
 			condition = r.Evaluate(stmt.condition, env)
 		}
@@ -267,23 +306,82 @@ func (r *Interpreter) EvalWhileLoop(stmt *WhileLoop, env *Environment) RuntimeVa
 
 func (r *Interpreter) EvalThrowStmt(stmt *ThrowStmt, env *Environment) RuntimeVal {
 	value := r.Evaluate(stmt.value, env)
-	r.terminated = true
 	env.throwValue(value, r)
 	return undefined
 }
 
 func (r *Interpreter) EvalTryStmt(stmt *TryCatch, env *Environment) RuntimeVal {
+	depth := r.CallStack.length
+	var frame FunctionVal
+	if depth > 0 {
+		frame = *r.CallStack.at(-1)
+	}
 	try_block := NewEnv(env, "try", env.sourcePath)
-	try_block.catch_block = stmt.catch
-	// always identifier
-	try_block.catch_param = stmt.catch_param
-	// try_block.finally_block = node.finally
 	// let's try
-	r.EvalBlock(stmt.try, try_block) // if theres an error, it will be caught
-	// finally
+	lastEval, thrown := r.EvalTryBlock(stmt.try, try_block)
+	if thrown != nil && stmt.catch != nil {
+		catch_block := NewEnv(env, "block", env.sourcePath)
+		if ident, ok := stmt.catch_param.(*Identifier); ok {
+			pos := getPosFromNode(ident)
+			catch_block.DeclareVar(ident.Symbol, thrown.value, "mutable", pos.line, pos.col, pos.count, env.sourcePath, r)
+		}
+		// an error thrown in catch still waits for finally
+		lastEval, thrown = r.EvalTryBlock(stmt.catch, catch_block)
+	}
+	if stmt.finally == nil {
+		if thrown != nil {
+			panic(thrown)
+		}
+		return lastEval
+	}
+	// try and catch completed with a return, break or continue (or a throw);
+	// it is put on hold while finally runs
+	returned, _break, _continue, _goto, label := r.returned_from_function, r._break, r._continue, r._goto, r.label
+	r.returned_from_function, r._break, r._continue, r._goto, r.label, r.terminated = false, false, false, false, "", false
+	if returned && depth > 0 && r.CallStack.length < depth {
+		// the return statement popped the function, but finally still runs inside it
+		r.CallStack.Push(frame)
+	}
 	finally_block := NewEnv(env, "block", env.sourcePath)
-	r.EvalBlock(stmt.finally, finally_block)
-	return undefined
+	finallyEval := r.EvalBlock(stmt.finally, finally_block)
+	if r.terminated {
+		// return, break or continue in finally overrides try and catch
+		return finallyEval
+	}
+	if returned && depth > 0 {
+		r.CallStack.Pop()
+	}
+	if thrown != nil {
+		panic(thrown)
+	}
+	r.returned_from_function, r._break, r._continue, r._goto, r.label = returned, _break, _continue, _goto, label
+	r.terminated = returned || _break || _continue || _goto
+	return lastEval
+}
+
+// evaluates the body of a try block and returns the error it threw, if any
+func (r *Interpreter) EvalTryBlock(body []Node, env *Environment) (lastEval RuntimeVal, thrown *ThrownError) {
+	depth := r.CallStack.length
+	defer func() {
+		if rec := recover(); rec != nil {
+			if returned, ok := rec.(*generatorReturn); ok {
+				// the return method of a generator resumed it, finally still runs
+				r.CallStack.truncate(depth)
+				lastEval, thrown = r.returnValue(returned.value), nil
+				return
+			}
+			err, ok := rec.(*ThrownError)
+			if !ok {
+				panic(rec)
+			}
+			err.materialize(r)
+			r.CallStack.truncate(depth)
+			r.terminated = false
+			r.returned_from_function = false
+			lastEval, thrown = undefined, err
+		}
+	}()
+	return r.EvalBlock(body, env), nil
 }
 
 func (r *Interpreter) EvalBlockStmt(stmt *BlockStmt, env *Environment) RuntimeVal {
@@ -336,17 +434,10 @@ start:
 	loop := NewEnv(scope, "loop", env.sourcePath)
 	condition := r.Evaluate(stmt.condition, loop)
 	if RtvToBool(condition) {
-		if r._break {
-			r.terminated = false
-			r._break = false
-			return undefined
-		}
-		if r._continue {
-			r._continue = false
-			r.terminated = false
-			return undefined
+		lastEval := r.EvalBlock(stmt.body, loop)
+		if r.exitLoop(stmt.label) {
+			return lastEval
 		}
-		r.EvalBlock(stmt.body, loop)
 		r.Evaluate(stmt.after, loop)
 		goto start
 	}
@@ -354,18 +445,22 @@ start:
 }
 
 func (r *Interpreter) EvalForIterLoop(stmt *ForIteratorLoop, env *Environment) RuntimeVal {
-	value := r.Evaluate(stmt.right, env)
 	pos := stmt.Pos
+	if stmt.await && r.CallStack.length > 0 && !r.CallStack.at(-1).async {
+		env.ThrowSyntaxError("for await is only valid in async functions and at the top level of scripts",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	value := r.Evaluate(stmt.right, env)
 	var iterable []RuntimeVal
 	if stmt.op == "in" {
 		// check if value is iterable
 		switch v := value.(type) {
 		case *ObjectVal:
-			v.properties.forEach(func(key RuntimeVal, _ string) {
+			v.properties.forEach(func(key RuntimeVal, _ Ref) {
 				iterable = append(iterable, key)
 			})
 		case *Instance:
-			v.properties.forEach(func(key RuntimeVal, _ string) {
+			v.properties.forEach(func(key RuntimeVal, _ Ref) {
 				iterable = append(iterable, key)
 			})
 		case *ArrayVal:
@@ -377,10 +472,18 @@ func (r *Interpreter) EvalForIterLoop(stmt *ForIteratorLoop, env *Environment) R
 		}
 	} else {
 		// for..of
+		if stmt.await {
+			if iterator, sync := r.getAsyncIterator(value, env, pos); iterator != nil {
+				return r.iterateLoop(stmt, iterator, !sync, env)
+			}
+		}
 		// check if value is iterable
 		switch v := value.(type) {
 		case *ObjectVal:
-			v.properties.forEach(func(_ RuntimeVal, ml string) {
+			if methodOf(v, iteratorKey()) != nil {
+				return r.iterateLoop(stmt, r.getIterator(v, env, pos), false, env)
+			}
+			v.properties.forEach(func(_ RuntimeVal, ml Ref) {
 				iterable = append(iterable, Memory.get(ml))
 			})
 		case *ArrayVal:
@@ -388,126 +491,23 @@ func (r *Interpreter) EvalForIterLoop(stmt *ForIteratorLoop, env *Environment) R
 				iterable = append(iterable, value)
 			})
 		case *StringVal:
-			for i := 0; i < len(v.value); i++ {
-				iterable = append(iterable, MK_STRING(string(v.value[i])))
+			for _, char := range v.value {
+				iterable = append(iterable, MK_STRING(string(char)))
 			}
 		case *Instance:
-			sym := MK_STRING(symbol_table.get("iterator").noAnsi())
-			if sym == nil {
-				sym = MK_STRING(MK_SYMBOL("iterator").noAnsi())
-				symbol_table.set("iterator", MK_SYMBOL(sym.value))
-			}
-			if proto, ok := v.prototype.(*ObjectVal); ok {
-				proto_ml := GetPropMlFromProto(sym, proto)
-				if len(proto_ml) > 0 {
-					method, ok := Memory.get(proto_ml).(*FunctionVal)
-					if ok {
-						pos := getPosFromNode(stmt.right)
-						iter, fn_scope := CallFunction(method, v.class_body, []RuntimeVal{}, v.r, pos)
-						ml := ""
-						switch i := iter.(type) {
-						case *Instance:
-							ml = GetPropMlFromProto(MK_STRING("next"), i.prototype)
-							if len(ml) == 0 {
-								goto err
-							}
-						case *ObjectVal:
-							ml = i.properties.get(MK_STRING("next"))
-							if len(ml) == 0 {
-								goto err
-							}
-						default:
-							goto err
-						}
-						next := Memory.get(ml).(*FunctionVal)
-						stop := false
-						for !stop {
-							v := next.Call(fn_scope, []RuntimeVal{}, r, pos)
-							if obj, ok := v.(*ObjectVal); ok {
-								done := obj.properties.get(MK_STRING("done"))
-								if len(done) > 0 {
-									done := Memory.get(done)
-									stop = RtvToBool(done)
-									if !stop {
-										value := obj.properties.get(MK_STRING("value"))
-										var v RuntimeVal = undefined
-										if len(value) > 0 {
-											v = Memory.get(value)
-										}
-										iterable = append(iterable, v)
-									}
-								}
-							} else {
-								goto err
-							}
-						}
-						goto start
-					}
-				}
-			}
-			if len(v.properties.get(sym)) > 0 {
-				method, ok := Memory.get(v.properties.get(sym)).(*FunctionVal)
-				if ok {
-					pos := getPosFromNode(stmt.right)
-					iter, fn_scope := CallFunction(method, v.class_body, []RuntimeVal{}, v.r, pos)
-					ml := ""
-					switch i := iter.(type) {
-					case *Instance:
-						ml = GetPropMlFromProto(MK_STRING("next"), i.prototype)
-						if len(ml) == 0 {
-							goto err
-						}
-					case *ObjectVal:
-						ml = i.properties.get(MK_STRING("next"))
-						if len(ml) == 0 {
-							goto err
-						}
-					default:
-						goto err
-					}
-					next := Memory.get(ml).(*FunctionVal)
-					stop := false
-					for !stop {
-						v := next.Call(fn_scope, []RuntimeVal{}, r, pos)
-						if obj, ok := v.(*ObjectVal); ok {
-							done := obj.properties.get(MK_STRING("done"))
-							if len(done) > 0 {
-								done := Memory.get(done)
-								stop = RtvToBool(done)
-								if !stop {
-									value := obj.properties.get(MK_STRING("value"))
-									var v RuntimeVal = undefined
-									if len(value) > 0 {
-										v = Memory.get(value)
-									}
-									iterable = append(iterable, v)
-								}
-							}
-						} else {
-							goto err
-						}
-					}
-					goto start
-				}
+			iterator := r.getIterator(v, env, pos)
+			if iterator == nil {
+				env.ThrowTypeError("an instance must have a Symbol.iterator method that returns an iterator: for..of loop" + SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 			}
-		err:
-			env.ThrowTypeError("an instance must have a Symbol.iterator method that returns an iterator: for..in loop" + SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			return r.iterateLoop(stmt, iterator, false, env)
 		default:
-			env.ThrowTypeError("type", ValueType(value), "is not iterable in for..in loop"+SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			env.ThrowTypeError("type", ValueType(value), "is not iterable in for..of loop"+SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 		}
 	}
-start:
 	for i := 0; i < len(iterable); i++ {
 		v := iterable[i]
-		if r._break {
-			r._break = false
-			r.terminated = false
-			return undefined
-		}
-		if r._continue {
-			r._continue = false
-			r.terminated = false
-			return undefined
+		if stmt.await {
+			v = r.Await(v, env, pos)
 		}
 		scope := NewEnv(env, "loop", env.sourcePath)
 		r.DeclareVar(&VarDecl{
@@ -516,24 +516,91 @@ start:
 			_type: stmt._type,
 			Pos:   pos,
 		}, v, scope)
-		r.EvalBlock(stmt.body, scope)
+		lastEval := r.EvalBlock(stmt.body, scope)
+		if r.exitLoop(stmt.label) {
+			return lastEval
+		}
 	}
 	return undefined
 }
 
-func (r *Interpreter) EvalFunctionDecl(decl *FunctionDecl, env *Environment) (*FunctionVal, string) {
-	name := ""
-	if decl.name.dynamic {
-		name = r.Evaluate(decl.name.node, env).noAnsi()
-	} else if !decl.anonymous {
-		// always identifier
-		name = decl.name.node.(*Identifier).Symbol
+// runs a for..of loop over the values of iterator, they are read lazily, one per run of the body.
+// the iterator is closed when the loop is left before it is done, by a break, a return or an error.
+// for await loops wait for each value, and for the results of the methods of an async iterator
+func (r *Interpreter) iterateLoop(stmt *ForIteratorLoop, iterator RuntimeVal, async bool, env *Environment) RuntimeVal {
+	pos := stmt.Pos
+	step := r.iteratorStep
+	if async {
+		step = r.asyncIteratorStep
+	}
+	leave := func() {
+		if result := r.closeIterator(iterator, env, pos); async && result != nil {
+			r.Await(result, env, pos)
+		}
+	}
+	inBody := false
+	defer func() {
+		if rec := recover(); rec != nil {
+			if _, ok := rec.(*ThrownError); ok && inBody {
+				leave()
+			}
+			panic(rec)
+		}
+	}()
+	for {
+		value, done := step(iterator, "next", []RuntimeVal{}, env, pos)
+		if done {
+			return undefined
+		}
+		if stmt.await && !async {
+			value = r.Await(value, env, pos)
+		}
+		scope := NewEnv(env, "loop", env.sourcePath)
+		inBody = true
+		r.DeclareVar(&VarDecl{
+			left:  stmt.left,
+			right: nil,
+			_type: stmt._type,
+			Pos:   pos,
+		}, value, scope)
+		lastEval := r.EvalBlock(stmt.body, scope)
+		inBody = false
+		if r.exitLoop(stmt.label) {
+			leave()
+			return lastEval
+		}
+	}
+}
+
+// called after each run of a loop body, reports whether the loop stops.
+// break and continue end here, a return stops the loop and keeps unwinding the function,
+// so does a goto or a break or continue with the label of a statement around the loop
+func (r *Interpreter) exitLoop(label string) bool {
+	yieldInterpreter()
+	if r._goto || r.label != "" && r.label != label {
+		return r.terminated
 	}
+	r.label = ""
+	if r._break {
+		r._break = false
+		r.terminated = false
+		return true
+	}
+	if r._continue {
+		r._continue = false
+		r.terminated = false
+		return false
+	}
+	return r.terminated
+}
+
+func (r *Interpreter) EvalFunctionDecl(decl *FunctionDecl, env *Environment) (*FunctionVal, Ref) {
+	name := r.functionName(decl, env)
 	fn := MK_FUNCTION(name, decl.body, decl.params, env, decl.async, decl.anonymous, decl._type == "arrow", r)
+	fn.generator = decl.generator
 	if decl.anonymous && len(fn.name) == 0 {
 		fn.name = "(anonymous)"
-		ml := GenerateRadix(16)
-		Memory.set(ml, fn)
+		ml := Memory.alloc(fn)
 		return fn, ml
 	}
 	ml, value := env.DeclareVarRef(name, fn, "constant", decl.line, decl.col, decl.count, env.sourcePath, r)
@@ -541,14 +608,23 @@ func (r *Interpreter) EvalFunctionDecl(decl *FunctionDecl, env *Environment) (*F
 }
 
 func (r *Interpreter) EvalReturnStmt(stmt *ReturnStmt, env *Environment) RuntimeVal {
-	if env.ResolveEnv("function", r) == nil {
-		env.ThrowSyntaxError("illegal use of the return keyword, return statements can only be used in the body of functions",
-			SourceLog(stmt.line, stmt.col, stmt.count, env.sourcePath, ""))
-	}
+	r.checkReturn(stmt.Pos, env)
 	var value RuntimeVal = undefined
 	if stmt.value != nil {
 		value = r.Evaluate(stmt.value, env)
 	}
+	return r.returnValue(value)
+}
+
+func (r *Interpreter) checkReturn(pos Pos, env *Environment) {
+	if env.ResolveEnv("function", r) == nil {
+		env.ThrowSyntaxError("illegal use of the return keyword, return statements can only be used in the body of functions",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+}
+
+// leaves the function that is running, value is what it returns
+func (r *Interpreter) returnValue(value RuntimeVal) RuntimeVal {
 	r.CallStack.Pop()
 	r.returned_from_function = true
 	r.terminated = true
@@ -556,29 +632,53 @@ func (r *Interpreter) EvalReturnStmt(stmt *ReturnStmt, env *Environment) Runtime
 }
 
 func (r *Interpreter) EvalBreakStmt(stmt *BreakStmt, env *Environment) RuntimeVal {
-	if env.ResolveEnv("loop", r) == nil {
+	// the parser checks that a labelled statement is around it
+	if stmt.label == "" && env.ResolveEnv("loop", r) == nil {
 		env.ThrowSyntaxError("illegal use of the break keyword, break statements can only be used in the body of loops",
 			SourceLog(stmt.line, stmt.col, stmt.count, env.sourcePath, ""))
 	}
 	r._break = true
+	r.label = stmt.label
 	r.terminated = true
 	return undefined
 }
 
 func (r *Interpreter) EvalContinueStmt(stmt *ContinueStmt, env *Environment) RuntimeVal {
-	if env.ResolveEnv("loop", r) == nil {
+	if stmt.label == "" && env.ResolveEnv("loop", r) == nil {
 		env.ThrowSyntaxError("illegal use of the continue keyword, continue statements can only be used in the body of loops",
 			SourceLog(stmt.line, stmt.col, stmt.count, env.sourcePath, ""))
 	}
 	r._continue = true
+	r.label = stmt.label
 	r.terminated = true
 	return undefined
 }
 
+// the parser checks that the label is in the function, in the block
+// of the goto statement or in a block around it
+func (r *Interpreter) EvalGotoStmt(stmt *GotoStmt, env *Environment) RuntimeVal {
+	r._goto = true
+	r.label = stmt.label.name
+	r.terminated = true
+	return undefined
+}
+
+// a break with the label of a statement that is not a loop ends here
+func (r *Interpreter) EvalLabel(stmt *Label, env *Environment) RuntimeVal {
+	if stmt.body == nil {
+		return undefined
+	}
+	value := r.Evaluate(stmt.body, env)
+	if r._break && r.label == stmt.name {
+		r._break, r.terminated, r.label = false, false, ""
+	}
+	return value
+}
+
 var anonyClassCount = 0
 
-func (r *Interpreter) EvalClassDecl(decl *ClassDecl, env *Environment) (*ClassVal, string) {
-	extends := ""
+func (r *Interpreter) EvalClassDecl(decl *ClassDecl, env *Environment) (*ClassVal, Ref) {
+	var extends Ref
 	if len(decl.extends) > 0 {
 		pos := decl.Pos
 		ml := env.ReferenceOf(decl.extends, pos.line, pos.col, pos.count, env.sourcePath, r)
@@ -592,10 +692,13 @@ func (r *Interpreter) EvalClassDecl(decl *ClassDecl, env *Environment) (*ClassVa
 	class := MK_CLASS(decl.name, decl.constructor, decl.properties, decl.methods, env, extends, r)
 	if class.anonymous {
 		class.name = "$" + string(rune(anonyClassCount))
-		ml := GenerateRadix(16)
+		ml := Memory.alloc(class)
+		r.EvalStatics(class, decl.statics, env)
 		return class, ml
 	}
+	// declared first, so that the static members can refer to the class
 	ml, v := env.DeclareVarRef(class.name, class, "constant", decl.line, decl.col, decl.count, env.sourcePath, r)
+	r.EvalStatics(class, decl.statics, env)
 	return v.(*ClassVal), ml
 }
 
@@ -610,10 +713,10 @@ func (r *Interpreter) EvalImportStmt(node *ImportStmt, env *Environment) Runtime
 	} else {
 		path := node.path
 		current_module_path := env.sourcePath
-		path = RealPath(RelativePathToFile(current_module_path, path))
+		path = ResolveImport(current_module_path, path)
 		env.sourcePath = path
 		runtime := NewRuntime()
-		parser := NewParser(path, "module", "")
+		parser := NewParser(path, "module", "", Tokenize)
 		AST := parser.Parse(false)
 		runtime.EvalProgram(AST, env)
 		env.sourcePath = current_module_path
@@ -626,7 +729,7 @@ func (r *Interpreter) EvalExportStmt(node *ExportStmt, env *Environment) Runtime
 	case *VarDecl:
 		rhs := r.Evaluate(export.right, env)
 		decls := r.DeclareVar(export, rhs, env)
-		decls.forEach(func(key, value string) {
+		decls.forEach(func(key string, value Ref) {
 			r.exports.set(MK_STRING(key), value)
 		})
 	case *FunctionDecl:
@@ -664,7 +767,7 @@ func (r *Interpreter) EvalSwitchStmt(stmt *SwitchStmt, env *Environment) *Undefi
 }
 
 // Declares Destructured Properties
-func DestructureObjectDecl(val RuntimeVal, destructuring *ObjectLiteral, _type string, env *Environment, r *Interpreter) *Map[string, string] {
+func DestructureObjectDecl(val RuntimeVal, destructuring *ObjectLiteral, _type string, env *Environment, r *Interpreter) *Map[string, Ref] {
 	var obj *ObjectVal
 	proto := MK_OBJECT(nil, nil, nil)
 	switch v := val.(type) {
@@ -684,7 +787,7 @@ func DestructureObjectDecl(val RuntimeVal, destructuring *ObjectLiteral, _type s
 		pos := getPosFromNode(destructuring)
 		env.ThrowTypeError("cannot destructure type", ValueType(val), "it is not an object"+SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 	}
-	decls := NewMap[string, string]()
+	decls := NewMap[string, Ref]()
 	destructuring.properties.forEach(func(key DynamicNode, value Node) {
 		prop_key := ""
 		ident := ""
@@ -706,9 +809,9 @@ func DestructureObjectDecl(val RuntimeVal, destructuring *ObjectLiteral, _type s
 		}
 		key_v := MK_STRING(prop_key)
 		ml := obj.properties.get(key_v)
-		if len(ml) == 0 {
+		if ml == nil {
 			ml = GetPropMlFromProto(key_v, proto)
-			if len(ml) == 0 {
+			if ml == nil {
 				pos := getPosFromNode(key.node)
 				env.ThrowReferenceError("type object has no property named", prop_key, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 			}
@@ -748,7 +851,7 @@ func DestructureObjectAssign(val RuntimeVal, destructuring *ObjectLiteral, env *
 			ident = prop_key
 		}
 		ml := obj.properties.get(MK_STRING(prop_key))
-		if len(ml) == 0 {
+		if ml == nil {
 			pos := getPosFromNode(key.node)
 			env.ThrowReferenceError("type object has no property named", prop_key, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 		}
@@ -758,14 +861,24 @@ func DestructureObjectAssign(val RuntimeVal, destructuring *ObjectLiteral, env *
 	})
 }
 
-// Declares Destructured Properties
-func DestructureArrayDecl(val RuntimeVal, destructuring *ArrayLiteral, _type string, env *Environment, r *Interpreter) *Map[string, string] {
-	arr, ok := val.(*ArrayVal)
-	if !ok {
-		pos := getPosFromNode(destructuring)
-		env.ThrowTypeError("cannot destructure type", ValueType(val), "it is not an array", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+// the array an array destructuring reads, the values of other iterables are read into one,
+// no more than the destructuring takes
+func (r *Interpreter) destructuredArray(val RuntimeVal, destructuring *ArrayLiteral, env *Environment) *ArrayVal {
+	if arr, ok := val.(*ArrayVal); ok {
+		return arr
 	}
-	decls := NewMap[string, string]()
+	pos := getPosFromNode(destructuring)
+	if _, ok := val.(*StringVal); ok || methodOf(val, iteratorKey()) != nil {
+		return MK_ARRAY(r.iterableValues(val, len(destructuring.elements), "destructuring", env, pos)...)
+	}
+	env.ThrowTypeError("cannot destructure type", ValueType(val), "it is not iterable", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	return nil
+}
+
+// Declares Destructured Properties
+func DestructureArrayDecl(val RuntimeVal, destructuring *ArrayLiteral, _type string, env *Environment, r *Interpreter) *Map[string, Ref] {
+	arr := r.destructuredArray(val, destructuring, env)
+	decls := NewMap[string, Ref]()
 	for i := 0; i < len(destructuring.elements); i++ {
 		node := destructuring.elements[i]
 		ident := node.(*Identifier)
@@ -778,16 +891,12 @@ func DestructureArrayDecl(val RuntimeVal, destructuring *ArrayLiteral, _type str
 }
 
 // Assigns Destructured Properties
-func DestructureArrayAssign(val RuntimeVal, destructuring *ArrayLiteral, env *Environment, r *Interpreter) *Map[string, string] {
-	arr, ok := val.(*ArrayVal)
-	if !ok {
-		pos := getPosFromNode(destructuring)
-		env.ThrowTypeError("cannot destructure type", ValueType(val), "it is not an array", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
-	}
-	decls := NewMap[string, string]()
+func DestructureArrayAssign(val RuntimeVal, destructuring *ArrayLiteral, env *Environment, r *Interpreter) *Map[string, Ref] {
+	arr := r.destructuredArray(val, destructuring, env)
+	decls := NewMap[string, Ref]()
 	for i, v := range destructuring.elements {
 		ident := v.(*Identifier)
-		var ml string = arr.getRef(i)
+		var ml Ref = arr.getRef(i)
 		pos := getPosFromNode(ident)
 		env.AssignVar(ident.Symbol, Memory.get(ml), pos.line, pos.col, pos.count, env.sourcePath, r)
 		decls.set(ident.Symbol, ml)
@@ -795,9 +904,9 @@ func DestructureArrayAssign(val RuntimeVal, destructuring *ArrayLiteral, env *En
 	return decls
 }
 
-var ud_ref = ""
+var ud_ref Ref
 
-func GetUDRef(r *Interpreter) string {
+func GetUDRef(r *Interpreter) Ref {
 	return GetGlobalEnv(r).variables.get("undefined")
 }
 
@@ -809,6 +918,8 @@ func RtvToBool(condition RuntimeVal) bool {
 		return len(val.value) > 0
 	case *NumberVal:
 		return val.value != 0
+	case *BigIntVal:
+		return val.value.Sign() != 0
 	case *ObjectVal:
 		return val.properties.length > 0
 	case *ArrayVal:
@@ -834,16 +945,31 @@ func (r *Interpreter) Eval_instanceof_expr(expr *InstanceofExpr, env *Environmen
 	lhs := r.Evaluate(expr.left, env)
 	rhs := r.Evaluate(expr.right, env)
 	boolean := false
-	if ValueType(rhs) == "class" && ValueType(lhs) == "instance" {
-		instance := lhs.(*Instance)
-		class := rhs.(*ClassVal)
-		pos := expr.Pos
-		ml := env.ReferenceOf(class.name, pos.line, pos.col, pos.count, env.sourcePath, r)
-		boolean = instance.class == ml
+	if instance, ok := lhs.(*Instance); ok {
+		if class, ok := rhs.(*ClassVal); ok {
+			boolean = InstanceOf(instance, class)
+		}
 	}
 	return MK_BOOL(boolean)
 }
 
+// reports whether class is the class of instance, or a class it extends
+func InstanceOf(instance *Instance, class *ClassVal) bool {
+	ml := instance.class
+	for ml != nil {
+		c, ok := Memory.get(ml).(*ClassVal)
+		if !ok {
+			return false
+		}
+		// class values are copied when passed around, their objects are not
+		if c.ObjectVal == class.ObjectVal {
+			return true
+		}
+		ml = c.extends
+	}
+	return false
+}
+
 func (r *Interpreter) Eval_dynamic_import(expr *DynamicImport, env *Environment) RuntimeVal {
 	DynamicImportMacro := MK_MACRO("import", func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
 		const err = "Dynamic import's specifier must be of type 'string', but here has type"
@@ -864,31 +990,71 @@ func (r *Interpreter) Eval_dynamic_import(expr *DynamicImport, env *Environment)
 	})
 	args := []RuntimeVal{r.Evaluate(expr.specifier, env)}
 	if expr.async {
-		return Promise(DynamicImportMacro, args, r, env, expr.Pos)
+		return r.AsyncCall(func() RuntimeVal {
+			return DynamicImportMacro.call(args, env, expr.Pos, r)
+		}, env, expr.Pos)
 	}
 	return DynamicImportMacro.call(args, env, expr.Pos, r)
 }
 
-func (r *Interpreter) Eval_match_expr(expr *MatchExpr, env *Environment) RuntimeVal {
-	match_against := r.Evaluate(expr.match, env)
-	var value RuntimeVal = null
-	for i := 0; i < len(expr.cases); i++ {
-		_case := expr.cases[i]
-		match := r.Evaluate(_case.match, env)
-		if RtvAreEqual(match_against, match) {
-			value = r.Evaluate(_case.body, env)
-			break
+func (r *Interpreter) Eval_template_string(expr *TemplateString, env *Environment) RuntimeVal {
+	values := make([]RuntimeVal, len(expr.exprs))
+	for i, e := range expr.exprs {
+		values[i] = r.Evaluate(e, env)
+	}
+	return MK_STRING(joinTemplate(expr, values))
+}
+
+// joins the cooked text of a template literal with the values of its interpolations
+// a literal makes a new RegExp every time it is evaluated, it is an instance of
+// the stdlib class even where the name RegExp is shadowed
+func (r *Interpreter) Eval_regexp_literal(expr *RegExpLiteral, env *Environment) RuntimeVal {
+	pos := getPosFromNode(expr)
+	class := stdEnv.ReferenceOf("RegExp", pos.line, pos.col, pos.count, env.sourcePath, r)
+	return r.Instantiate(class, env, []RuntimeVal{MK_STRING(expr.pattern), MK_STRING(expr.flags)}, pos)
+}
+
+func joinTemplate(expr *TemplateString, values []RuntimeVal) string {
+	var str strings.Builder
+	for i, text := range expr.cooked {
+		str.WriteString(*text)
+		if i < len(values) {
+			str.WriteString(values[i].noAnsi())
 		}
 	}
+	return str.String()
+}
+
+// calls the tag with the cooked and raw text, { cooked, raw }, followed by
+// the values of the interpolations
+func (r *Interpreter) Eval_tagged_template(expr *TaggedTemplate, env *Environment) RuntimeVal {
+	tag := r.Evaluate(expr.tag, env)
+	cooked, raw := MK_ARRAY(), MK_ARRAY()
+	for i, text := range expr.template.cooked {
+		if text == nil {
+			cooked.Push(undefined)
+		} else {
+			cooked.Push(MK_STRING(*text))
+		}
+		raw.Push(MK_STRING(expr.template.raw[i]))
+	}
+	text := NewMap[RuntimeVal, Ref]()
+	text.set(MK_STRING("cooked"), Memory.alloc(cooked))
+	text.set(MK_STRING("raw"), Memory.alloc(raw))
+	args := []RuntimeVal{MK_OBJECT(text, env, r)}
+	for _, e := range expr.template.exprs {
+		args = append(args, r.Evaluate(e, env))
+	}
+	value, _ := CallFunction(tag, env, args, r, expr.Pos)
 	return value
 }
 
 func (r *Interpreter) Eval_from_expr(node *FromExpr, env *Environment) *ObjectVal {
 	path := node.path
-	path = RealPath(RelativePathToFile(env.sourcePath, path))
+	path = ResolveImport(env.sourcePath, path)
 	script_env := CreateScriptEnv(r, path)
 	runtime := NewRuntime()
-	parser := NewParser(path, "module", "")
+	parser := NewParser(path, "module", "", Tokenize)
 	AST := parser.Parse(false)
 	module := runtime.EvalProgram(AST, script_env)
 	return module
@@ -900,6 +1066,12 @@ func (r *Interpreter) Eval_logical_expr(expr *LogicalExpr, env *Environment) Run
 	left := r.Evaluate(expr.left, env)
 	lhs := RtvToBool(left)
 	switch op {
+	case "??":
+		// the right side is only evaluated when the left one is nullish
+		value = left
+		if ValIsNullish(left) {
+			value = r.Evaluate(expr.right, env)
+		}
 	case "&&":
 		right := r.Evaluate(expr.right, env)
 		if !lhs {
@@ -921,7 +1093,7 @@ func (r *Interpreter) Eval_logical_expr(expr *LogicalExpr, env *Environment) Run
 }
 
 func (r *Interpreter) Eval_new_expr(expr *NewExpr, env *Environment) RuntimeVal {
-	var ml string
+	var ml Ref
 	args := []RuntimeVal{}
 	switch exp := expr.operand.(type) {
 	case *CallExpr:
@@ -934,7 +1106,7 @@ func (r *Interpreter) Eval_new_expr(expr *NewExpr, env *Environment) RuntimeVal
 	return r.Instantiate(ml, env, args, pos)
 }
 
-func (r *Interpreter) getRef(exp Node, env *Environment) string {
+func (r *Interpreter) getRef(exp Node, env *Environment) Ref {
 	switch node := exp.(type) {
 	case *MemberExpr:
 		return r.Get_Member(node, env)
@@ -942,8 +1114,7 @@ func (r *Interpreter) getRef(exp Node, env *Environment) string {
 		pos := getPosFromNode(node)
 		return env.ReferenceOf(node.Symbol, pos.line, pos.col, pos.count, env.sourcePath, r)
 	default:
-		ml := GenerateRadix(16)
-		Memory.set(ml, r.Evaluate(node, env))
+		ml := Memory.alloc(r.Evaluate(node, env))
 		return ml
 	}
 }
@@ -952,7 +1123,7 @@ func (r *Interpreter) Eval_super_expr(node *SuperExpr, ctor_body *Environment) R
 	pos := getPosFromNode(node)
 	this := ctor_body.LookupVar("this", pos.line, pos.col, pos.count, ctor_body.sourcePath, r).(*Instance)
 	class := Memory.get(this.class).(*ClassVal)
-	if len(class.extends) == 0 {
+	if class.extends == nil {
 		return null
 	}
 	args := []RuntimeVal{}
@@ -960,8 +1131,13 @@ func (r *Interpreter) Eval_super_expr(node *SuperExpr, ctor_body *Environment) R
 		arg := node.args[i]
 		args = append(args, r.Evaluate(arg, ctor_body))
 	}
-	// executes base contructor
-	supers_instance := r.Instantiate(class.extends, ctor_body, args, pos)
+	r.CallSuper(this, class, args, ctor_body, pos)
+	return undefined
+}
+
+// executes the base constructor of class and links its instance into this' prototype chain
+func (r *Interpreter) CallSuper(this *Instance, class *ClassVal, args []RuntimeVal, env *Environment, pos Pos) {
+	supers_instance := r.Instantiate(class.extends, env, args, pos)
 	supers_proto, ok := supers_instance.prototype.(*ObjectVal)
 	if ok {
 		this_proto, ok := this.prototype.(*ObjectVal)
@@ -974,19 +1150,19 @@ func (r *Interpreter) Eval_super_expr(node *SuperExpr, ctor_body *Environment) R
 			this_proto = supers_proto
 		}
 	}
-	return undefined
 }
 
-func (r *Interpreter) Instantiate(class_ml string, env *Environment, args []RuntimeVal, pos Pos) *Instance {
+func (r *Interpreter) Instantiate(class_ml Ref, env *Environment, args []RuntimeVal, pos Pos) *Instance {
 	value := Memory.get(class_ml)
 	class, ok := value.(*ClassVal)
 	if !ok {
 		env.ThrowTypeError("type", ValueType(value), "is not a class and is not constructable", SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
 	}
-	prototype := NewMap[RuntimeVal, string]()
+	prototype := NewMap[RuntimeVal, Ref]()
 	class_body := NewEnv(class.declEnv, "object", class.declEnv.sourcePath)
+	class_body.class = class
 	this := MK_INSTANCE(class.name, class_ml, prototype, ud_ref, class_body, r)
-	// if len(class.extends) > 0 {
+	// if class.extends != nil {
 	// }
 	class_body.DeclareVar("this", this, "constant", pos.line, pos.col, pos.count, class_body.sourcePath, r)
 	for i := 0; i < len(class.fields); i++ {
@@ -1001,12 +1177,22 @@ func (r *Interpreter) Instantiate(class_ml string, env *Environment, args []Runt
 	}
 	for i := 0; i < len(class.methods); i++ {
 		method := class.methods[i]
+		if method.kind != "" {
+			// accessors are members of the instance only
+			r.defineMethod(prototype, method, r.functionName(&method.decl, class_body), class_body)
+			continue
+		}
 		v, ml := r.EvalFunctionDecl(&method.decl, class_body)
 		Memory.set(ml, v)
 		class_body.variables.set(v.name, ml)
 		prototype.set(MK_STRING(v.name), ml)
 	}
-	r.CallCtor(class.ctor, args, class_body, this)
+	if class.ctor != nil {
+		r.CallCtor(class.ctor, args, class_body, this)
+	} else if class.extends != nil {
+		// a derived class without a constructor passes its arguments to the base class
+		r.CallSuper(this, class, args, class_body, pos)
+	}
 	return this
 }
 
@@ -1064,25 +1250,12 @@ top:
 }
 
 func (r *Interpreter) Eval_await_expr(expr *AwaitExpr, env *Environment) RuntimeVal {
-	var value RuntimeVal
-	switch op := expr.operand.(type) {
-	case *CallExpr:
-		fn, ok := r.Evaluate(op.caller, env).(*FunctionVal)
-		async := false
-		if ok {
-			async = fn.async
-			fn.async = false
-		}
-		args := r.eval_args(op.args, env)
-		value = fn.Call(env, args, r, op.Pos)
-		fn.async = async
-	case *DynamicImport:
-		op.async = false
-		value = r.Eval_dynamic_import(op, env)
-	default:
-		value = r.Evaluate(op, env)
+	if r.CallStack.length > 0 && !r.CallStack.at(-1).async {
+		env.ThrowSyntaxError("await is only valid in async functions and at the top level of scripts",
+			SourceLog(expr.line, expr.col, expr.count, env.sourcePath, ""))
 	}
-	return value
+	value := r.Evaluate(expr.operand, env)
+	return r.Await(value, env, expr.Pos)
 }
 
 func (r *Interpreter) Eval_call_expr(expr *CallExpr, env *Environment) RuntimeVal {
@@ -1099,9 +1272,11 @@ func CallFunction(value RuntimeVal, env *Environment, args []RuntimeVal, r *Inte
 	case *FunctionVal:
 		funtion_scope := NewEnv(v.declEnv, "function", env.sourcePath)
 		r.ResolveTHIS(v, pos, env, funtion_scope)
+		if v.generator {
+			return r.CallGenerator(v, args, funtion_scope, env, pos), funtion_scope
+		}
 		if v.async {
-			// sets promise_mem_loc to this new Promise
-			return Promise(v, args, r, env, pos), funtion_scope
+			return r.CallAsync(v, args, funtion_scope, env, pos), funtion_scope
 		}
 		DeclareParams(v.params, args, funtion_scope, r)
 		return r.pushToStack(*v, *funtion_scope), funtion_scope
@@ -1130,6 +1305,17 @@ func (r *Interpreter) pushToStack(function FunctionVal, funtion_scope Environmen
 	var lastEvaluated RuntimeVal = undefined
 	function.declEnv = &funtion_scope
 	r.CallStack.Push(function)
+	depth := r.CallStack.length
+	defer func() {
+		if rec := recover(); rec != nil {
+			if thrown, ok := rec.(*ThrownError); ok {
+				// errors raised by the runtime record the stack before it unwinds
+				thrown.materialize(r)
+				r.CallStack.truncate(depth - 1)
+			}
+			panic(rec)
+		}
+	}()
 	// for r.CallStack.length > 0 {
 	callback := r.CallStack.at(-1)
 	lastEvaluated = r.EvalBlock(callback.body, callback.declEnv)
@@ -1193,7 +1379,7 @@ top:
 
 func DuplicateRtv(v RuntimeVal) RuntimeVal {
 	switch rtv := v.(type) {
-	case *BoolVal, *NullVal, *NumberVal, *StringVal, *Symbol, *Undefined:
+	case *BoolVal, *NullVal, *NumberVal, *BigIntVal, *StringVal, *Symbol, *Undefined:
 		return rtv
 	case *Macro:
 		m := *rtv
@@ -1219,9 +1405,8 @@ func DuplicateRtv(v RuntimeVal) RuntimeVal {
 	case *ObjectVal:
 		new_object := MK_OBJECT(nil, rtv.body_env, rtv.r)
 		// new_map := NewMap[string, RuntimeVal]()
-		rtv.properties.forEach(func(key RuntimeVal, value string) {
-			ml := GenerateRadix(16)
-			Memory.set(ml, Memory.get(value))
+		rtv.properties.forEach(func(key RuntimeVal, value Ref) {
+			ml := Memory.alloc(Memory.get(value))
 			new_object.properties.set(key, ml)
 		})
 		return new_object
@@ -1266,6 +1451,7 @@ func (r *Interpreter) Eval_globalThisMemberAssignment(expr *globalThisMemberAssi
 	ml, _, invalidMl := r.Get_globalThis_Member(expr.Symbol, expr.property, expr.line, expr.col, expr.count, env)
 	rhs := r.Evaluate(expr.right, env)
 	if invalidMl { // variable does not exist
+		ml = Memory.alloc(rhs)
 		e := env.ResolveEnv("program", r)
 		e.variables.set(expr.property, ml)
 		e.varTypes.set(expr.property, "mutable")
@@ -1282,11 +1468,11 @@ func (r *Interpreter) Eval_globalThisMember(node *globalThisMember, env *Environ
 	return Memory.get(ml)
 }
 
-func (r *Interpreter) Get_globalThis_Member(Symbol, property string, line, col, count int, env *Environment) (string, RuntimeVal, bool) {
+func (r *Interpreter) Get_globalThis_Member(Symbol, property string, line, col, count int, env *Environment) (Ref, RuntimeVal, bool) {
 	object := env.LookupVar(Symbol, line, col, count, env.sourcePath, r).(*ObjectVal)
 	ml := object.properties.get(MK_STRING(property))
-	if len(ml) == 0 {
-		return "", undefined, true
+	if ml == nil {
+		return nil, undefined, true
 	}
 	return ml, nil, false
 }
@@ -1310,12 +1496,44 @@ func (r *Interpreter) Eval_grouping_expr(node *GroupingExpr, env *Environment) R
 
 func (r *Interpreter) Eval_increment_expr(node *IncrementExpr, env *Environment) RuntimeVal {
 	operand := node.operand
-	op := node.op
 	operand_pos := getPosFromNode(operand)
 	operand_value := r.Evaluate(operand, env)
+	value, number := increment(node.op, node.pre, operand_value, operand_pos, env)
+	switch operand := operand.(type) {
+	case *MemberExpr:
+		if member, done := r.assignedMember(operand, value, env, operand_pos); !done {
+			r.setMember(member, value, env, operand_pos)
+		}
+	default:
+		ref := env.ReferenceOf(
+			operand.(*Identifier).Symbol,
+			operand_pos.line,
+			operand_pos.col,
+			operand_pos.count,
+			env.sourcePath,
+			r,
+		)
+		Memory.set(ref, value)
+	}
+	return number
+}
+
+// returns the value stored by ++ or -- and the value of the expression
+func increment(op string, pre bool, operand_value RuntimeVal, pos Pos, env *Environment) (RuntimeVal, RuntimeVal) {
+	if n, ok := operand_value.(*BigIntVal); ok {
+		one := big.NewInt(1)
+		if op == "--" {
+			one.Neg(one)
+		}
+		value := MK_BIGINT(new(big.Int).Add(n.value, one))
+		if pre {
+			return value, value
+		}
+		return value, n
+	}
 	if ValueType(operand_value) != "number" {
 		env.ThrowTypeError(
-			SourceLog(operand_pos.line, operand_pos.col, operand_pos.count, env.sourcePath, ""),
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
 		)
 	}
 
@@ -1325,59 +1543,77 @@ func (r *Interpreter) Eval_increment_expr(node *IncrementExpr, env *Environment)
 	number = operand_value.Value().(float64)
 	if op == "++" {
 		value = number + 1
-		if node.pre {
+		if pre {
 			number += 1
 		}
 	} else {
 		value = number - 1
-		if node.pre {
+		if pre {
 			number -= 1
 		}
 	}
-	switch operand := operand.(type) {
-	case *MemberExpr:
-		member := r.Get_Member(
-			operand,
-			env)
-		Memory.set(member, MK_NUMBER(value))
-	default:
-		ref := env.ReferenceOf(
-			operand.(*Identifier).Symbol,
-			operand_pos.line,
-			operand_pos.col,
-			operand_pos.count,
-			env.sourcePath,
-			r,
-		)
-		Memory.set(ref, MK_NUMBER(value))
-	}
-	return MK_NUMBER(number)
+	return MK_NUMBER(value), MK_NUMBER(number)
 }
 
 func (r *Interpreter) Eval_member_expr(expr *MemberExpr, env *Environment) RuntimeVal {
-	ml := r.Get_Member(expr, env)
-	if len(ml) == 0 {
-		return undefined
+	return r.memberValue(r.Get_Member(expr, env), env, expr.Pos)
+}
+
+func (r *Interpreter) Get_Member(expr *MemberExpr, env *Environment) Ref {
+	if _, ok := expr.object.(*SuperRef); ok {
+		return r.superRef(expr, env)
 	}
-	return Memory.get(ml)
+	return r.memberOf(r.Evaluate(expr.object, env), expr, env)
+}
+
+// the reference of the property expr reads from object_value
+func (r *Interpreter) memberOf(object_value RuntimeVal, expr *MemberExpr, env *Environment) Ref {
+	return r.MemberRef(object_value, r.memberKey(expr, env), expr.computed, getPosFromNode(expr.property), env)
 }
 
-func (r *Interpreter) Get_Member(expr *MemberExpr, env *Environment) string {
-	object_value := r.Evaluate(expr.object, env)
+// the key of the property expr reads, a computed one is evaluated
+func (r *Interpreter) memberKey(expr *MemberExpr, env *Environment) RuntimeVal {
 	computed_property, property := GetMemberExprProp(expr, r, env)
-	var prop RuntimeVal
 	if expr.computed {
-		prop = computed_property
-	} else {
-		prop = MK_STRING(property)
+		return computed_property
+	}
+	return MK_STRING(property)
+}
+
+// the reference of the property an assignment of value to expr stores in, nil when the
+// property does not exist yet; done reports that value was stored as the length of an array
+func (r *Interpreter) assignedMember(expr *MemberExpr, value RuntimeVal, env *Environment, pos Pos) (ml Ref, done bool) {
+	if _, ok := expr.object.(*SuperRef); ok {
+		return r.superRef(expr, env), false
+	}
+	object, key := r.Evaluate(expr.object, env), r.memberKey(expr, env)
+	if arr, ok := object.(*ArrayVal); ok && arr.assignLength(key, value, env, pos) {
+		return nil, true
+	}
+	return r.MemberRef(object, key, expr.computed, getPosFromNode(expr.property), env), false
+}
+
+// returns the reference of a property, nil when it does not exist
+func (r *Interpreter) MemberRef(object_value RuntimeVal, prop RuntimeVal, computed bool, pos Pos, env *Environment) Ref {
+	computed_property := prop
+	property := ""
+	if !computed {
+		property = prop.(*StringVal).value
 	}
-	pos := getPosFromNode(expr.property)
 	switch v := object_value.(type) {
 	case *ObjectVal:
 		ml := v.properties.get(prop)
 		return ml
 	case *ArrayVal:
-		if !expr.computed {
+		if key, ok := prop.(*StringVal); ok {
+			if key.value == "length" {
+				return Memory.alloc(MK_NUMBER(float64(v.elements.length)))
+			}
+			if method := v.method(key.value); method != nil {
+				return Memory.alloc(method)
+			}
+		}
+		if !computed {
 			env.ThrowTypeError(
 				"cannot read properties of type array (reading", property+")",
 				SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
@@ -1393,26 +1629,51 @@ func (r *Interpreter) Get_Member(expr *MemberExpr, env *Environment) string {
 				SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
 			)
 		}
-		// always number
-		index := computed_property.Value().(float64)
-		ml := v.elements.slice[int(index)]
-		if len(ml) == 0 {
-			return ud_ref
+		// always number, a negative index counts from the end
+		index := int(computed_property.Value().(float64))
+		if index < 0 {
+			index += v.elements.length
+		}
+		if index < 0 || index >= v.elements.length {
+			return nil
+		}
+		// nil for a hole
+		return v.elements.slice[index]
+	case *NumberVal, *BigIntVal:
+		if key, ok := prop.(*StringVal); ok {
+			// the native methods of the primitive
+			if method := v.(interface{ method(string) *Macro }).method(key.value); method != nil {
+				return Memory.alloc(method)
+			}
 		}
-		return ml
-	case *NullVal, *Undefined, *NumberVal, *BoolVal:
+		env.ThrowTypeError(
+			"cannot read properties of type", ValueType(v), "(reading", prop.noAnsi()+")",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
+		)
+	case *NullVal, *Undefined, *BoolVal:
 		env.ThrowTypeError(
 			"cannot read properties of type", ValueType(v), "(reading", prop.noAnsi()+")",
 			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
 		)
 	case *Instance:
 		ml := v.properties.get(prop)
-		if len(ml) == 0 && !expr.computed {
-			ml = GetPropMlFromProto(prop, v.prototype)
+		if ml != nil {
+			class, _ := Memory.get(v.class).(*ClassVal)
+			r.checkPrivate(prop, class, false, env, pos)
+		} else if !computed {
+			ml = r.protoRef(prop, v.prototype, env, pos)
 		}
 		return ml
 	case *StringVal:
-		if !expr.computed {
+		if key, ok := prop.(*StringVal); ok {
+			if key.value == "length" {
+				return Memory.alloc(MK_NUMBER(float64(utf8.RuneCountInString(v.value))))
+			}
+			if method := v.method(key.value); method != nil {
+				return Memory.alloc(method)
+			}
+		}
+		if !computed {
 			env.ThrowTypeError(
 				"cannot read properties of type string (reading", prop.noAnsi()+")",
 				SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
@@ -1426,25 +1687,21 @@ func (r *Interpreter) Get_Member(expr *MemberExpr, env *Environment) string {
 				SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""),
 			)
 		}
-		i := int(index)
-		if i >= len(v.value) {
-			return ud_ref
-		} else if i < 0 {
-			i += len(v.value)
+		// the code point at index, a negative index counts from the end
+		char, ok := codePointAt(v.value, int(index))
+		if !ok {
+			return nil
 		}
-		char := string(v.value[i])
-		ml := GenerateRadix(16)
-		Memory.set(ml, MK_STRING(char))
-		return ml
+		return Memory.alloc(MK_STRING(string(char)))
 	case *FunctionVal:
 		ml := v.properties.get(prop)
-		if len(ml) == 0 && !expr.computed {
+		if ml == nil && !computed {
 			ml = GetPropMlFromProto(prop, v.prototype)
 		}
 		return ml
 	case *ClassVal:
-		ml := v.properties.get(prop)
-		if len(ml) == 0 && !expr.computed {
+		ml := r.staticRef(v, prop, env, pos)
+		if ml == nil && !computed {
 			ml = GetPropMlFromProto(prop, v.prototype)
 		}
 		return ml
@@ -1463,12 +1720,12 @@ func GetMemberExprProp(expr *MemberExpr, r *Interpreter, env *Environment) (Runt
 	return computed_property, property
 }
 
-func GetPropMlFromProto(prop RuntimeVal, proto RuntimeVal) string {
-	ml := ""
+func GetPropMlFromProto(prop RuntimeVal, proto RuntimeVal) Ref {
+	var ml Ref
 	switch proto := proto.(type) {
 	case *ObjectVal:
 		ml = proto.properties.get(prop)
-		if len(ml) == 0 {
+		if ml == nil {
 			return GetPropMlFromProto(prop, proto.prototype)
 		}
 	}
@@ -1478,7 +1735,7 @@ func GetPropMlFromProto(prop RuntimeVal, proto RuntimeVal) string {
 func (r *Interpreter) Eval_object(object_lit *ObjectLiteral, env *Environment) *ObjectVal {
 	object_env := NewEnv(env, "object", env.sourcePath)
 	object_val := MK_OBJECT(nil, object_env, r)
-	properties := NewMap[string, string]()
+	properties := NewMap[string, Ref]()
 	pos := object_lit.Pos
 	object_lit.properties.forEach(func(k DynamicNode, v Node) {
 		var key RuntimeVal
@@ -1504,8 +1761,7 @@ func (r *Interpreter) Eval_object(object_lit *ObjectLiteral, env *Environment) *
 				v.name = key.noAnsi()
 			}
 		}
-		ml := GenerateRadix(16)
-		Memory.set(ml, value)
+		ml := Memory.alloc(value)
 		object_val.properties.set(key, ml)
 		properties.set(key.noAnsi(), ml)
 	})
@@ -1550,24 +1806,39 @@ func (r *Interpreter) Eval_in_expr(node *InExpr, env *Environment) RuntimeVal {
 }
 
 func (r *Interpreter) Eval_comparison_expr(expr *ComparisonExpr, env *Environment) RuntimeVal {
-	bool := false
 	op := expr.op
 	left := r.Evaluate(expr.left, env)
+	pos := comparisonPos(expr)
+	right := r.Evaluate(expr.right, env)
+	return r.compare(op, left, right, pos, env)
+}
+
+// the position of a comparison spans both operands
+func comparisonPos(expr *ComparisonExpr) Pos {
 	left_pos := getPosFromNode(expr.left)
 	right_pos := getPosFromNode(expr.right)
-	pos := Pos{
+	return Pos{
 		line:  left_pos.line,
 		col:   left_pos.col,
 		count: left_pos.count + right_pos.col + right_pos.count - 5,
 	}
-	right := r.Evaluate(expr.right, env)
+}
+
+func (r *Interpreter) compare(op string, left, right RuntimeVal, pos Pos, env *Environment) RuntimeVal {
+	bool := false
 	lhs_type := ValueType(left)
 	rhs_type := ValueType(right)
-	comparison_op_err_msg := "'" + op + "' operator cannot take operands of type " + lhs_type + " and " + rhs_type +
-		SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")
+	// reads the source, only when the operands are invalid
+	comparison_op_err_msg := func() string {
+		return "'" + op + "' operator cannot take operands of type " + lhs_type + " and " + rhs_type +
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")
+	}
 	lhs := 0.0
 	rhs := 0.0
 	if is_value(op, "<", ">", "<=", ">=") {
+		if lhs_type == "bigint" || rhs_type == "bigint" {
+			return MK_BOOL(compareBigInt(op, left, right, comparison_op_err_msg, env))
+		}
 		lhs = RtvToInt(left, comparison_op_err_msg, env)
 		rhs = RtvToInt(right, comparison_op_err_msg, env)
 	}
@@ -1595,6 +1866,11 @@ func (r *Interpreter) Eval_comparison_expr(expr *ComparisonExpr, env *Environmen
 }
 
 func RtvAreEqual(v1, v2 RuntimeVal) bool {
+	// the same integer can be held by big.Ints that differ inside
+	if n1, ok := v1.(*BigIntVal); ok {
+		n2, ok := v2.(*BigIntVal)
+		return ok && n1.value.Cmp(n2.value) == 0
+	}
 	return reflect.DeepEqual(v1, v2)
 }
 
@@ -1602,6 +1878,8 @@ func ValueType(v RuntimeVal) string {
 	switch v.(type) {
 	case *NumberVal:
 		return "number"
+	case *BigIntVal:
+		return "bigint"
 	case *StringVal:
 		return "string"
 	case *BoolVal:
@@ -1626,6 +1904,8 @@ func ValueType(v RuntimeVal) string {
 		return "array"
 	case *NativeClass:
 		return "class"
+	case *Accessor:
+		return "accessor"
 	default:
 		return "raw"
 		// return "\x1b[3munknown-value\x1b[0m"
@@ -1633,14 +1913,14 @@ func ValueType(v RuntimeVal) string {
 }
 
 // convert values like strings, objects and arrays to AS numbers
-func RtvToInt(runtimeVal RuntimeVal, err_msg string, env *Environment) float64 {
+func RtvToInt(runtimeVal RuntimeVal, err_msg func() string, env *Environment) float64 {
 	switch rtv := runtimeVal.(type) {
 	case *NumberVal:
 		return rtv.value
 	case *StringVal:
 		return float64(len(rtv.value))
 	default:
-		env.ThrowTypeError(err_msg)
+		env.ThrowTypeError(err_msg())
 	}
 	return 0
 }
@@ -1656,51 +1936,39 @@ func (r *Interpreter) Eval_assignment(expr *AssignmentExpr, env *Environment) Ru
 		value = rhs
 	} else {
 		lhs := r.Evaluate(expr.left, env)
-		pos := expr.Pos
 		switch expr.op {
-		case "+=":
-			result := r.add(lhs.Value(), rhs.Value(), ValueType(lhs), ValueType(rhs), pos, env)
-			switch r := result.(type) {
-			case string:
-				value = MK_STRING(r)
-			case float64:
-				value = MK_NUMBER(r)
-			}
-		case "-=":
-			value = MK_NUMBER(r.sub(env, pos, lhs.Value(), rhs.Value()))
-		case "/=":
-			value = MK_NUMBER(r.div(env, pos, lhs.Value(), rhs.Value()))
-		case "*=":
-			value = MK_NUMBER(r.mul(env, pos, lhs.Value(), rhs.Value()))
-		case "%=":
-			value = MK_NUMBER(r.mod(env, pos, lhs.Value(), rhs.Value()))
 		case "??=":
 			// nullish assignment
 			// if the value of lhs is nullish, then assign lhs with rhs
 			// otherwise return lhs
-			if ValIsNullish(lhs) {
-				value = rhs
-			} else {
+			if !ValIsNullish(lhs) {
+				return lhs
+			}
+			value = rhs
+		case "||=":
+			if RtvToBool(lhs) {
+				return lhs
+			}
+			value = rhs
+		case "&&=":
+			if !RtvToBool(lhs) {
 				return lhs
 			}
+			value = rhs
+		default:
+			value = r.compound(expr.op, lhs, rhs, expr.Pos, env)
 		}
 	}
 	switch exp := expr.left.(type) {
 	case *Identifier:
 		env.AssignVar(exp.Symbol, value, expr.line, expr.col, expr.count, env.sourcePath, r)
 	case *MemberExpr:
-		ml := r.Get_Member(exp, env) // member expression is verified
-		if len(ml) == 0 {
-			ml = GenerateRadix(16)
-			o, ok := ResolveMemberObject(exp.object).(*Identifier)
-			if ok {
-				pos := getPosFromNode(o)
-				e := env.ResolveVarEnv(o.Symbol, env, pos.line, pos.col, pos.count, env.sourcePath, r)
-				if e.varTypes.get(o.Symbol) == "static" {
-					env.ThrowSyntaxError("Assignment: to static variable",
-						SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
-				}
-			}
+		ml, done := r.assignedMember(exp, value, env, expr.Pos) // member expression is verified
+		if done {
+			break
+		}
+		if ml == nil {
+			r.checkStaticMember(exp, env)
 			v := r.Evaluate(exp.object, env)
 			computed_prop, prop := GetMemberExprProp(exp, r, env)
 			var key RuntimeVal
@@ -1709,18 +1977,9 @@ func (r *Interpreter) Eval_assignment(expr *AssignmentExpr, env *Environment) Ru
 			} else {
 				key = MK_STRING(prop)
 			}
-			switch object := v.(type) {
-			case *ObjectVal:
-				object.properties.set(key, ml)
-			case *Instance:
-				object.properties.set(key, ml)
-			case *FunctionVal:
-				object.properties.set(key, ml)
-			case *ClassVal:
-				object.properties.set(key, ml)
-			}
+			ml = AddProperty(v, key)
 		}
-		Memory.set(ml, value)
+		r.setMember(ml, value, env, expr.Pos)
 	case *ObjectLiteral:
 		DestructureObjectAssign(rhs, exp, env, r)
 	case *ArrayLiteral:
@@ -1733,6 +1992,75 @@ func (r *Interpreter) Eval_assignment(expr *AssignmentExpr, env *Environment) Ru
 	return value
 }
 
+// properties cannot be added to the objects of static variables
+func (r *Interpreter) checkStaticMember(exp *MemberExpr, env *Environment) {
+	o, ok := ResolveMemberObject(exp.object).(*Identifier)
+	if !ok {
+		return
+	}
+	pos := getPosFromNode(o)
+	e := env.ResolveVarEnv(o.Symbol, env, pos.line, pos.col, pos.count, env.sourcePath, r)
+	if e.varTypes.get(o.Symbol) == "static" {
+		env.ThrowSyntaxError("Assignment: to static variable",
+			SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+}
+
+// adds a property to an object and returns its reference, values
+// that cannot have properties get a reference that nothing refers to
+func AddProperty(v RuntimeVal, key RuntimeVal) Ref {
+	ml := Memory.alloc(undefined)
+	switch object := v.(type) {
+	case *ObjectVal:
+		object.properties.set(key, ml)
+	case *ArrayVal:
+		// the array grows to hold the element
+		if index, ok := key.(*NumberVal); ok && index.value >= 0 {
+			object.set(int(index.value), undefined)
+			return object.elements.slice[int(index.value)]
+		}
+	case *Instance:
+		object.properties.set(key, ml)
+	case *FunctionVal:
+		object.properties.set(key, ml)
+	case *ClassVal:
+		object.properties.set(key, ml)
+	}
+	return ml
+}
+
+// the value of lhs op= rhs
+func (r *Interpreter) compound(op string, lhs, rhs RuntimeVal, pos Pos, env *Environment) RuntimeVal {
+	var value RuntimeVal
+	switch op {
+	case "+=", "-=", "/=", "*=", "%=":
+		if ValueType(lhs) == "bigint" || ValueType(rhs) == "bigint" {
+			return r.bigint(strings.TrimSuffix(op, "="), lhs, rhs, pos, env)
+		}
+	}
+	switch op {
+	case "+=":
+		result := r.add(lhs.Value(), rhs.Value(), ValueType(lhs), ValueType(rhs), pos, env)
+		switch r := result.(type) {
+		case string:
+			value = MK_STRING(r)
+		case float64:
+			value = MK_NUMBER(r)
+		}
+	case "-=":
+		value = MK_NUMBER(r.sub(env, pos, lhs.Value(), rhs.Value()))
+	case "/=":
+		value = MK_NUMBER(r.div(env, pos, lhs.Value(), rhs.Value()))
+	case "*=":
+		value = MK_NUMBER(r.mul(env, pos, lhs.Value(), rhs.Value()))
+	case "%=":
+		value = MK_NUMBER(r.mod(env, pos, lhs.Value(), rhs.Value()))
+	case "**=", "&=", "|=", "^=", "<<=", ">>=", ">>>=":
+		value = r.binary(strings.TrimSuffix(op, "="), lhs, rhs, pos, env)
+	}
+	return value
+}
+
 func ResolveMemberObject(node Node) Node {
 	n, ok := node.(*MemberExpr)
 	if !ok {
@@ -1768,11 +2096,19 @@ func (r *Interpreter) Eval_void_expr(expr *VoidExpr, env *Environment) RuntimeVa
 func (r *Interpreter) Eval_binary_expr(expr *BinaryExpr, env *Environment) RuntimeVal {
 	v1 := r.Evaluate(expr.left, env)
 	v2 := r.Evaluate(expr.right, env)
+	return r.binary(expr.op, v1, v2, expr.Pos, env)
+}
+
+func (r *Interpreter) binary(op string, v1, v2 RuntimeVal, pos Pos, env *Environment) RuntimeVal {
+	_, big1 := v1.(*BigIntVal)
+	_, big2 := v2.(*BigIntVal)
+	if big1 || big2 {
+		return r.bigint(op, v1, v2, pos, env)
+	}
 	lhs := v1.Value()
 	rhs := v2.Value()
-	pos := expr.Pos
 	var value RuntimeVal
-	switch expr.op {
+	switch op {
 	case "+":
 		v := r.add(lhs, rhs, ValueType(v1), ValueType(v2), pos, env)
 		switch v := v.(type) {
@@ -1796,6 +2132,8 @@ func (r *Interpreter) Eval_binary_expr(expr *BinaryExpr, env *Environment) Runti
 	case "**":
 		v := r.exp(lhs, rhs, env, pos)
 		value = &NumberVal{v}
+	case "&", "|", "^", "<<", ">>", ">>>":
+		value = r.bitwise(op, v1, v2, pos, env)
 	}
 	return value
 }
@@ -1809,7 +2147,7 @@ func (r *Interpreter) add(n1, n2 any, t1, t2 string, pos Pos, env *Environment)
 		found_string = true
 		value = v
 	case float64:
-		value = fmt.Sprint(v)
+		value = formatNumber(v)
 	default:
 		env.ThrowTypeError(fmt.Sprintf("'+' operation between type %s and %s is invalid.%s", t1, t2, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
 	}
@@ -1819,7 +2157,7 @@ func (r *Interpreter) add(n1, n2 any, t1, t2 string, pos Pos, env *Environment)
 		value += v
 	case float64:
 		if found_string {
-			value += fmt.Sprint(v)
+			value += formatNumber(v)
 		} else {
 			float, _ := strconv.ParseFloat(value, 64)
 			value = fmt.Sprint(float + v)
@@ -1923,6 +2261,86 @@ func (r *Interpreter) exp(v1, v2 any, env *Environment, pos Pos) float64 {
 	return math.Pow(value, f2)
 }
 
+// converts a number to a 32 bit integer the way the bitwise operators do
+func toInt32(n float64) int32 {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0
+	}
+	n = math.Mod(math.Trunc(n), 1<<32)
+	if n < 0 {
+		n += 1 << 32
+	}
+	return int32(uint32(n))
+}
+
+// the value of the bitwise and shift operators, both operands are converted to 32 bit integers
+func (r *Interpreter) bitwise(op string, v1, v2 RuntimeVal, pos Pos, env *Environment) RuntimeVal {
+	n1, ok := v1.(*NumberVal)
+	n2, v2ok := v2.(*NumberVal)
+	if !ok || !v2ok {
+		env.ThrowTypeError(fmt.Sprintf("'%s' operation between type %s and %s is invalid.%s", op, ValueType(v1), ValueType(v2), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	a, b := toInt32(n1.value), toInt32(n2.value)
+	// only the lowest 5 bits of the count of a shift are used
+	shift := uint32(b) & 31
+	switch op {
+	case "&":
+		return MK_NUMBER(float64(a & b))
+	case "|":
+		return MK_NUMBER(float64(a | b))
+	case "^":
+		return MK_NUMBER(float64(a ^ b))
+	case "<<":
+		return MK_NUMBER(float64(a << shift))
+	case ">>":
+		return MK_NUMBER(float64(a >> shift))
+	default:
+		// >>> shifts in zeros, the result is unsigned
+		return MK_NUMBER(float64(uint32(a) >> shift))
+	}
+}
+
+func (r *Interpreter) Eval_bitwise_not(expr *BitwiseNotExpr, env *Environment) RuntimeVal {
+	value := r.Evaluate(expr.operand, env)
+	if n, ok := value.(*BigIntVal); ok {
+		return MK_BIGINT(new(big.Int).Not(n.value))
+	}
+	n, ok := value.(*NumberVal)
+	if !ok {
+		env.ThrowTypeError(fmt.Sprintf("'~' operation on type %s is invalid.%s", ValueType(value), SourceLog(expr.line, expr.col, expr.count, env.sourcePath, "")))
+	}
+	return MK_NUMBER(float64(^toInt32(n.value)))
+}
+
+func (r *Interpreter) Eval_optional_chain(expr *OptionalChain, env *Environment) RuntimeVal {
+	value, _ := r.evalChainLink(expr.expr, env)
+	return value
+}
+
+// evaluates a member access or call of an optional chain, ok is false when the
+// object of a ?. in it was nullish, which makes the rest of the chain undefined
+func (r *Interpreter) evalChainLink(node Node, env *Environment) (RuntimeVal, bool) {
+	switch node := node.(type) {
+	case *MemberExpr:
+		if _, ok := node.object.(*SuperRef); ok {
+			return r.Eval_member_expr(node, env), true
+		}
+		object, ok := r.evalChainLink(node.object, env)
+		if !ok || node.optional && ValIsNullish(object) {
+			return undefined, false
+		}
+		return r.memberValue(r.memberOf(object, node, env), env, node.Pos), true
+	case *CallExpr:
+		caller, ok := r.evalChainLink(node.caller, env)
+		if !ok || node.optional && ValIsNullish(caller) {
+			return undefined, false
+		}
+		rv, _ := CallFunction(caller, env, r.eval_args(node.args, env), r, node.Pos)
+		return rv, true
+	}
+	return r.Evaluate(node, env), true
+}
+
 // #region Runtime Func
 
 // Create A new Runtime
@@ -1932,13 +2350,11 @@ func NewRuntime() *Interpreter {
 		terminated:             false,
 		_break:                 false,
 		_continue:              false,
-		MemThreshold:           100 * 1024 * 1024, // 100MB
+		_goto:                  false,
 		CallStack:              NewStack(),
-		microTaskQueue: &MicroTaskQueue{
-			queue:  []Task{},
-			length: 0,
-		},
-		exports: NewMap[RuntimeVal, string](),
+		microTaskQueue:         eventLoop.microTasks,
+		exports:                NewMap[RuntimeVal, Ref](),
+		chunks:                 &sync.Map{},
 	}
 }
 
@@ -1984,21 +2400,19 @@ func GetGlobalEnv(r *Interpreter) *Environment {
 type Environment struct {
 	parent *Environment
 	// key: variable identifier, value: reference
-	variables *Map[string, string]
+	variables *Map[string, Ref]
 	// key: variable identifier, value: type ("constant" | "mutable" | "static" | "var")
 	varTypes *Map[string, string]
 	// ("global", "script", "block", "function")
 	_type      string
 	sourcePath string
-	// Error handling ...
-	catch_block   []Node
-	catch_param   Node
-	finally_block []Node
+	// the class whose body this is, private members of it are accessible from here
+	class *ClassVal
 }
 
 // get all variable names and references from the current scope to the global scope
-func (env *Environment) all() *Map[string, string] {
-	vars := NewMap[string, string]()
+func (env *Environment) all() *Map[string, Ref] {
+	vars := NewMap[string, Ref]()
 	vars.copy(env.variables)
 	if env.parent != nil {
 		vars.copy(env.parent.all())
@@ -2010,7 +2424,7 @@ func (env *Environment) DeleteVar(
 	symbol string,
 	line, col, count int, path string,
 	r *Interpreter,
-) string {
+) Ref {
 	decl_env := env.parent.ResolveVarEnv(symbol, env, line, col, count, path, r)
 	ml := decl_env.ReferenceOf(symbol, line, col, count, path, r)
 	decl_env.varTypes.delete(symbol)
@@ -2022,7 +2436,7 @@ func (env *Environment) ReferenceOf(
 	varname string,
 	line, col, count int, path string,
 	r *Interpreter,
-) string {
+) Ref {
 	e := env.ResolveVarEnv(varname, env, line, col, count, path, r)
 	return e.variables.get(varname)
 }
@@ -2033,27 +2447,20 @@ func (env *Environment) DeclareVar(
 	r *Interpreter,
 ) RuntimeVal {
 	if env.variables.has(varname) {
-		env.ThrowSyntaxError("cannot redeclare " + env.varTypes.get(varname) + " variable " + varname +
-			SourceWithinRange(path, line, col, count, "") +
-			SourceAtPosition(path, line, col))
+		env.ThrowRedeclaration(varname, env.varTypes.get(varname), line, col, count, path)
 	}
-	ml := GenerateRadix(16)
-	env.variables.set(varname, ml)
+	env.variables.set(varname, Memory.alloc(value))
 	env.varTypes.set(varname, _type)
-	Memory.set(ml, value)
 	return value
 }
 
-func (env *Environment) DeclareVarRef(name string, value RuntimeVal, _type string, line int, col int, count int, path string, r *Interpreter) (string, RuntimeVal) {
+func (env *Environment) DeclareVarRef(name string, value RuntimeVal, _type string, line int, col int, count int, path string, r *Interpreter) (Ref, RuntimeVal) {
 	if env.variables.has(name) {
-		env.ThrowSyntaxError("cannot redeclare " + env.varTypes.get(name) + " variable " + name +
-			SourceWithinRange(path, line, col, count, "") +
-			SourceAtPosition(path, line, col))
+		env.ThrowRedeclaration(name, env.varTypes.get(name), line, col, count, path)
 	}
-	ml := GenerateRadix(16)
+	ml := Memory.alloc(value)
 	env.variables.set(name, ml)
 	env.varTypes.set(name, _type)
-	Memory.set(ml, value)
 	return ml, value
 }
 
@@ -2064,9 +2471,7 @@ func (env *Environment) AssignVar(
 ) RuntimeVal {
 	e := env.ResolveVarEnv(varname, env, line, col, count, path, r)
 	if is_value(env.varTypes.get(varname), "constant", "static") {
-		env.ThrowSyntaxError("assignment to " + env.varTypes.get(varname) + " variable: \x1b[34m" + varname + "\x1b[0m" +
-			SourceWithinRange(path, line, col, count, "") +
-			SourceAtPosition(path, line, col))
+		env.ThrowConstantAssignment(varname, env.varTypes.get(varname), line, col, count, path)
 	}
 	ml := e.variables.get(varname)
 	Memory.set(ml, value)
@@ -2112,56 +2517,44 @@ func (env *Environment) ResolveEnv(_type string, r *Interpreter) *Environment {
 	return nil
 }
 
+func (env *Environment) ThrowRedeclaration(name, _type string, line, col, count int, path string) {
+	env.ThrowSyntaxError("cannot redeclare " + _type + " variable " + name +
+		SourceWithinRange(path, line, col, count, "") +
+		SourceAtPosition(path, line, col))
+}
+
+func (env *Environment) ThrowConstantAssignment(name, _type string, line, col, count int, path string) {
+	env.ThrowSyntaxError("assignment to " + _type + " variable: \x1b[34m" + name + "\x1b[0m" +
+		SourceWithinRange(path, line, col, count, "") +
+		SourceAtPosition(path, line, col))
+}
+
 func (env *Environment) ThrowSyntaxError(message ...string) {
-	print("\x1b[31mSyntaxError\x1b[0m: ")
-	env.throwError(message)
+	env.raise("SyntaxError", message)
 }
 
 func (env *Environment) ThrowReferenceError(message ...string) {
-	print("\x1b[31mReferenceError\x1b[0m: ")
-	env.throwError(message)
+	env.raise("ReferenceError", message)
 }
 
 func (env *Environment) ThrowTypeError(s ...string) {
-	print("\x1b[31mTypeError\x1b[0m: ")
-	env.throwError(s)
+	env.raise("TypeError", s)
 }
 
-// func (env *Environment) throwRuntimeError(message string) {
-// 	print("\x1b[31mRuntimeError\x1b[0m: ")
-// 	env.throwError(message)
-// }
+func (env *Environment) ThrowRangeError(s ...string) {
+	env.raise("RangeError", s)
+}
 
+// throws the value to the nearest try block,
+// the process only terminates if no try block catches it
 func (env *Environment) throwValue(value RuntimeVal, r *Interpreter) {
-	if try_ := env.ResolveEnv("try", r); try_ != nil {
-		r.terminated = false
-		catch_block := NewEnv(try_.parent, "block", env.sourcePath)
-		// always identifier (for now)
-		ident := try_.catch_param.(*Identifier)
-		pos := getPosFromNode(ident)
-		catch_block.DeclareVar(
-			ident.Symbol,
-			value,
-			"mutable",
-			pos.line,
-			pos.col,
-			pos.count,
-			try_.sourcePath,
-			r,
-		)
-		r.EvalBlock(try_.catch_block, catch_block)
-		return
-	}
-	print("Uncaught \x1b[31mError\x1b[0m: ")
-	PrintRtv(value)
-	os.Exit(1)
+	panic(&ThrownError{value: value, env: env})
 }
 
-// errors thrown with this cannot be caught
+// throws an instance of Error with the joined message
 func (env *Environment) throwError(message []string,
 ) {
-	print(JoinSlice(message, " "), "\r\n")
-	os.Exit(1)
+	env.raise("Error", message)
 }
 
 func CreateScriptEnv(r *Interpreter, path string) *Environment {
@@ -2173,9 +2566,9 @@ func CreateScriptEnv(r *Interpreter, path string) *Environment {
 	return script
 }
 
-func SCOPE_OBJECT(obj *Map[string, string]) *ObjectVal {
-	props := NewMap[RuntimeVal, string]()
-	obj.forEach(func(key, value string) {
+func SCOPE_OBJECT(obj *Map[string, Ref]) *ObjectVal {
+	props := NewMap[RuntimeVal, Ref]()
+	obj.forEach(func(key string, value Ref) {
 		props.set(MK_STRING(key), value)
 	})
 	return &ObjectVal{
@@ -2188,7 +2581,7 @@ func SCOPE_OBJECT(obj *Map[string, string]) *ObjectVal {
 func NewEnv(parent *Environment, _type, path string) *Environment {
 	return &Environment{
 		parent:    parent,
-		variables: NewMap[string, string](),
+		variables: NewMap[string, Ref](),
 		varTypes:  NewMap[string, string](),
 		_type:     _type, sourcePath: path,
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"strings"
 )
@@ -26,11 +27,33 @@ func (n *NumberVal) Value() any {
 }
 
 func (n *NumberVal) noAnsi() string {
-	return fmt.Sprint(n.value)
+	return formatNumber(n.value)
 }
 
 func (n *NumberVal) String(_ int, _ string) string {
-	return fmt.Sprintf("\x1b[33m%v\x1b[0m", n.value)
+	return "\x1b[33m" + formatNumber(n.value) + "\x1b[0m"
+}
+
+// BigInts
+type BigIntVal struct {
+	// never changed once the value is made, operators make new ones
+	value *big.Int
+}
+
+func MK_BIGINT(n *big.Int) *BigIntVal {
+	return &BigIntVal{n}
+}
+
+func (n *BigIntVal) Value() any {
+	return n.value
+}
+
+func (n *BigIntVal) noAnsi() string {
+	return n.value.String()
+}
+
+func (n *BigIntVal) String(_ int, _ string) string {
+	return "\x1b[33m" + n.value.String() + "n\x1b[0m"
 }
 
 // Strings
@@ -126,21 +149,23 @@ func (n *NullVal) String(_ int, _ string) string {
 // Object
 type ObjectVal struct {
 	// "own" properties
-	properties *Map[RuntimeVal, string]
+	properties *Map[RuntimeVal, Ref]
 	// a property that every object will inherit.
 	// Value: either null or object
 	prototype RuntimeVal
 	body_env  *Environment
 	r         *Interpreter
 	value     string
+	// the values of the weak collections the object is a key of
+	weakValues weakValues
 }
 
 // (key: property key, value: reference to value)
-type ObjectProps *Map[RuntimeVal, string]
+type ObjectProps *Map[RuntimeVal, Ref]
 
 func MK_OBJECT(props ObjectProps, body_env *Environment, r *Interpreter) *ObjectVal {
 	if props == nil {
-		props = NewMap[RuntimeVal, string]()
+		props = NewMap[RuntimeVal, Ref]()
 	}
 	return &ObjectVal{
 		properties: props,
@@ -166,10 +191,10 @@ func (obj *ObjectVal) String(depth int, sep string) string {
 		return obj.value
 	}
 	debug_symbol := MK_STRING(symbol_table.get("debug").noAnsi())
-	proto_ml := ""
+	var proto_ml Ref
 	if proto, ok := obj.prototype.(*ObjectVal); ok {
 		proto_ml = GetPropMlFromProto(debug_symbol, proto)
-		if len(proto_ml) > 0 {
+		if proto_ml != nil {
 			method, ok := Memory.get(proto_ml).(*FunctionVal)
 			if ok {
 				v, ok := method.Call(obj.body_env, []RuntimeVal{MK_STRING(sep)}, obj.r, Pos{}).(*StringVal)
@@ -185,7 +210,7 @@ func (obj *ObjectVal) String(depth int, sep string) string {
 	props := [][]any{}
 	for i := 0; i < len(object); i++ {
 		k := object[i][0].(RuntimeVal)
-		prop := Memory.get(object[i][1].(string))
+		prop := Memory.get(object[i][1].(Ref))
 		props = append(props, []any{
 			k.String(depth, ""),
 			prop.String(depth+1, sep),
@@ -222,7 +247,7 @@ func (obj *ObjectVal) String(depth int, sep string) string {
 }
 
 type ArrayValue struct {
-	slice  []string
+	slice  []Ref
 	length int
 }
 
@@ -230,9 +255,11 @@ type ArrayValue struct {
 type ArrayVal struct {
 	elements ArrayValue
 	value    string
+	// the values of the weak collections the array is a key of
+	weakValues weakValues
 }
 
-func (arr *ArrayVal) getRef(index int) string {
+func (arr *ArrayVal) getRef(index int) Ref {
 	if index >= arr.elements.length {
 		return ud_ref
 	}
@@ -240,21 +267,23 @@ func (arr *ArrayVal) getRef(index int) string {
 	return ml
 }
 
+// the value of the element at index, undefined when there is none
 func (arr *ArrayVal) get(index int) RuntimeVal {
-	if index >= arr.elements.length {
+	if index < 0 || index >= arr.elements.length {
 		return undefined
 	}
-	ml := arr.elements.slice[index]
-	return Memory.get(ml)
+	if value := Memory.get(arr.elements.slice[index]); value != nil {
+		return value
+	}
+	return undefined
 }
 
 func (arr *ArrayVal) set(index int, value RuntimeVal) RuntimeVal {
-	ml := GenerateRadix(16)
-	Memory.set(ml, value)
+	ml := Memory.alloc(value)
 	if index >= arr.elements.length {
 		arr.elements.length = index + 1
-		for i := index; i >= index; i-- {
-			arr.elements.slice = append(arr.elements.slice, "")
+		for len(arr.elements.slice) <= index {
+			arr.elements.slice = append(arr.elements.slice, nil)
 		}
 	}
 	arr.elements.slice[index] = ml
@@ -286,11 +315,9 @@ func (arr *ArrayVal) Push(elements ...RuntimeVal) *ArrayVal {
 	// do not use range over loop
 	for i := 0; i < len(elements); i++ {
 		el := elements[i]
-		ml := GenerateRadix(16)
 		*length++
-		arr.elements.slice = append(arr.elements.slice, ml)
+		arr.elements.slice = append(arr.elements.slice, Memory.alloc(el))
 		index++
-		Memory.set(ml, el)
 	}
 	return arr
 }
@@ -410,6 +437,7 @@ type FunctionVal struct {
 	declEnv   *Environment
 	r         *Interpreter
 	async     bool
+	generator bool
 	anonymous bool
 	arrow     bool
 }
@@ -418,7 +446,7 @@ func MK_FUNCTION(name string, body, params []Node, declEnv *Environment, async,
 	fn_val := &FunctionVal{
 		ObjectVal: &ObjectVal{
 			value:      "\x1b[36m[function]\x1b[0m",
-			properties: NewMap[RuntimeVal, string](),
+			properties: NewMap[RuntimeVal, Ref](),
 			prototype:  MK_OBJECT(nil, declEnv, nil),
 		},
 		name:      name,
@@ -459,7 +487,10 @@ type ClassVal struct {
 	fields    []*ClassProperty
 	methods   []*ClassMethod
 	declEnv   *Environment
-	extends   string
+	extends   Ref
+	// names of the private members, key: name
+	private       map[string]bool
+	staticPrivate map[string]bool
 }
 
 func MK_CLASS(
@@ -467,13 +498,13 @@ func MK_CLASS(
 	ctor *Constructor,
 	props []*ClassProperty, methods []*ClassMethod,
 	declEnv *Environment,
-	extends string,
+	extends Ref,
 	r *Interpreter,
 ) *ClassVal {
 	class := &ClassVal{
 		ObjectVal: &ObjectVal{
 			value:      "\x1b[36m[class]\x1b[0m",
-			properties: NewMap[RuntimeVal, string](),
+			properties: NewMap[RuntimeVal, Ref](),
 			prototype:  MK_OBJECT(nil, nil, r),
 		},
 		name:    name,
@@ -482,6 +513,17 @@ func MK_CLASS(
 		methods: methods,
 		declEnv: declEnv,
 		ctor:    ctor,
+		private: map[string]bool{},
+	}
+	for _, prop := range props {
+		if prop.private {
+			class.private[prop.name] = true
+		}
+	}
+	for _, method := range methods {
+		if ident, ok := method.name.node.(*Identifier); ok && method.private && !method.name.dynamic {
+			class.private[ident.Symbol] = true
+		}
 	}
 	return class
 }
@@ -508,23 +550,23 @@ type NativeClass struct {
 	name string
 	ctor *Macro
 	// key: identifier, value: 0x0
-	properties *Map[string, string]
+	properties *Map[string, Ref]
 	methods    []*Macro
 	declEnv    *Environment
-	extends    string
+	extends    Ref
 }
 
 func MK_NT_CLASS(
 	name string,
 	ctor *Macro,
-	props *Map[string, string], methods []*Macro,
+	props *Map[string, Ref], methods []*Macro,
 	declEnv *Environment,
-	extends string,
+	extends Ref,
 ) *NativeClass {
 	class := &NativeClass{
 		ObjectVal: &ObjectVal{
 			value:      "\x1b[36m[class]\x1b[0m",
-			properties: NewMap[RuntimeVal, string](),
+			properties: NewMap[RuntimeVal, Ref](),
 			prototype:  MK_OBJECT(nil, nil, nil),
 		},
 		name:       name,
@@ -553,28 +595,53 @@ func (class *NativeClass) String(_ int, _ string) string {
 	return "\x1b[36m[class " + name + "]\x1b[0m"
 }
 
+// Accessor is a class member defined by a getter, a setter or both.
+type Accessor struct {
+	name string
+	get  *FunctionVal
+	set  *FunctionVal
+}
+
+func (a *Accessor) Value() any {
+	return a.name
+}
+
+func (a *Accessor) noAnsi() string {
+	switch {
+	case a.get != nil && a.set != nil:
+		return "[Getter/Setter]"
+	case a.get != nil:
+		return "[Getter]"
+	}
+	return "[Setter]"
+}
+
+func (a *Accessor) String(_ int, _ string) string {
+	return "\x1b[36m" + a.noAnsi() + "\x1b[0m"
+}
+
 // Instance
 type Instance struct {
 	*ObjectVal
 	name     string
-	_default string
+	_default Ref
 	// reference to it's constructor
-	class      string
+	class      Ref
 	r          *Interpreter
 	class_body *Environment
 }
 
 func MK_INSTANCE(
-	name, class string,
-	proto *Map[RuntimeVal, string],
-	_default string,
+	name string, class Ref,
+	proto *Map[RuntimeVal, Ref],
+	_default Ref,
 	class_body *Environment,
 	r *Interpreter,
 ) *Instance {
 	instance := &Instance{
 		ObjectVal: &ObjectVal{
 			value:      "\x1b[35m[object Instance]\x1b[0m",
-			properties: NewMap[RuntimeVal, string](),
+			properties: NewMap[RuntimeVal, Ref](),
 			prototype:  MK_OBJECT(proto, class_body, r),
 		},
 		name:       name,
@@ -599,10 +666,10 @@ func (i *Instance) String(depth int, sep string) string {
 		return "\x1b[36m[" + i.name + "]\x1b[0m"
 	}
 	debug_symbol := MK_STRING(symbol_table.get("debug").noAnsi())
-	proto_ml := ""
+	var proto_ml Ref
 	if proto, ok := i.prototype.(*ObjectVal); ok {
 		proto_ml = GetPropMlFromProto(debug_symbol, proto)
-		if len(proto_ml) > 0 {
+		if proto_ml != nil {
 			method, ok := Memory.get(proto_ml).(*FunctionVal)
 			if ok {
 				rv := method.Call(i.class_body, []RuntimeVal{MK_STRING(sep)}, i.r, Pos{})
@@ -613,7 +680,7 @@ func (i *Instance) String(depth int, sep string) string {
 			}
 		}
 	}
-	if len(i.properties.get(debug_symbol)) > 0 {
+	if i.properties.get(debug_symbol) != nil {
 		method, ok := Memory.get(i.properties.get(debug_symbol)).(*FunctionVal)
 		if ok {
 			rv := method.Call(i.class_body, []RuntimeVal{MK_STRING(sep)}, i.r, Pos{})
@@ -629,7 +696,7 @@ func (i *Instance) String(depth int, sep string) string {
 	props := [][]any{}
 	for i := 0; i < len(object); i++ {
 		k := object[i][0].(RuntimeVal)
-		prop := Memory.get(object[i][1].(string))
+		prop := Memory.get(object[i][1].(Ref))
 		props = append(props, []any{
 			k.String(depth, ""),
 			prop.String(depth+1, sep),
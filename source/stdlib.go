@@ -0,0 +1,46 @@
+package main
+
+import (
+	"embed"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// the standard library is built into the binary, its files have
+// paths like "std:http.as"
+//
+//go:embed stdlib/*.as stdlib/verdex.js stdlib/verdex
+var stdlibFS embed.FS
+
+const STD_PREFIX = "std:"
+
+func IsStdPath(path string) bool {
+	return strings.HasPrefix(path, STD_PREFIX)
+}
+
+// returns the path of a file of the standard library, in the
+// --stdlib directory when there is one
+func StdPath(name string) string {
+	if stdlibDir != "" {
+		return filepath.Join(AbsPath(stdlibDir), filepath.FromSlash(name))
+	}
+	return STD_PREFIX + path.Clean(name)
+}
+
+// returns the name of a std: path in stdlibFS
+func stdFile(std_path string) string {
+	return "stdlib/" + strings.TrimPrefix(std_path, STD_PREFIX)
+}
+
+// resolves the path of an import in the module at base,
+// "std:http" imports http.as from the standard library
+func ResolveImport(base, target string) string {
+	if name, ok := strings.CutPrefix(target, STD_PREFIX); ok {
+		if path.Ext(name) == "" {
+			name += ".as"
+		}
+		target = StdPath(name)
+	}
+	return RealPath(RelativePathToFile(base, target))
+}
@@ -32,16 +32,9 @@ class Array {
     setLength()
   }
 
-  private function [Symbol.iterator]() {
-    spawn i = 0
-    spawn self = this
-    return {
-      next: () => {
-        return {
-          done: i >= self.length,
-          value: self[i++]
-        }
-      }
+  private function* [Symbol.iterator]() {
+    for (i = 0; i < this.length; i++) {
+      yield this[i]
     }
   }
 }
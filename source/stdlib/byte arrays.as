@@ -19,21 +19,9 @@ class Uint8Array {
     }
   }
 
-  function [Symbol.iterator]() {
-    spawn self = this
-    spawn i = 0
-    return {
-      next: () => {
-        spawn done = i >= self.length
-        spawn value = undefined
-        if (!done) {
-          value = #_byte_at(self.bytes, i++)
-        }
-        return {
-          done,
-          value
-        }
-      }
+  function* [Symbol.iterator]() {
+    for (i = 0; i < this.length; i++) {
+      yield #_byte_at(this.bytes, i)
     }
   }
 
@@ -0,0 +1,185 @@
+$ parameters are copies of the arguments, the elements of a rest parameter
+$ are not, so keys are taken as ...args to keep the identity of objects
+
+class Map {
+  private #state = #_collection_state("Map")
+
+  constructor(entries) {
+    #_collection_init(this, entries)
+  }
+
+  get size() {
+    return #_collection_size(this)
+  }
+
+  function get(...args) {
+    return #_collection_get(this, args[0])
+  }
+
+  function set(...args) {
+    #_collection_set(this, args[0], args[1])
+    return this
+  }
+
+  function has(...args) {
+    return #_collection_has(this, args[0])
+  }
+
+  function delete(...args) {
+    return #_collection_delete(this, args[0])
+  }
+
+  function clear() {
+    #_collection_clear(this)
+  }
+
+  function forEach(callback) {
+    #_collection_for_each(this, callback)
+  }
+
+  function keys() {
+    return new CollectionIterator(this, "keys")
+  }
+
+  function values() {
+    return new CollectionIterator(this, "values")
+  }
+
+  function entries() {
+    return new CollectionIterator(this, "entries")
+  }
+
+  function [Symbol.iterator]() {
+    return new CollectionIterator(this, "entries")
+  }
+
+  function [Symbol.debug]() {
+    return #_collection_debug(this)
+  }
+}
+
+class Set {
+  private #state = #_collection_state("Set")
+
+  constructor(values) {
+    #_collection_init(this, values)
+  }
+
+  get size() {
+    return #_collection_size(this)
+  }
+
+  function add(...args) {
+    #_collection_set(this, args[0], args[0])
+    return this
+  }
+
+  function has(...args) {
+    return #_collection_has(this, args[0])
+  }
+
+  function delete(...args) {
+    return #_collection_delete(this, args[0])
+  }
+
+  function clear() {
+    #_collection_clear(this)
+  }
+
+  function forEach(callback) {
+    #_collection_for_each(this, callback)
+  }
+
+  function keys() {
+    return new CollectionIterator(this, "values")
+  }
+
+  function values() {
+    return new CollectionIterator(this, "values")
+  }
+
+  function entries() {
+    return new CollectionIterator(this, "entries")
+  }
+
+  function [Symbol.iterator]() {
+    return new CollectionIterator(this, "values")
+  }
+
+  function [Symbol.debug]() {
+    return #_collection_debug(this)
+  }
+}
+
+$ the keys of weak collections must be objects, an entry goes away once
+$ nothing else refers to its key; they cannot be iterated
+class WeakMap {
+  private #state = #_collection_state("WeakMap")
+
+  constructor(entries) {
+    #_collection_init(this, entries)
+  }
+
+  function get(...args) {
+    return #_collection_get(this, args[0])
+  }
+
+  function set(...args) {
+    #_collection_set(this, args[0], args[1])
+    return this
+  }
+
+  function has(...args) {
+    return #_collection_has(this, args[0])
+  }
+
+  function delete(...args) {
+    return #_collection_delete(this, args[0])
+  }
+
+  function [Symbol.debug]() {
+    return #_collection_debug(this)
+  }
+}
+
+class WeakSet {
+  private #state = #_collection_state("WeakSet")
+
+  constructor(values) {
+    #_collection_init(this, values)
+  }
+
+  function add(...args) {
+    #_collection_set(this, args[0], true)
+    return this
+  }
+
+  function has(...args) {
+    return #_collection_has(this, args[0])
+  }
+
+  function delete(...args) {
+    return #_collection_delete(this, args[0])
+  }
+
+  function [Symbol.debug]() {
+    return #_collection_debug(this)
+  }
+}
+
+$ returned by keys, values and entries, it is iterable itself
+class CollectionIterator {
+  private #state = #_iterator_state()
+
+  constructor(collection, kind) {
+    #_collection_iterator(this, collection, kind)
+  }
+
+  function next() {
+    return #_iterator_next(this)
+  }
+
+  function [Symbol.iterator]() {
+    return this
+  }
+}
@@ -0,0 +1,79 @@
+class Channel {
+  private #state = #_channel_state()
+
+  constructor(capacity) {
+    #_channel_init(this, capacity)
+  }
+
+  function send(value) {
+    #_channel_send(this, value)
+  }
+
+  function receive() {
+    return #_channel_receive(this).value
+  }
+
+  function next() {
+    return #_channel_receive(this)
+  }
+
+  function close() {
+    #_channel_close(this)
+  }
+
+  function size() {
+    return #_channel_size(this)
+  }
+
+  function capacity() {
+    return #_channel_capacity(this)
+  }
+
+  function [Symbol.iterator]() {
+    return this
+  }
+
+  function [Symbol.debug]() {
+    return "Channel { " + #_channel_size(this) + "/" + #_channel_capacity(this) + " }"
+  }
+}
+
+function select(...cases) {
+  return #_channel_select(cases, true)
+}
+
+function trySelect(...cases) {
+  return #_channel_select(cases, false)
+}
+
+class WaitGroup {
+  private #state = #_wait_group_state()
+
+  function add(delta) {
+    #_wait_group_add(this, delta)
+  }
+
+  function done() {
+    #_wait_group_add(this, -1)
+  }
+
+  function wait() {
+    #_wait_group_wait(this)
+  }
+}
+
+class Mutex {
+  private #state = #_mutex_state()
+
+  function lock() {
+    #_mutex_lock(this)
+  }
+
+  function tryLock() {
+    return #_mutex_try_lock(this)
+  }
+
+  function unlock() {
+    #_mutex_unlock(this)
+  }
+}
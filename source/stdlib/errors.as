@@ -0,0 +1,62 @@
+class Error {
+  public name = "Error"
+  public message = ""
+  public stack = ""
+
+  constructor(message) {
+    message ??= ""
+    this.message = #_to_string(message)
+    this.stack = #_error_stack(this.name, this.message)
+  }
+
+  function toString() {
+    return this.name + ": " + this.message
+  }
+
+  function [Symbol.debug]() {
+    return this.stack
+  }
+}
+
+class TypeError extends Error {
+  constructor(message) {
+    super(message)
+    this.name = "TypeError"
+    this.stack = #_error_stack(this.name, this.message)
+  }
+}
+
+class ReferenceError extends Error {
+  constructor(message) {
+    super(message)
+    this.name = "ReferenceError"
+    this.stack = #_error_stack(this.name, this.message)
+  }
+}
+
+class RangeError extends Error {
+  constructor(message) {
+    super(message)
+    this.name = "RangeError"
+    this.stack = #_error_stack(this.name, this.message)
+  }
+}
+
+class SyntaxError extends Error {
+  constructor(message) {
+    super(message)
+    this.name = "SyntaxError"
+    this.stack = #_error_stack(this.name, this.message)
+  }
+}
+
+class AggregateError extends Error {
+  public errors = []
+  constructor(errors, message) {
+    errors ??= []
+    super(message)
+    this.name = "AggregateError"
+    this.errors = errors
+    this.stack = #_error_stack(this.name, this.message)
+  }
+}
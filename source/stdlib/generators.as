@@ -0,0 +1,49 @@
+$ returned by generator functions, next runs their body until the next yield
+class Generator {
+  private #state = #_generator_state()
+
+  function next(value) {
+    return #_generator_resume(this, "next", value)
+  }
+
+  function ["return"](value) {
+    return #_generator_resume(this, "return", value)
+  }
+
+  function ["throw"](error) {
+    return #_generator_resume(this, "throw", error)
+  }
+
+  function [Symbol.iterator]() {
+    return this
+  }
+
+  function [Symbol.debug]() {
+    return #_generator_inspect(this)
+  }
+}
+
+$ returned by async generator functions, next, return and throw return promises
+class AsyncGenerator {
+  private #state = #_generator_state()
+
+  function next(value) {
+    return #_generator_resume(this, "next", value)
+  }
+
+  function ["return"](value) {
+    return #_generator_resume(this, "return", value)
+  }
+
+  function ["throw"](error) {
+    return #_generator_resume(this, "throw", error)
+  }
+
+  function [Symbol.asyncIterator]() {
+    return this
+  }
+
+  function [Symbol.debug]() {
+    return #_generator_inspect(this)
+  }
+}
@@ -77,7 +77,7 @@ static spawn http = {
 
     function wrapFunc(handler) {
       return function (w, r) {
-        handler(new ResponseWriter(w), new Request(r))
+        return handler(new ResponseWriter(w), new Request(r))
       }
     }
 
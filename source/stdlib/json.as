@@ -0,0 +1,10 @@
+$ maps between JSON text and AS values, the value to stringify is taken
+$ as ...args to keep its identity, which is how cycles are found
+immortal spawn JSON = {
+  parse(text, reviver) {
+    return #_json_parse(text, reviver)
+  }
+  stringify(...args) {
+    return #_json_stringify(...args)
+  }
+}
@@ -0,0 +1,28 @@
+import "symbols.as"
+import "math.as"
+import "errors.as"
+import "promise.as"
+import "generators.as"
+import "timers.as"
+import "concurrency.as"
+import "date.as"
+import "io.as"
+import "code-points.as"
+import "strings.as"
+import "byte arrays.as"
+import "arrays.as"
+import "collections.as"
+import "regexp.as"
+import "json.as"
+import "encoding.as"
+import "http.as"
+import "runtime.as"
+import "verdex.as"
+
+if (runtime.command == "test") {
+  #_run_tests(runtime.args)
+} else if (#_array_length(runtime.args) > 0) {
+  #_run_as_script(runtime.args[0])
+} else {
+  #_start_repl()
+}
\ No newline at end of file
@@ -0,0 +1,21 @@
+immortal spawn Math = #_math()
+
+function Number(...value) {
+  return #_to_number(...value)
+}
+#_number(Number)
+
+function BigInt(value) {
+  return #_to_bigint(value)
+}
+
+immortal spawn NaN = Number.NaN
+immortal spawn Infinity = Number.POSITIVE_INFINITY
+
+function parseInt(text, radix) {
+  return Number.parseInt(text, radix)
+}
+
+function parseFloat(text) {
+  return Number.parseFloat(text)
+}
@@ -0,0 +1,30 @@
+class Promise {
+  private #state = #_promise_state()
+
+  constructor(executor) {
+    #_promise_init(this, executor)
+  }
+
+  function then(onFulfilled, onRejected) {
+    return #_promise_then(this, onFulfilled, onRejected)
+  }
+
+  function ["catch"](onRejected) {
+    return #_promise_then(this, undefined, onRejected)
+  }
+
+  function ["finally"](onFinally) {
+    return #_promise_finally(this, onFinally)
+  }
+
+  function [Symbol.debug]() {
+    return #_promise_inspect(this)
+  }
+}
+
+Promise.resolve = function (value) { return #_promise_resolve(value) }
+Promise.reject = function (reason) { return #_promise_reject(reason) }
+Promise.all = function (promises) { return #_promise_all(promises) }
+Promise.race = function (promises) { return #_promise_race(promises) }
+Promise.any = function (promises) { return #_promise_any(promises) }
+Promise.allSettled = function (promises) { return #_promise_all_settled(promises) }
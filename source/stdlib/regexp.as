@@ -0,0 +1,57 @@
+$ patterns run on Go's regexp package (RE2): matching takes linear time, and
+$ backreferences and lookarounds, which it cannot run in linear time, are rejected
+
+class RegExp {
+  private #state = #_regexp_state()
+  $ where global and sticky regexps search from, in code points
+  lastIndex = 0
+
+  constructor(pattern, flags) {
+    #_regexp_init(this, pattern, flags)
+  }
+
+  get source() {
+    return #_regexp_source(this)
+  }
+
+  get flags() {
+    return #_regexp_flags(this)
+  }
+
+  get global() {
+    return this.flags.includes("g")
+  }
+
+  get ignoreCase() {
+    return this.flags.includes("i")
+  }
+
+  get multiline() {
+    return this.flags.includes("m")
+  }
+
+  get dotAll() {
+    return this.flags.includes("s")
+  }
+
+  get sticky() {
+    return this.flags.includes("y")
+  }
+
+  $ the next match, or null
+  function exec(str) {
+    return #_regexp_exec(this, str)
+  }
+
+  function test(str) {
+    return #_regexp_exec(this, str) != null
+  }
+
+  function toString() {
+    return #_regexp_debug(this)
+  }
+
+  function [Symbol.debug]() {
+    return #_regexp_debug(this)
+  }
+}
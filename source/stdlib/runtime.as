@@ -0,0 +1,5 @@
+
+export static spawn runtime = {
+  args: #_runtime_arguments(),
+  command: #_runtime_command()
+}
\ No newline at end of file
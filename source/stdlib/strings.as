@@ -1,10 +1,12 @@
 
 class String {
   private string = "";
-  private length = 0;
   constructor(value) {
     this.string = #_to_string(value)
-    this.length = #_str_length(this.string)
+  }
+
+  get length() {
+    return #_str_length(this.string)
   }
 
   function at(index) {
@@ -25,6 +27,10 @@ class String {
     return #_slice_str(_from, to, this.string)
   }
 
+  static function fromCodePoint(...codes) {
+    return #_from_code_point(codes)
+  }
+
   function [#_symbol_for("debug")]() {
     return this.string
   }
@@ -7,3 +7,4 @@ function Symbol(symbol) {
 
 Symbol.debug = #_symbol("debug");
 Symbol.iterator = #_symbol("iterator");
+Symbol.asyncIterator = #_symbol("asyncIterator");
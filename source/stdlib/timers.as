@@ -0,0 +1,19 @@
+function setTimeout(callback, delay, ...args) {
+  return #_set_timer(callback, delay, args, false)
+}
+
+function setInterval(callback, delay, ...args) {
+  return #_set_timer(callback, delay, args, true)
+}
+
+function clearTimeout(id) {
+  #_clear_timer(id)
+}
+
+function clearInterval(id) {
+  #_clear_timer(id)
+}
+
+function queueMicrotask(callback) {
+  #_queue_microtask(callback)
+}
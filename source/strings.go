@@ -0,0 +1,438 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// strings are indexed by code point: their length, indexing and the indexes the
+// methods of strings take and return count code points, not bytes or UTF-16 units
+
+// a method every string has, str is the string it is called on
+type stringMethod func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal
+
+// the native methods of strings, they are looked up when a property of a string is read
+var stringMethods map[string]stringMethod
+
+// the method of s named name bound to s, nil when strings have no such method
+func (s *StringVal) method(name string) *Macro {
+	method, ok := stringMethods[name]
+	if !ok {
+		return nil
+	}
+	return MK_MACRO(name, func(args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+		return method(s.value, args, env, pos, r)
+	})
+}
+
+// the code point at index of str, ok is false when index is out of range;
+// a negative index counts from the end
+func codePointAt(str string, index int) (rune, bool) {
+	if index < 0 {
+		index += utf8.RuneCountInString(str)
+		if index < 0 {
+			return 0, false
+		}
+	}
+	for _, char := range str {
+		if index == 0 {
+			return char, true
+		}
+		index--
+	}
+	return 0, false
+}
+
+// the byte offset of the code point at index of str, the length of str when index is past its end
+func byteOffset(str string, index int) int {
+	for offset := range str {
+		if index == 0 {
+			return offset
+		}
+		index--
+	}
+	return len(str)
+}
+
+// the code point index of the byte offset of str
+func runeIndex(str string, offset int) int {
+	return utf8.RuneCountInString(str[:offset])
+}
+
+// the string argument at index of the function name
+func stringArg(args []RuntimeVal, index int, name string, env *Environment, pos Pos) string {
+	s, ok := argAt(args, index).(*StringVal)
+	if !ok {
+		env.ThrowTypeError(fmt.Sprintf("%s expects a string, got type %s%s", name, ValueType(argAt(args, index)), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	return s.value
+}
+
+// the number argument at index of the function name, fallback when it was not passed
+func numberArg(args []RuntimeVal, index int, fallback float64, name string, env *Environment, pos Pos) float64 {
+	switch arg := argAt(args, index).(type) {
+	case *Undefined:
+		return fallback
+	case *NumberVal:
+		return arg.value
+	default:
+		env.ThrowTypeError(fmt.Sprintf("%s expects a number, got type %s%s", name, ValueType(arg), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, "")))
+	}
+	return 0
+}
+
+// replaces the first occurrence of pattern in str, or every one when all is true; the
+// replacement is a string or a function called with the match and its index
+func (r *Interpreter) replaceString(str string, args []RuntimeVal, all bool, name string, env *Environment, pos Pos) string {
+	pattern := stringArg(args, 0, name, env, pos)
+	replacement := func(match string, offset int) string {
+		if fn, ok := argAt(args, 1).(*FunctionVal); ok {
+			rv, _ := CallFunction(fn, env, []RuntimeVal{MK_STRING(match), MK_NUMBER(float64(runeIndex(str, offset)))}, r, pos)
+			return rv.noAnsi()
+		}
+		return stringArg(args, 1, name, env, pos)
+	}
+	result := strings.Builder{}
+	offset := 0
+	for {
+		i := strings.Index(str[offset:], pattern)
+		if i < 0 {
+			break
+		}
+		result.WriteString(str[offset : offset+i])
+		result.WriteString(replacement(pattern, offset+i))
+		offset += i + len(pattern)
+		if !all {
+			break
+		}
+		if pattern == "" {
+			// an empty pattern matches between every code point
+			if offset == len(str) {
+				return result.String()
+			}
+			_, size := utf8.DecodeRuneInString(str[offset:])
+			result.WriteString(str[offset : offset+size])
+			offset += size
+		}
+	}
+	result.WriteString(str[offset:])
+	return result.String()
+}
+
+// the RegExp argument at index of the function name; a string is compiled with flags,
+// there is no instance then, which only exec needs to move the lastIndex of
+func regExpArg(args []RuntimeVal, index int, flags, name string, env *Environment, pos Pos) (*RegExpState, *Instance) {
+	if state, regexp := regExpOf(argAt(args, index)); state != nil {
+		return state, regexp
+	}
+	pattern := stringArg(args, index, name, env, pos)
+	state, err := compileRegExp(pattern, flags)
+	if err != "" {
+		env.ThrowSyntaxError("invalid regular expression /"+pattern+"/"+flags+":", err, SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+	}
+	return state, nil
+}
+
+// pads str to length code points with fill, at the start or at the end
+func pad(str string, args []RuntimeVal, start bool, name string, env *Environment, pos Pos) string {
+	length := int(numberArg(args, 0, 0, name, env, pos))
+	fill := " "
+	if len(args) > 1 {
+		fill = stringArg(args, 1, name, env, pos)
+	}
+	missing := length - utf8.RuneCountInString(str)
+	if missing <= 0 || fill == "" {
+		return str
+	}
+	runes := []rune(strings.Repeat(fill, missing/utf8.RuneCountInString(fill)+1))[:missing]
+	if start {
+		return string(runes) + str
+	}
+	return str + string(runes)
+}
+
+// the text of a value in a formatted string, arrays are joined with commas
+func formatText(value RuntimeVal) string {
+	if _, ok := value.(*ArrayVal); ok {
+		return joinText(value, ",")
+	}
+	return value.noAnsi()
+}
+
+// replaces the placeholders of str: {} is the next argument, {0} the first one,
+// {name} a property of the first argument and {{ and }} are braces
+func (r *Interpreter) format(str string, args []RuntimeVal, env *Environment, pos Pos) string {
+	result := strings.Builder{}
+	next := 0
+	for i := 0; i < len(str); i++ {
+		char := str[i]
+		if char == '}' && i+1 < len(str) && str[i+1] == '}' {
+			result.WriteByte('}')
+			i++
+			continue
+		}
+		if char != '{' {
+			result.WriteByte(char)
+			continue
+		}
+		if i+1 < len(str) && str[i+1] == '{' {
+			result.WriteByte('{')
+			i++
+			continue
+		}
+		end := strings.IndexByte(str[i:], '}')
+		if end < 0 {
+			result.WriteString(str[i:])
+			break
+		}
+		placeholder := str[i+1 : i+end]
+		i += end
+		var value RuntimeVal = undefined
+		if placeholder == "" {
+			value = argAt(args, next)
+			next++
+		} else if index, err := strconv.Atoi(placeholder); err == nil {
+			value = argAt(args, index)
+		} else {
+			switch object := argAt(args, 0).(type) {
+			case *ObjectVal, *Instance:
+				value = r.memberValue(r.MemberRef(object, MK_STRING(placeholder), false, pos, env), env, pos)
+			}
+		}
+		result.WriteString(formatText(value))
+	}
+	return result.String()
+}
+
+func init() {
+	stringMethods = map[string]stringMethod{
+		"at": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			char, ok := codePointAt(str, int(numberArg(args, 0, 0, "string.at", env, pos)))
+			if !ok {
+				return undefined
+			}
+			return MK_STRING(string(char))
+		},
+		"codePointAt": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			index := int(numberArg(args, 0, 0, "string.codePointAt", env, pos))
+			char, ok := codePointAt(str, index)
+			if !ok || index < 0 {
+				return undefined
+			}
+			return MK_NUMBER(float64(char))
+		},
+		"slice": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			length := utf8.RuneCountInString(str)
+			start := indexArg(args, 0, length, 0, "string.slice", env, pos)
+			end := indexArg(args, 1, length, length, "string.slice", env, pos)
+			if end < start {
+				return MK_STRING("")
+			}
+			return MK_STRING(str[byteOffset(str, start):byteOffset(str, end)])
+		},
+		"split": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			limit := math.Inf(1)
+			if _, ok := argAt(args, 1).(*Undefined); !ok {
+				// converted like ToUint32, so -1 is 2^32 - 1 and a negative limit none
+				limit = float64(uint32(toInt32(numberArg(args, 1, 0, "string.split", env, pos))))
+			}
+			if state, _ := regExpOf(argAt(args, 0)); state != nil {
+				return state.split(str, limit)
+			}
+			var parts []string
+			if _, ok := argAt(args, 0).(*Undefined); ok {
+				parts = []string{str}
+			} else {
+				// an empty separator splits the code points
+				parts = strings.Split(str, stringArg(args, 0, "string.split", env, pos))
+			}
+			array := MK_ARRAY()
+			for i := 0; i < len(parts) && float64(i) < limit; i++ {
+				array.Push(MK_STRING(parts[i]))
+			}
+			return array
+		},
+		"replace": func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			if state, regexp := regExpOf(argAt(args, 0)); state != nil {
+				return MK_STRING(r.replaceRegExp(state, regexp, str, args, "string.replace", env, pos))
+			}
+			return MK_STRING(r.replaceString(str, args, false, "string.replace", env, pos))
+		},
+		"replaceAll": func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			if state, regexp := regExpOf(argAt(args, 0)); state != nil {
+				if !state.global {
+					env.ThrowTypeError("string.replaceAll expects a global RegExp, got", state.String(), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+				}
+				return MK_STRING(r.replaceRegExp(state, regexp, str, args, "string.replaceAll", env, pos))
+			}
+			return MK_STRING(r.replaceString(str, args, true, "string.replaceAll", env, pos))
+		},
+		"match": func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			state, regexp := regExpArg(args, 0, "", "string.match", env, pos)
+			if !state.global {
+				if loc := state.exec(regexp, str); loc != nil {
+					return state.result(str, loc, r)
+				}
+				return null
+			}
+			// the text of every match
+			matches := state.findAll(str)
+			SetInstanceMember(regexp, "lastIndex", MK_NUMBER(0))
+			if len(matches) == 0 {
+				return null
+			}
+			array := MK_ARRAY()
+			for _, loc := range matches {
+				array.Push(MK_STRING(str[loc[0]:loc[1]]))
+			}
+			return array
+		},
+		"matchAll": func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			state, _ := regExpArg(args, 0, "g", "string.matchAll", env, pos)
+			if !state.global {
+				env.ThrowTypeError("string.matchAll expects a global RegExp, got", state.String(), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			array := MK_ARRAY()
+			for _, loc := range state.findAll(str) {
+				array.Push(state.result(str, loc, r))
+			}
+			return array
+		},
+		"search": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			state, _ := regExpArg(args, 0, "", "string.search", env, pos)
+			// lastIndex and the global and sticky flags play no part
+			loc := state.find(str, 0)
+			if loc == nil {
+				return MK_NUMBER(-1)
+			}
+			return MK_NUMBER(float64(runeIndex(str, loc[0])))
+		},
+		"indexOf": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			search := stringArg(args, 0, "string.indexOf", env, pos)
+			from := byteOffset(str, indexArg(args, 1, utf8.RuneCountInString(str), 0, "string.indexOf", env, pos))
+			i := strings.Index(str[from:], search)
+			if i < 0 {
+				return MK_NUMBER(-1)
+			}
+			return MK_NUMBER(float64(runeIndex(str, from+i)))
+		},
+		"lastIndexOf": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			search := stringArg(args, 0, "string.lastIndexOf", env, pos)
+			length := utf8.RuneCountInString(str)
+			// the match may start at from at the latest
+			from := byteOffset(str, indexArg(args, 1, length, length, "string.lastIndexOf", env, pos))
+			i := strings.LastIndex(str[:min(from+len(search), len(str))], search)
+			if i < 0 {
+				return MK_NUMBER(-1)
+			}
+			return MK_NUMBER(float64(runeIndex(str, i)))
+		},
+		"includes": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			search := stringArg(args, 0, "string.includes", env, pos)
+			from := byteOffset(str, indexArg(args, 1, utf8.RuneCountInString(str), 0, "string.includes", env, pos))
+			return MK_BOOL(strings.Contains(str[from:], search))
+		},
+		"startsWith": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			search := stringArg(args, 0, "string.startsWith", env, pos)
+			from := byteOffset(str, indexArg(args, 1, utf8.RuneCountInString(str), 0, "string.startsWith", env, pos))
+			return MK_BOOL(strings.HasPrefix(str[from:], search))
+		},
+		"endsWith": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			search := stringArg(args, 0, "string.endsWith", env, pos)
+			length := utf8.RuneCountInString(str)
+			end := byteOffset(str, indexArg(args, 1, length, length, "string.endsWith", env, pos))
+			return MK_BOOL(strings.HasSuffix(str[:end], search))
+		},
+		"trim": func(str string, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(strings.TrimSpace(str))
+		},
+		"trimStart": func(str string, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(strings.TrimLeftFunc(str, unicode.IsSpace))
+		},
+		"trimEnd": func(str string, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(strings.TrimRightFunc(str, unicode.IsSpace))
+		},
+		"toUpperCase": func(str string, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(mapCase(str, specialUpper, unicode.ToUpper))
+		},
+		"toLowerCase": func(str string, _ []RuntimeVal, _ *Environment, _ Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(mapCase(str, specialLower, unicode.ToLower))
+		},
+		"padStart": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(pad(str, args, true, "string.padStart", env, pos))
+		},
+		"padEnd": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			return MK_STRING(pad(str, args, false, "string.padEnd", env, pos))
+		},
+		"repeat": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			count := numberArg(args, 0, 0, "string.repeat", env, pos)
+			if count < 0 || count != math.Trunc(count) || math.IsInf(count, 0) {
+				env.ThrowRangeError("string.repeat expects a count that is a positive integer or 0, got", sprint(count), SourceLog(pos.line, pos.col, pos.count, env.sourcePath, ""))
+			}
+			return MK_STRING(strings.Repeat(str, int(count)))
+		},
+		// orders the strings by their code points, without normalizing them first
+		"compare": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			return MK_NUMBER(float64(strings.Compare(str, stringArg(args, 0, "string.compare", env, pos))))
+		},
+		"equalsIgnoreCase": func(str string, args []RuntimeVal, env *Environment, pos Pos, _ *Interpreter) RuntimeVal {
+			return MK_BOOL(strings.EqualFold(str, stringArg(args, 0, "string.equalsIgnoreCase", env, pos)))
+		},
+		"format": func(str string, args []RuntimeVal, env *Environment, pos Pos, r *Interpreter) RuntimeVal {
+			return MK_STRING(r.format(str, args, env, pos))
+		},
+	}
+}
+
+// maps the case of every code point of str, special holds the code points
+// that map to more than one, which unicode.ToUpper and ToLower leave as they are
+func mapCase(str string, special map[rune]string, to func(rune) rune) string {
+	var b strings.Builder
+	b.Grow(len(str))
+	for _, char := range str {
+		if mapped, ok := special[char]; ok {
+			b.WriteString(mapped)
+		} else {
+			b.WriteRune(to(char))
+		}
+	}
+	return b.String()
+}
+
+// the unconditional mappings of SpecialCasing.txt to more than one code point
+var specialUpper = map[rune]string{
+	0x00DF: "SS", 0x0149: "\u02BCN", 0x01F0: "J\u030C", 0x0390: "\u0399\u0308\u0301",
+	0x03B0: "\u03A5\u0308\u0301", 0x0587: "\u0535\u0552", 0x1E96: "H\u0331", 0x1E97: "T\u0308",
+	0x1E98: "W\u030A", 0x1E99: "Y\u030A", 0x1E9A: "A\u02BE", 0x1F50: "\u03A5\u0313",
+	0x1F52: "\u03A5\u0313\u0300", 0x1F54: "\u03A5\u0313\u0301", 0x1F56: "\u03A5\u0313\u0342", 0x1F80: "\u1F08\u0399",
+	0x1F81: "\u1F09\u0399", 0x1F82: "\u1F0A\u0399", 0x1F83: "\u1F0B\u0399", 0x1F84: "\u1F0C\u0399",
+	0x1F85: "\u1F0D\u0399", 0x1F86: "\u1F0E\u0399", 0x1F87: "\u1F0F\u0399", 0x1F88: "\u1F08\u0399",
+	0x1F89: "\u1F09\u0399", 0x1F8A: "\u1F0A\u0399", 0x1F8B: "\u1F0B\u0399", 0x1F8C: "\u1F0C\u0399",
+	0x1F8D: "\u1F0D\u0399", 0x1F8E: "\u1F0E\u0399", 0x1F8F: "\u1F0F\u0399", 0x1F90: "\u1F28\u0399",
+	0x1F91: "\u1F29\u0399", 0x1F92: "\u1F2A\u0399", 0x1F93: "\u1F2B\u0399", 0x1F94: "\u1F2C\u0399",
+	0x1F95: "\u1F2D\u0399", 0x1F96: "\u1F2E\u0399", 0x1F97: "\u1F2F\u0399", 0x1F98: "\u1F28\u0399",
+	0x1F99: "\u1F29\u0399", 0x1F9A: "\u1F2A\u0399", 0x1F9B: "\u1F2B\u0399", 0x1F9C: "\u1F2C\u0399",
+	0x1F9D: "\u1F2D\u0399", 0x1F9E: "\u1F2E\u0399", 0x1F9F: "\u1F2F\u0399", 0x1FA0: "\u1F68\u0399",
+	0x1FA1: "\u1F69\u0399", 0x1FA2: "\u1F6A\u0399", 0x1FA3: "\u1F6B\u0399", 0x1FA4: "\u1F6C\u0399",
+	0x1FA5: "\u1F6D\u0399", 0x1FA6: "\u1F6E\u0399", 0x1FA7: "\u1F6F\u0399", 0x1FA8: "\u1F68\u0399",
+	0x1FA9: "\u1F69\u0399", 0x1FAA: "\u1F6A\u0399", 0x1FAB: "\u1F6B\u0399", 0x1FAC: "\u1F6C\u0399",
+	0x1FAD: "\u1F6D\u0399", 0x1FAE: "\u1F6E\u0399", 0x1FAF: "\u1F6F\u0399", 0x1FB2: "\u1FBA\u0399",
+	0x1FB3: "\u0391\u0399", 0x1FB4: "\u0386\u0399", 0x1FB6: "\u0391\u0342", 0x1FB7: "\u0391\u0342\u0399",
+	0x1FBC: "\u0391\u0399", 0x1FC2: "\u1FCA\u0399", 0x1FC3: "\u0397\u0399", 0x1FC4: "\u0389\u0399",
+	0x1FC6: "\u0397\u0342", 0x1FC7: "\u0397\u0342\u0399", 0x1FCC: "\u0397\u0399", 0x1FD2: "\u0399\u0308\u0300",
+	0x1FD3: "\u0399\u0308\u0301", 0x1FD6: "\u0399\u0342", 0x1FD7: "\u0399\u0308\u0342", 0x1FE2: "\u03A5\u0308\u0300",
+	0x1FE3: "\u03A5\u0308\u0301", 0x1FE4: "\u03A1\u0313", 0x1FE6: "\u03A5\u0342", 0x1FE7: "\u03A5\u0308\u0342",
+	0x1FF2: "\u1FFA\u0399", 0x1FF3: "\u03A9\u0399", 0x1FF4: "\u038F\u0399", 0x1FF6: "\u03A9\u0342",
+	0x1FF7: "\u03A9\u0342\u0399", 0x1FFC: "\u03A9\u0399", 0xFB00: "FF", 0xFB01: "FI",
+	0xFB02: "FL", 0xFB03: "FFI", 0xFB04: "FFL", 0xFB05: "ST",
+	0xFB06: "ST", 0xFB13: "\u0544\u0546", 0xFB14: "\u0544\u0535", 0xFB15: "\u0544\u053B",
+	0xFB16: "\u054E\u0546", 0xFB17: "\u0544\u053D",
+}
+
+var specialLower = map[rune]string{
+	0x0130: "i\u0307",
+}
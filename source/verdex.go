@@ -105,7 +105,7 @@ type ASXModule struct {
 type ASXParser struct{}
 
 func (p *ASXParser) Parse(path string, main bool) *ASXModule {
-	parser := NewParser(path, "module", "")
+	parser := NewParser(path, "module", "", TokenizeVerdex)
 	module := &ASXModule{
 		imports:    []*ImportStmt{},
 		components: NewMap[string, string](),
@@ -364,7 +364,10 @@ func (p *ASXParser) CompileAS(code Node) string {
 	case *ArrayLiteral:
 	case *AssignmentExpr:
 	case *AwaitExpr:
+	case *BigInt:
+		compiled = code.Value.String() + "n"
 	case *BinaryExpr:
+	case *BitwiseNotExpr:
 	case *BlockStmt:
 	case *BreakStmt:
 	case *CallExpr:
@@ -382,6 +385,7 @@ func (p *ASXParser) CompileAS(code Node) string {
 	case *ForLoop:
 	case *FromExpr:
 	case *FunctionDecl:
+	case *GoStmt:
 	case *GotoStmt:
 	case *GroupingExpr:
 	case *Identifier:
@@ -399,12 +403,16 @@ func (p *ASXParser) CompileAS(code Node) string {
 	case *Number:
 		compiled = sprint(code.Value)
 	case *ObjectLiteral:
+	case *OptionalChain:
+	case *Pattern:
 	case *Program:
 	case *RestOrSpreadExpr:
 	case *ReturnStmt:
 	case *String:
 		compiled = "\"" + code.Value + "\""
+	case *StaticBlock:
 	case *SuperExpr:
+	case *SuperRef:
 	case *SwitchStmt:
 	case *TemplateString:
 	case *TernaryExpr:
@@ -421,6 +429,7 @@ func (p *ASXParser) CompileAS(code Node) string {
 		compiled += sprintf("%s %s = %s;", keyword[code._type], p.CompileAS(code.left), p.CompileAS(code.right))
 	case *VoidExpr:
 	case *WhileLoop:
+	case *YieldExpr:
 	case *globalThis:
 		compiled += code.Symbol
 	case *globalThisMember:
@@ -0,0 +1,226 @@
+package main
+
+import "slices"
+
+// set by --vm, blocks are compiled and run by the VM instead of the tree-walker
+var useVM = false
+
+// RunChunk runs a compiled block in env, like EvalBlock it returns the value of
+// the last statement, or what the function returns when it returns.
+func (r *Interpreter) RunChunk(chunk *Chunk, env *Environment) RuntimeVal {
+	code := chunk.code
+	names := chunk.names
+	// envs[0] is env, the others are the scopes that are open
+	envs := []*Environment{env}
+	slots := make([]RuntimeVal, chunk.slots)
+	stack := make([]RuntimeVal, 0, 16)
+	// references of the variables read by name, cleared when a scope opens or closes
+	// or when a variable may have been declared
+	refs := make([]Ref, len(names))
+	var last RuntimeVal = undefined
+
+	pop := func() RuntimeVal {
+		value := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return value
+	}
+	// closes the scopes above depth
+	unwind := func(depth int) {
+		if len(envs) > depth+1 {
+			envs = envs[:depth+1]
+			env = envs[depth]
+			clear(refs)
+		}
+	}
+
+	for pc := 0; pc < len(code); pc++ {
+		in := &code[pc]
+		pos := in.pos
+		switch in.op {
+		case OP_CONST:
+			stack = append(stack, chunk.consts[in.a])
+		case OP_POP:
+			pop()
+		case OP_POP_LAST:
+			last = pop()
+		case OP_LOAD_SLOT:
+			stack = append(stack, slots[in.a])
+		case OP_STORE_SLOT:
+			slots[in.a] = stack[len(stack)-1]
+		case OP_DECLARE_SLOT:
+			slots[in.a] = pop()
+		case OP_LOAD_NAME:
+			ref := refs[in.a]
+			if ref == nil || ref.value == nil {
+				name := names[in.a]
+				value := env.LookupVar(name, pos.line, pos.col, pos.count, env.sourcePath, r)
+				if value != nil {
+					refs[in.a] = env.ReferenceOf(name, pos.line, pos.col, pos.count, env.sourcePath, r)
+				}
+				stack = append(stack, value)
+				break
+			}
+			stack = append(stack, ref.value)
+		case OP_STORE_NAME:
+			name := names[in.a]
+			value := stack[len(stack)-1]
+			if in.b == 1 {
+				env.AssignVar(name, value, pos.line, pos.col, pos.count, env.sourcePath, r)
+			} else {
+				Memory.set(env.ReferenceOf(name, pos.line, pos.col, pos.count, env.sourcePath, r), value)
+			}
+		case OP_DECLARE_NAME:
+			env.DeclareVar(names[in.a], pop(), names[in.b], pos.line, pos.col, pos.count, env.sourcePath, r)
+			clear(refs)
+		case OP_BINARY:
+			rhs := pop()
+			stack = append(stack, r.binary(names[in.a], pop(), rhs, pos, env))
+		case OP_COMPARE:
+			rhs := pop()
+			stack = append(stack, r.compare(names[in.a], pop(), rhs, pos, env))
+		case OP_COMPOUND:
+			lhs := pop()
+			stack = append(stack, r.compound(names[in.a], lhs, pop(), pos, env))
+		case OP_COALESCE:
+			lhs := pop()
+			rhs := pop()
+			if ValIsNullish(lhs) {
+				stack = append(stack, rhs)
+			} else {
+				stack = append(stack, lhs)
+				pc = in.a - 1
+			}
+		case OP_AND:
+			right := pop()
+			if left := pop(); !RtvToBool(left) {
+				stack = append(stack, left)
+			} else {
+				stack = append(stack, right)
+			}
+		case OP_OR:
+			right := pop()
+			if left := pop(); RtvToBool(left) {
+				stack = append(stack, left)
+			} else {
+				stack = append(stack, right)
+			}
+		case OP_NOT:
+			stack = append(stack, MK_BOOL(!RtvToBool(pop())))
+		case OP_TYPEOF:
+			stack = append(stack, MK_STRING(ValueType(pop())))
+		case OP_GET_MEMBER:
+			key := pop()
+			ml := r.MemberRef(pop(), key, in.a == 1, pos, env)
+			stack = append(stack, r.memberValue(ml, env, pos))
+		case OP_SET_MEMBER:
+			value := pop()
+			key := pop()
+			object := pop()
+			exp := chunk.nodes[in.a].(*MemberExpr)
+			if arr, ok := object.(*ArrayVal); ok && arr.assignLength(key, value, env, pos) {
+				stack = append(stack, value)
+				break
+			}
+			ml := r.MemberRef(object, key, exp.computed, getPosFromNode(exp.property), env)
+			if ml == nil {
+				if in.b == 1 {
+					r.checkStaticMember(exp, env)
+				}
+				ml = AddProperty(object, key)
+			}
+			r.setMember(ml, value, env, pos)
+			stack = append(stack, value)
+		case OP_MEMBER_COMPOUND:
+			rhs := pop()
+			var lhs RuntimeVal = undefined
+			if ml := r.MemberRef(stack[len(stack)-2], stack[len(stack)-1], in.b == 1, pos, env); ml != nil {
+				lhs = r.memberValue(ml, env, pos)
+			}
+			stack = append(stack, r.compound(names[in.a], lhs, rhs, pos, env))
+		case OP_INCREMENT:
+			stored, value := increment(names[in.a], in.b == 1, pop(), pos, env)
+			stack = append(stack, value, stored)
+		case OP_INCREMENT_MEMBER:
+			key := pop()
+			object := pop()
+			ml := r.MemberRef(object, key, in.c == 1, pos, env)
+			operand_value := r.memberValue(ml, env, pos)
+			stored, value := increment(names[in.a], in.b == 1, operand_value, pos, env)
+			if arr, ok := object.(*ArrayVal); !ok || !arr.assignLength(key, stored, env, pos) {
+				r.setMember(ml, stored, env, pos)
+			}
+			stack = append(stack, value)
+		case OP_CALL:
+			args := slices.Clone(stack[len(stack)-in.a:])
+			fn := stack[len(stack)-in.a-1]
+			stack = stack[:len(stack)-in.a-1]
+			value, _ := CallFunction(fn, env, args, r, pos)
+			stack = append(stack, value)
+		case OP_ARRAY:
+			array := MK_ARRAY()
+			for _, el := range stack[len(stack)-in.a:] {
+				array.Push(el)
+			}
+			stack = append(stack[:len(stack)-in.a], array)
+		case OP_TEMPLATE:
+			str := joinTemplate(chunk.nodes[in.a].(*TemplateString), stack[len(stack)-in.b:])
+			stack = append(stack[:len(stack)-in.b], MK_STRING(str))
+		case OP_JUMP:
+			if in.a <= pc {
+				// the end of a loop body
+				yieldInterpreter()
+			}
+			pc = in.a - 1
+		case OP_JUMP_IF_FALSE:
+			if !RtvToBool(pop()) {
+				pc = in.a - 1
+			}
+		case OP_PUSH_ENV:
+			env = NewEnv(env, names[in.a], env.sourcePath)
+			envs = append(envs, env)
+			clear(refs)
+		case OP_POP_ENV:
+			unwind(len(envs) - 2)
+		case OP_BREAK:
+			loop := chunk.loops[in.a]
+			unwind(loop.breakDepth)
+			pc = loop.breakAt - 1
+		case OP_CONTINUE:
+			loop := chunk.loops[in.a]
+			unwind(loop.continueDepth)
+			pc = loop.continueAt - 1
+		case OP_RETURN:
+			value := pop()
+			r.checkReturn(pos, env)
+			return r.returnValue(value)
+		case OP_THROW:
+			env.throwValue(pop(), r)
+		case OP_REDECLARE:
+			env.ThrowRedeclaration(names[in.a], names[in.b], pos.line, pos.col, pos.count, env.sourcePath)
+		case OP_ASSIGN_CONSTANT:
+			env.ThrowConstantAssignment(names[in.a], names[in.b], pos.line, pos.col, pos.count, env.sourcePath)
+		case OP_EVAL:
+			value := r.Evaluate(chunk.nodes[in.a], env)
+			clear(refs)
+			if r.terminated {
+				if in.b < 0 || !(r._break || r._continue) || r.label != "" {
+					// a return, a goto, a break out of a loop this block is in
+					// or a break or continue with a label
+					return value
+				}
+				loop := chunk.loops[in.b]
+				if r._break {
+					unwind(loop.breakDepth)
+					pc = loop.breakAt - 1
+				} else {
+					unwind(loop.continueDepth)
+					pc = loop.continueAt - 1
+				}
+				r.exitLoop("")
+				break
+			}
+			stack = append(stack, value)
+		}
+	}
+	return last
+}
@@ -1,4 +0,0 @@
-
-static spawn runtime = {
-  args: #_runtime_arguments()
-}
\ No newline at end of file
@@ -0,0 +1,44 @@
+import { check } from "./check.as"
+
+$ native methods
+spawn numbers = [5, 3, 8, 1]
+check(numbers.push(2), 5, "push")
+check(numbers.pop(), 2, "pop")
+check(numbers.shift(), 5, "shift")
+check(numbers.unshift(4), 4, "unshift")
+check(numbers.map((n) => { return n * 2 }).join(","), "8,6,16,2", "map")
+check(numbers.filter((n) => { return n > 2 }).length, 3, "filter")
+check(numbers.reduce((sum, n) => { return sum + n }, 0), 16, "reduce")
+check(numbers.find((n) => { return n > 3 }), 4, "find")
+check(numbers.some((n) => { return n > 7 }) && numbers.every((n) => { return n > 0 }), true, "some and every")
+check(numbers.slice(1, -1).join(","), "3,8", "slice")
+check(numbers.splice(1, 2, "x").join(","), "3,8", "splice")
+check(numbers.join(","), "4,x,1", "spliced array")
+check([3, 1, 2].sort().join(","), "1,2,3", "sort")
+check([3, 1, 2].sort((a, b) => { return b - a }).join(","), "3,2,1", "sort with a comparator")
+check([1, [2, [3]]].flat().length, 3, "flat")
+check([1, 2].flatMap((n) => { return [n, n] }).join(""), "1122", "flatMap")
+check([0, 0, 0].fill(7, 1).join(""), "077", "fill")
+check(numbers[-1], 1, "negative index")
+
+$ indexOf compares strictly, includes finds NaN
+spawn nan = 0 / 0
+check([1, nan].indexOf(nan), -1, "indexOf NaN")
+check([1, nan].includes(nan), true, "includes NaN")
+check([1, "1"].indexOf("1"), 1, "indexOf a string")
+check([1, 2, 3].includes(1, 1), false, "includes from an index")
+
+$ setting length truncates or extends the array
+spawn letters = ["a", "b", "c"]
+letters.length = 1
+check(letters.join(","), "a", "truncated")
+letters.length = 3
+check(letters.length, 3, "extended")
+check(letters[2], undefined, "hole")
+spawn invalid = ""
+try {
+  letters.length = -1
+} catch (e) {
+  invalid = e.name
+}
+check(invalid, "RangeError", "negative length")
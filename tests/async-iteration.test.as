@@ -0,0 +1,42 @@
+import { check } from "./check.as"
+
+$ async generators and for await
+function delay(ms) {
+  return new Promise((resolve) => {
+    setTimeout(() => { resolve() }, ms)
+  })
+}
+async function* countdown(n) {
+  while (n > 0) {
+    await delay(1)
+    yield n--
+  }
+}
+spawn seen = []
+for await (spawn n of countdown(3)) {
+  seen.push(n)
+}
+check(seen.join(","), "3,2,1", "for await")
+spawn ag = countdown(2)
+spawn [x, y] = await Promise.all([ag.next(), ag.next()])
+check(x.value + y.value, 3, "queued next calls")
+check((await ag.next()).done, true, "finished")
+spawn plain = []
+for await (spawn v of [Promise.resolve("p"), "q"]) {
+  plain.push(v)
+}
+check(plain.join(""), "pq", "for await over an array")
+
+$ Symbol.asyncIterator
+class Feed {
+  async *[Symbol.asyncIterator]() {
+    yield "x"
+    await delay(1)
+    yield "y"
+  }
+}
+spawn items = ""
+for await (spawn item of new Feed()) {
+  items += item
+}
+check(items, "xy", "async iterator method")
@@ -0,0 +1,33 @@
+import { check } from "./check.as"
+
+function delay(ms, value) {
+  return new Promise((resolve) => {
+    setTimeout(() => { resolve(value) }, ms)
+  })
+}
+
+$ await suspends the function until the promise settles, top-level await too
+spawn order = ""
+async function double(x) {
+  order += "start "
+  spawn value = await delay(5, x)
+  order += "resumed"
+  return value * 2
+}
+spawn pending = double(21)
+order += "suspended "
+check(await pending, 42, "await")
+check(order, "start suspended resumed", "suspended function")
+check(await 5, 5, "await of a value")
+
+$ a rejection is thrown where it is awaited
+async function fail() {
+  throw new Error("nope")
+}
+spawn message = ""
+try {
+  await fail()
+} catch (e) {
+  message = e.message
+}
+check(message, "nope", "rejected await")
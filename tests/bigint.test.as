@@ -0,0 +1,28 @@
+import { check } from "./check.as"
+
+$ arithmetic of any size
+check((2n ** 64n).toString(), "18446744073709551616", "power")
+check(-7n / 2n, -3n, "division towards zero")
+check(1n << 70n, 2n ** 70n, "shift")
+check(typeof 1n, "bigint", "typeof")
+check((255n).toString(16), "ff", "toString radix")
+
+$ conversions are explicit
+check(BigInt(10), 10n, "BigInt of a number")
+check(BigInt("0x1f"), 31n, "BigInt of text")
+check(Number(2n ** 10n), 1024, "Number of a bigint")
+check(1n < 2 && 3n > 2.5, true, "comparison with a number")
+spawn mixed = ""
+try {
+  1n + 1
+} catch (e) {
+  mixed = e.name
+}
+check(mixed, "TypeError", "mixed arithmetic")
+spawn zero = ""
+try {
+  1n / 0n
+} catch (e) {
+  zero = e.name
+}
+check(zero, "RangeError", "division by zero")
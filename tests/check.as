@@ -0,0 +1,6 @@
+$ shared by the tests: a failed check throws, which fails the file that made it
+export function check(actual, expected, what) {
+  if (actual !== expected) {
+    throw `#{what}: expected #{expected}, got #{actual}`
+  }
+}
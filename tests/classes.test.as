@@ -0,0 +1,54 @@
+import { check } from "./check.as"
+
+$ private, static and accessor members, super calls
+class Animal {
+  private #sound = "..."
+  static count = 0
+  constructor(sound) {
+    this.#sound = sound
+    Animal.count += 1
+  }
+  get sound() {
+    return this.#sound
+  }
+  set sound(value) {
+    if (value == "") {
+      throw new RangeError("a sound cannot be empty")
+    }
+    this.#sound = value
+  }
+  function speak() {
+    return this.#sound
+  }
+  static function make(sound) {
+    return new Animal(sound)
+  }
+}
+class Dog extends Animal {
+  constructor() {
+    super("woof")
+  }
+  function speak() {
+    return super.speak() + "!"
+  }
+}
+spawn dog = new Dog()
+check(dog.speak(), "woof!", "super method")
+check(Animal.make("moo").sound, "moo", "static method and getter")
+check(Animal.count, 2, "static field")
+dog.sound = "bark"
+check(dog.speak(), "bark!", "setter")
+spawn rejected = ""
+try {
+  dog.sound = ""
+} catch (e) {
+  rejected = e.name
+}
+check(rejected, "RangeError", "setter that throws")
+spawn denied = false
+try {
+  dog.#sound
+} catch (e) {
+  denied = e instanceof TypeError
+}
+check(denied, true, "private member outside of the class")
@@ -0,0 +1,56 @@
+import { check } from "./check.as"
+
+$ keys are the same when they are equal primitives or the same object
+spawn key = [1]
+spawn nan = 0 / 0
+spawn map = new Map([["a", 1]])
+map.set(key, 2).set(nan, 3)
+check(map.get(key), 2, "object key")
+check(map.get(nan), 3, "NaN key")
+check(map.size, 3, "map size")
+spawn order = ""
+map.forEach((value, k) => {
+  order += `#{value}`
+})
+check(order, "123", "insertion order")
+check(map.delete("a"), true, "delete")
+spawn set = new Set([1, 1, 2])
+check(set.size, 2, "set size")
+check(set.has(2), true, "set has")
+
+$ the constructors take any iterable
+check(new Set("abca").size, 3, "set of a string")
+check(new Map(map).get(nan), 3, "map of a map")
+check(new Set(map).size, 2, "set of the entries of a map")
+class Pairs {
+  function [Symbol.iterator]() {
+    spawn i = 0
+    return {
+      next: () => {
+        i += 1
+        return { value: [`k#{i}`, i], done: i > 2 }
+      }
+    }
+  }
+}
+check(new Map(new Pairs()).get("k2"), 2, "map of an iterable")
+spawn failed = ""
+try {
+  new Set(5)
+} catch (e) {
+  failed = e.message
+}
+check(failed, "a Set is made from an iterable of values: type number is not iterable", "set of a number")
+
+$ weak collections only take objects as keys
+spawn owner = {}
+spawn weak = new WeakMap([[owner, "data"]])
+check(weak.get(owner), "data", "weak get")
+check(new WeakSet([owner]).has(owner), true, "weak set has")
+spawn rejected = false
+try {
+  weak.set("text", 1)
+} catch (e) {
+  rejected = e instanceof TypeError
+}
+check(rejected, true, "primitive weak key")
@@ -0,0 +1,19 @@
+$ line comments run to the end of the line, block comments may span lines
+spawn hits = 0 $ after a statement
+$* a block comment
+   over two lines *$
+hits += 1
+spawn inline = $* inside an expression *$ 2
+spawn text = "$ in a string"
+if (hits != 1 || inline != 2 || text != "$ in" + " a string") {
+  throw "comments were not skipped"
+}
+
+$ a comment directly after a closing brace is not a Verdex template delimiter
+spawn count = 0
+if (true) { count++ }$ counted once
+function twice(n) { return n * 2 }$* a block comment *$
+spawn o = { a: twice(1) }$ an object literal
+if (count != 1 || o.a != 2) {
+  throw "comment after } was not skipped"
+}
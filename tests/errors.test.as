@@ -0,0 +1,29 @@
+import { check } from "./check.as"
+
+$ runtime errors are values that try/catch catches
+spawn name = ""
+try {
+  missing
+} catch (e) {
+  name = e.name
+}
+check(name, "ReferenceError", "undefined variable")
+try {
+  null.property
+} catch (e) {
+  check(e instanceof TypeError, true, "instanceof TypeError")
+  check(e instanceof Error, true, "instanceof Error")
+  check(typeof (e.stack), "string", "stack")
+}
+
+$ thrown values are kept as they are
+try {
+  throw new RangeError("too far")
+} catch (e) {
+  check(e.name + ": " + e.message, "RangeError: too far", "error class")
+}
+try {
+  throw 42
+} catch (e) {
+  check(e, 42, "thrown number")
+}
@@ -0,0 +1,23 @@
+import { check } from "./check.as"
+
+$ micro tasks run in order after the code that queued them, timers after them
+spawn order = ""
+setTimeout(() => { order += "timer" }, 0)
+Promise.resolve().then(() => { order += "then " })
+queueMicrotask(() => { order += "micro " })
+order += "sync "
+spawn cancelled = setTimeout(() => { order += " cancelled" }, 0)
+clearTimeout(cancelled)
+
+$ the loop runs until no timer is left
+spawn ticks = 0
+spawn interval = setInterval(() => {
+  ticks += 1
+  if (ticks == 3) {
+    clearInterval(interval)
+  }
+}, 1)
+setTimeout(() => {
+  check(order, "sync then micro timer", "event loop order")
+  check(ticks, 3, "interval cleared")
+}, 50)
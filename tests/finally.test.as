@@ -0,0 +1,55 @@
+import { check } from "./check.as"
+
+$ finally runs on every way out of try
+spawn steps = ""
+function leave() {
+  try {
+    steps += "try "
+    return "returned"
+  } finally {
+    steps += "finally"
+  }
+}
+check(leave(), "returned", "return from try")
+check(steps, "try finally", "finally after return")
+spawn runs = 0
+for (i = 0; i < 3; i++) {
+  try {
+    if (i == 1) {
+      continue
+    }
+    if (i == 2) {
+      break
+    }
+  } finally {
+    runs += 1
+  }
+}
+check(runs, 3, "finally on continue and break")
+
+$ a throw in catch goes on after finally, through the functions that called it
+spawn order = ""
+function inner() {
+  try {
+    throw new Error("first")
+  } catch (e) {
+    throw new Error("second")
+  } finally {
+    order += "inner "
+  }
+}
+function outer() {
+  try {
+    inner()
+  } finally {
+    order += "outer"
+  }
+}
+spawn message = ""
+try {
+  outer()
+} catch (e) {
+  message = e.message
+}
+check(message, "second", "error thrown in catch")
+check(order, "inner outer", "finally blocks unwound")
@@ -0,0 +1,65 @@
+import { check } from "./check.as"
+
+function* count(limit) {
+  for (i = 0; i < limit; i++) {
+    spawn skip = yield i
+    if (skip) {
+      i++
+    }
+  }
+  return "done"
+}
+
+$ next resumes the body with the value of yield
+spawn gen = count(5)
+check(gen.next().value, 0, "first value")
+check(gen.next(true).value, 2, "value passed to yield")
+spawn last = gen.return(7)
+check(`#{last.value}:#{last.done}`, "7:true", "return")
+
+$ finally runs when a suspended generator is finished early
+spawn cleaned = false
+function* guarded() {
+  try {
+    yield 1
+    yield 2
+  } finally {
+    cleaned = true
+  }
+}
+for (spawn n of guarded()) {
+  break
+}
+check(cleaned, true, "break out of for..of")
+
+$ yield* and iteration
+function* both() {
+  spawn result = yield* count(2)
+  yield result
+}
+spawn [a, b, c] = both()
+check(`#{a} #{b} #{c}`, "0 1 done", "yield* value")
+class Range {
+  constructor(low, high) {
+    this.low = low
+    this.high = high
+  }
+  *[Symbol.iterator]() {
+    for (i = this.low; i < this.high; i++) {
+      yield i
+    }
+  }
+}
+spawn total = 0
+for (spawn n of new Range(1, 4)) {
+  total += n
+}
+check(total, 6, "iterator method")
+
+$ what takes an iterable takes a generator
+function* pairs() {
+  yield ["a", 1]
+  yield ["b", 2]
+}
+check(new Map(pairs()).get("b"), 2, "map of a generator")
+check(new Set(count(3)).size, 3, "set of a generator")
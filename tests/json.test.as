@@ -0,0 +1,55 @@
+import { check } from "./check.as"
+
+$ objects keep the order of their properties
+spawn text = JSON.stringify({ b: 1, a: [true, null], c: undefined })
+check(text, '{"b":1,"a":[true,null]}', "stringify")
+check(JSON.stringify([undefined, 1 / 0, `\n`]), `[null,null,"\\n"]`, "values with no JSON")
+function keys(object) {
+  spawn names = []
+  for (spawn name in object) {
+    names.push(name)
+  }
+  return names.join(",")
+}
+spawn parsed = JSON.parse(text)
+check(parsed.a[0], true, "parse")
+check(keys(parsed), "b,a", "parsed order")
+
+$ replacer and space
+check(JSON.stringify({ a: 1, b: 2 }, ["b"]), '{"b":2}', "replacer array")
+spawn doubled = JSON.stringify({ a: 1, b: [2] }, (key, value) => {
+  return typeof value == "number" ? value * 2 : value
+})
+check(doubled, '{"a":2,"b":[4]}', "replacer function")
+check(JSON.stringify({ a: [1] }, null, 2), `{\n  "a": [\n    1\n  ]\n}`, "space")
+check(JSON.stringify([1], null, "--"), `[\n--1\n]`, "space string")
+
+$ the reviver sees the innermost values first and deletes undefined ones
+spawn seen = []
+spawn revived = JSON.parse('{"a": 1, "b": {"c": 2}}', (key, value) => {
+  seen.push(key)
+  if (key == "a") {
+    return undefined
+  }
+  return value
+})
+check(seen.join(","), "a,c,b,", "reviver order")
+check(keys(revived), "b", "reviver deletes")
+
+$ errors
+spawn cyclic = {}
+cyclic.self = cyclic
+spawn cycle = ""
+try {
+  JSON.stringify(cyclic)
+} catch (e) {
+  cycle = e.name
+}
+check(cycle, "TypeError", "cycle")
+spawn syntax = ""
+try {
+  JSON.parse('{"a": 1,}')
+} catch (e) {
+  syntax = e.message
+}
+check(syntax.includes("line 1 column 9"), true, "syntax error position")
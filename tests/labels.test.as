@@ -0,0 +1,31 @@
+import { check } from "./check.as"
+
+$ break and continue with a label leave the loops inside it
+spawn pairs = 0
+outer :> for (i = 0; i < 3; i++) {
+  for (j = 0; j < 3; j++) {
+    if (j == 1) {
+      continue outer
+    }
+    pairs++
+  }
+}
+check(pairs, 3, "continue outer")
+spawn found = ""
+search :> for (i = 0; i < 3; i++) {
+  for (j = 0; j < 3; j++) {
+    if (i * j == 2) {
+      found = `#{i},#{j}`
+      break search
+    }
+  }
+}
+check(found, "1,2", "break outer")
+
+$ goto jumps to a label in the same block
+spawn n = 0
+again :> n++
+if (n < 5) {
+  goto again
+}
+check(n, 5, "goto")
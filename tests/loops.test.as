@@ -0,0 +1,35 @@
+import { check } from "./check.as"
+
+$ loops, conditions, member access and calls, run the same with and without --vm
+spawn sum = 0
+for (i = 0; i < 1000; i++) {
+  if (i % 2 == 0) {
+    sum += i
+  } else {
+    sum -= 1
+  }
+}
+check(sum, 249000, "for loop")
+spawn point = { x: 0 }
+spawn n = 0
+while (n < 10) {
+  point.x = point.x + n
+  n++
+}
+check(point.x, 45, "while loop and members")
+function fib(k) {
+  if (k < 2) {
+    return k
+  }
+  return fib(k - 1) + fib(k - 2)
+}
+check(fib(15), 610, "recursion")
+
+$ a closure keeps the variable of its iteration
+spawn captured = 0
+for (i = 0; i < 3; i++) {
+  spawn k = i
+  spawn read = () => { return k }
+  captured += read() * 10
+}
+check(captured, 30, "closures in a loop")
@@ -0,0 +1,43 @@
+import { check } from "./check.as"
+
+$ match arms, patterns and guards
+class Person {
+  constructor(name, age) {
+    this.name = name
+    this.age = age
+  }
+}
+function describe(value) {
+  return match value {
+    0 => "zero",
+    1 | 2 | 3 => "small",
+    4..10 => "medium",
+    10..=20 => "large",
+    typeof "number" as x if x < 0 => "negative",
+    [] => "empty",
+    [first, ...rest] => "list of " + first + " and " + rest[0],
+    Person { name, age: 0..18 } => name + " is a minor",
+    { kind: "circle", r } => "circle " + r,
+    null | undefined => "nothing",
+    _ => "other"
+  }
+}
+check(describe(2), "small", "alternatives")
+check(describe(7), "medium", "range")
+check(describe(20), "large", "inclusive range")
+check(describe(-1), "negative", "guard")
+check(describe([]), "empty", "empty array")
+check(describe([1, 2]), "list of 1 and 2", "array pattern")
+check(describe(new Person("Ann", 9)), "Ann is a minor", "class pattern")
+check(describe({ kind: "circle", r: 3 }), "circle 3", "object pattern")
+check(describe(null), "nothing", "null")
+check(describe("x"), "other", "default arm")
+
+$ a value no arm matches throws
+spawn failed = false
+try {
+  match 5 { 1 => "one" }
+} catch (e) {
+  failed = true
+}
+check(failed, true, "no arm matches")
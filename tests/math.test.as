@@ -0,0 +1,22 @@
+import { check } from "./check.as"
+
+$ Math
+check(Math.floor(2.7) + Math.ceil(2.1) + Math.round(2.5), 8, "rounding")
+check(Math.max(1, 5, 3), 5, "max")
+check(Math.max(), 0 - Infinity, "max of nothing")
+check(Math.pow(2, 10), 1024, "pow")
+check(Math.hypot(3, 4), 5, "hypot")
+Math.seed(42)
+spawn first = Math.random()
+Math.seed(42)
+check(Math.random(), first, "seed")
+
+$ literals and Number
+check(0xff + 0o17 + 0b1010, 280, "radix literals")
+check(1_000 + 1.5e3, 2500, "separators and exponents")
+check(Number.parseInt("ff", 16), 255, "parseInt")
+check(Number.parseFloat("3.14abc"), 3.14, "parseFloat")
+check(Number.isInteger(2.5), false, "isInteger")
+check(Number.isNaN(Number.parseFloat("px")), true, "parseFloat of text")
+check((3.14159).toFixed(2), "3.14", "toFixed")
+check((255).toString(16), "ff", "toString radix")
@@ -0,0 +1,36 @@
+import { check } from "./check.as"
+
+$ bitwise and shift operators work on 32 bit integers
+check(5 & 3 | 8, 9, "and, or")
+check(5 ^ 3, 6, "xor")
+check(~5, -6, "not")
+check(1 << 4, 16, "left shift")
+check(-16 >> 2, -4, "right shift")
+check(-1 >>> 28, 15, "unsigned right shift")
+
+$ ** binds tighter than * and groups to the right
+check(2 ** 3 ** 2, 512, "right to left")
+check(2 * 3 ** 2, 18, "before *")
+check((2 ** 3) ** 2, 64, "parentheses")
+
+$ ?. and ??
+spawn user = { profile: null, greet: () => { return "hi" } }
+check(user.profile?.name, undefined, "optional chaining")
+check(user.profile?.name ?? "anonymous", "anonymous", "nullish coalescing")
+check(0 ?? 1, 0, "?? keeps 0")
+check(user.greet?.(), "hi", "optional call")
+check(user.missing?.(), undefined, "optional call of nothing")
+
+$ compound and logical assignments
+spawn flags = 0
+flags |= 4
+flags <<= 1
+check(flags, 8, "compound assignment")
+flags ||= 1
+check(flags, 8, "||=")
+spawn missing = null
+missing ??= "set"
+check(missing, "set", "??=")
+spawn both = 1
+both &&= 2
+check(both, 2, "&&=")
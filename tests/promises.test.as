@@ -0,0 +1,28 @@
+import { check } from "./check.as"
+
+$ an async function that throws rejects its promise, catch recovers
+async function fail() {
+  throw new Error("nope")
+}
+fail().catch((e) => { return e.message + " recovered" }).then((value) => {
+  check(value, "nope recovered", "catch")
+})
+
+$ then returns a new promise, finally passes the value through
+Promise.resolve(1).then((n) => { return n + 1 }).finally(() => { return 10 }).then((n) => {
+  check(n, 2, "then and finally")
+})
+
+$ combinators
+Promise.all([Promise.resolve("a"), "b"]).then((values) => {
+  check(values[0] + values[1], "ab", "all")
+})
+Promise.race([new Promise(() => {}), Promise.resolve("fast")]).then((value) => {
+  check(value, "fast", "race")
+})
+Promise.allSettled([Promise.reject("x"), 1]).then((settled) => {
+  check(settled[0].status + " " + settled[1].status, "rejected fulfilled", "allSettled")
+})
+Promise.any([Promise.reject("x"), Promise.resolve("y")]).then((value) => {
+  check(value, "y", "any")
+})
@@ -0,0 +1,28 @@
+import { check } from "./check.as"
+
+$ literals, exec and lastIndex
+spawn date = /(?<year>\d{4})-(?<month>\d\d)/g
+spawn text = "from 2024-05 to 2025-11"
+check(date.test(text), true, "test")
+check(date.lastIndex, 12, "lastIndex")
+check(date.exec(text).groups.month, "11", "named group")
+check(/(\d+)-(\d+)/.exec("on 2024-05").captures[1], "2024", "capture")
+spawn made = new RegExp("a+", "gi")
+check(made.source + " " + made.flags, "a+ gi", "RegExp")
+check(4 / 2 / 1, 2, "division is not a literal")
+check(/[/]/.test("/"), true, "slash in a class")
+check(/[{]/.test("{"), true, "brace in a class")
+
+$ string methods take regexps
+check(text.match(date).join(","), "2024-05,2025-11", "match")
+check(text.search(/\d/), 5, "search")
+check(text.replace(date, "$<month>/$<year>"), "from 05/2024 to 11/2025", "replace")
+check("a1b22".replace(/\d+/g, "#"), "a#b#", "global replace")
+check("a1b22c".split(/\d+/).join(""), "abc", "split")
+spawn unsupported = ""
+try {
+  new RegExp(`(a)\\1`)
+} catch (e) {
+  unsupported = e.name
+}
+check(unsupported, "SyntaxError", "backreference")
@@ -0,0 +1,10 @@
+import { check } from "./check.as"
+import { runtime } from "std:runtime"
+
+$ std: imports read the standard library built into the binary
+check(runtime.command, "test", "std: import")
+spawn count = 0
+for (spawn arg in runtime.args) {
+  count++
+}
+check(count, 1, "runtime args")
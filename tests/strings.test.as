@@ -0,0 +1,24 @@
+import { check } from "./check.as"
+
+$ strings are indexed by code point
+spawn greeting = "héllo wörld 🎉"
+check(greeting.length, 13, "length")
+check(greeting[1], "é", "index")
+check(greeting[-1], "🎉", "negative index")
+check(greeting.codePointAt(12), 127881, "codePointAt")
+check(String.fromCodePoint(72, 233), "Hé", "fromCodePoint")
+check(greeting.indexOf("wörld"), 6, "indexOf")
+check(greeting.slice(6, 11), "wörld", "slice")
+check(greeting.toUpperCase(), "HÉLLO WÖRLD 🎉", "toUpperCase")
+check("straße".toUpperCase(), "STRASSE", "toUpperCase of ß")
+check("İ".toLowerCase().length, 2, "toLowerCase of İ")
+
+$ split, trim, pad and format
+check(greeting.split(" ").length, 3, "split")
+check("a,b,c".split(",", 2).join("|"), "a|b", "split limit")
+check("a,b,c".split(",", -1).join("|"), "a|b|c", "negative split limit")
+check(" x ".trim() + "|" + " x ".trimStart(), "x|x ", "trim")
+check("7".padStart(3, "0"), "007", "padStart")
+check("ab".repeat(3), "ababab", "repeat")
+check("{} + {} = {2}".format(1, 2, 3), "1 + 2 = 3", "format")
+check("École".equalsIgnoreCase("éCOLE"), true, "equalsIgnoreCase")
@@ -0,0 +1,46 @@
+$ more tasks finish than the event loop buffers callbacks for, while the
+$ main goroutine keeps running a loop of its own
+spawn finished = 0
+spawn group = new WaitGroup()
+
+function work() {
+  finished += 1
+  group.done()
+}
+
+group.add(100)
+for (k = 0; k < 100; k++) {
+  go work()
+}
+
+spawn sum = 0
+for (i = 0; i < 200000; i++) {
+  sum += 1
+}
+group.wait()
+
+if (sum != 200000 || finished != 100) {
+  throw "expected 200000 iterations and 100 finished tasks, got " + sum + " and " + finished
+}
+
+$ channels, closed once their values are read, and select
+spawn numbers = new Channel(2)
+function produce() {
+  for (n = 1; n <= 3; n++) {
+    numbers.send(n)
+  }
+  numbers.close()
+}
+go produce()
+spawn received = 0
+for (spawn n of numbers) {
+  received += n
+}
+if (received != 6 || numbers.receive() != undefined) {
+  throw "expected the values 1 to 3 before the channel closed, got " + received
+}
+spawn full = new Channel(1)
+spawn { index, ok } = select(new Channel(), [full, "sent"])
+if (index != 1 || ok != true || trySelect(new Channel()).index != -1) {
+  throw "select did not pick the channel with room"
+}
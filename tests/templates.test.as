@@ -0,0 +1,20 @@
+import { check } from "./check.as"
+
+$ interpolation, escapes and nested templates
+spawn name = "web"
+check(`hello #{name}, #{1 + 2} items`, "hello web, 3 items", "interpolation")
+check(`nested #{`#{name}!`}`, "nested web!", "nested template")
+check(`\x41\u{1F600}`, `A😀`, "escapes")
+check(`\#{name}`, "#" + "{name}", "escaped interpolation")
+check(`two
+lines`, `two\nlines`, "multi-line")
+
+$ a tag is called with the cooked and raw text and the values
+function tag(strings, value) {
+  return strings.raw[0] + "|" + strings.cooked[0] + "|" + value + "|" + strings.cooked[1]
+}
+check(tag`a\n#{1}b`, `a\\n|a\n|1|b`, "tagged template")
+function invalid(strings) {
+  return strings.cooked[0] === undefined
+}
+check(invalid`\u{bad`, true, "invalid escape in a tag")